@@ -4,19 +4,100 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
+	"sync"
 
 	"github.com/Cekretik/BoostBot/models"
 )
 
 const (
-	apiCategoriesEndpoint     = "https://api.stagesmm.com/categories"
-	apiSubcategoriesEndpoint  = "https://api.stagesmm.com/subcategories/"
-	apiServicesEndpointFormat = "https://api.stagesmm.com/services?search=&limit=25000&subcategory_id=%s&pagination=1&order=DESC&order_by=id"
+	apiCategoriesPath     = "/categories"
+	apiSubcategoriesPath  = "/subcategories/"
+	apiServicesPathFormat = "/services?search=&limit=25000&subcategory_id=%s&pagination=1&order=DESC&order_by=id"
 )
 
-func FetchCategoriesFromAPI() ([]models.Category, error) {
-	resp, err := http.Get(apiCategoriesEndpoint)
+// Каталог StageSMM собирается обходом соцсетей и их разделов, не больше fetchWorkers
+// запросов одновременно. Ошибка любого запроса прерывает обход, чтобы синхронизация
+// не удалила недополученные услуги
+func (s *StageSMM) Catalog() (Catalog, error) {
+	categories, err := s.fetchCategories()
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	subcategories := make([][]models.Subcategory, len(categories))
+	err = forEachLimit(len(categories), s.fetchWorkers, func(i int) error {
+		var err error
+		subcategories[i], err = s.fetchSubcategories(categories[i].ID)
+		if err != nil {
+			return fmt.Errorf("subcategories of %s: %w", categories[i].ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	catalog := Catalog{Categories: categories}
+	for _, list := range subcategories {
+		catalog.Subcategories = append(catalog.Subcategories, list...)
+	}
+	services := make([][]models.Services, len(catalog.Subcategories))
+	err = forEachLimit(len(catalog.Subcategories), s.fetchWorkers, func(i int) error {
+		var err error
+		services[i], err = s.fetchServices(catalog.Subcategories[i].ID)
+		if err != nil {
+			return fmt.Errorf("services of %s: %w", catalog.Subcategories[i].ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Catalog{}, err
+	}
+	for _, list := range services {
+		catalog.Services = append(catalog.Services, list...)
+	}
+	return catalog, nil
+}
+
+// Вызывает fn для 0..n-1, не больше limit вызовов одновременно. Возвращает первую ошибку,
+// после нее новые вызовы не начинаются
+func forEachLimit(n, limit int, fn func(i int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (s *StageSMM) fetchCategories() ([]models.Category, error) {
+	resp, err := s.client.Get(s.baseURL + apiCategoriesPath)
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +119,8 @@ func FetchCategoriesFromAPI() ([]models.Category, error) {
 	return categories, nil
 }
 
-func FetchSubcategoriesFromAPI(categoryID string) ([]models.Subcategory, error) {
-	resp, err := http.Get(apiSubcategoriesEndpoint + categoryID)
+func (s *StageSMM) fetchSubcategories(categoryID string) ([]models.Subcategory, error) {
+	resp, err := s.client.Get(s.baseURL + apiSubcategoriesPath + categoryID)
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +142,9 @@ func FetchSubcategoriesFromAPI(categoryID string) ([]models.Subcategory, error)
 	return subcategories, nil
 }
 
-func FetchServicesFromAPI(subcategoryID string) ([]models.Services, error) {
-	apiUrl := fmt.Sprintf(apiServicesEndpointFormat, subcategoryID)
-	resp, err := http.Get(apiUrl)
+func (s *StageSMM) fetchServices(subcategoryID string) ([]models.Services, error) {
+	apiUrl := s.baseURL + fmt.Sprintf(apiServicesPathFormat, subcategoryID)
+	resp, err := s.client.Get(apiUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -3,42 +3,88 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Cekretik/BoostBot/config"
 	"github.com/Cekretik/BoostBot/models"
-	"github.com/joho/godotenv"
 )
 
-var apiOrdersEndpoint string
-var Token string
+// Адрес API StageSMM без завершающего слэша и токен, задаются STAGESMM_API_URL и STAGESMM_TOKEN
+var (
+	apiBaseURL = "https://api.stagesmm.com"
+	token      string
+)
+
+// Подключает StageSMM поставщиком по умолчанию и источником курса рубля
+func Configure(cfg config.StageSMM) {
+	apiBaseURL = strings.TrimRight(cfg.APIURL, "/")
+	token = cfg.Token
+	Default = NewStageSMM(cfg)
+	Register(Default)
+}
+
+// Клиент API StageSMM
+type StageSMM struct {
+	baseURL        string
+	ordersEndpoint string
+	token          string
+	client         *http.Client
+	// Одновременных запросов при загрузке каталога
+	fetchWorkers int
+}
 
-func init() {
-	// Загрузка переменных окружения
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+func NewStageSMM(cfg config.StageSMM) *StageSMM {
+	return &StageSMM{
+		baseURL:        strings.TrimRight(cfg.APIURL, "/"),
+		ordersEndpoint: cfg.OrdersEndpoint,
+		token:          cfg.Token,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		fetchWorkers:   cfg.FetchWorkers,
 	}
+}
+
+func (s *StageSMM) Name() string { return "stagesmm" }
 
-	// Инициализация глобальных переменных
-	apiOrdersEndpoint = os.Getenv("API_ORDERS_ENDPOINT")
-	Token = os.Getenv("STAGESMM_TOKEN")
+// API StageSMM отдает только полный список заказов, нужные выбираются из него
+func (s *StageSMM) OrderStatus(orderIDs []int) (map[int]OrderStatus, error) {
+	details, err := s.fetchOrders()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[int]bool, len(orderIDs))
+	for _, id := range orderIDs {
+		wanted[id] = true
+	}
+	statuses := make(map[int]OrderStatus)
+	for _, detail := range details {
+		if !wanted[detail.ID] {
+			continue
+		}
+		statuses[detail.ID] = OrderStatus{
+			Status:     detail.Status,
+			Charge:     detail.Charge,
+			StartCount: detail.StartCount,
+			Remains:    detail.Remains,
+		}
+	}
+	return statuses, nil
 }
-func FetchOrders() ([]models.ServiceDetails, error) {
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", apiOrdersEndpoint, nil)
+func (s *StageSMM) fetchOrders() ([]models.ServiceDetails, error) {
+	req, err := http.NewRequest("GET", s.ordersEndpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", Token)
+	req.Header.Add("Authorization", s.token)
 
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -58,8 +104,7 @@ func FetchOrders() ([]models.ServiceDetails, error) {
 	return serviceDetails, nil
 }
 
-func CreateOrder(order models.Order, token string) (models.UserOrders, error) {
-	client := &http.Client{}
+func (s *StageSMM) AddOrder(order models.Order) (int, error) {
 	// Создание данных для запроса из структуры Order
 	data := map[string]interface{}{
 		"id":            order.ID,
@@ -90,45 +135,54 @@ func CreateOrder(order models.Order, token string) (models.UserOrders, error) {
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		return models.UserOrders{}, err
+		return 0, err
 	}
 
-	req, err := http.NewRequest("POST", apiOrdersEndpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", s.ordersEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return models.UserOrders{}, err
+		return 0, err
 	}
 
-	req.Header.Add("Authorization", token)
+	req.Header.Add("Authorization", s.token)
 	req.Header.Add("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
-		return models.UserOrders{}, err
+		return 0, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return 0, fmt.Errorf("stagesmm: unexpected status %s", resp.Status)
+	}
 
 	var responseOrder models.UserOrders
 	if err := json.NewDecoder(resp.Body).Decode(&responseOrder); err != nil {
-		return models.UserOrders{}, err
+		return 0, err
 	}
 
-	return responseOrder, nil
+	return responseOrder.OrderID, nil
 }
 
+// Отмена, докрутка и баланс в API StageSMM недоступны
+func (s *StageSMM) Cancel(int) error { return ErrNotSupported }
+
+func (s *StageSMM) Refill(int) (int, error) { return 0, ErrNotSupported }
+
+func (s *StageSMM) Balance() (Balance, error) { return Balance{}, ErrNotSupported }
+
+// Курсы валют к доллару, например {"rates": {"EUR": 0.92, "KZT": 450}}
 type RatesResponse struct {
-	RUB float64 `json:"RUB"`
+	Rates map[string]float64 `json:"rates"`
 }
 
-var CurrentRate float64
-
 func GetCurrencyRate() (float64, error) {
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://api.stagesmm.com/rates", nil)
+	req, err := http.NewRequest("GET", apiBaseURL+"/rates", nil)
 	if err != nil {
 		return 0, err
 	}
 
-	req.Header.Add("Authorization", Token)
+	req.Header.Add("Authorization", token)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -154,19 +208,20 @@ func GetCurrencyRate() (float64, error) {
 	return rate, nil
 }
 
-func UpdateCurrencyRatePeriodically() {
-	for {
-		rate, err := GetCurrencyRate()
-		if err != nil {
-			log.Printf("Error getting currency rate: %v", err)
-		} else {
-			CurrentRate = rate
-			log.Printf("Updated currency rate: %f", CurrentRate)
-		}
-		time.Sleep(1 * time.Hour)
+// Таблица курсов валют к доллару от внешнего источника
+func GetExchangeRates(url string) (map[string]float64, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rates: unexpected status %s", resp.Status)
 	}
-}
 
-func GetCurrentCurrencyRate() float64 {
-	return CurrentRate
+	var rates RatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rates); err != nil {
+		return nil, err
+	}
+	return rates.Rates, nil
 }
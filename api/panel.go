@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+)
+
+// Клиент SMM-панели со стандартным API v2: POST на один адрес с key и action
+type Panel struct {
+	name   string
+	url    string
+	key    string
+	client *http.Client
+}
+
+func NewPanel(name, apiURL, key string) *Panel {
+	return &Panel{name: name, url: apiURL, key: key, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (p *Panel) Name() string { return p.name }
+
+// Число в ответе панели, приходит и строкой, и числом
+type panelNumber float64
+
+func (n *panelNumber) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "" || raw == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("panel: bad number %s", data)
+	}
+	*n = panelNumber(f)
+	return nil
+}
+
+// Ответ с ошибкой вида {"error": "Incorrect request"}
+type panelError struct {
+	Error string `json:"error"`
+}
+
+func (p *Panel) call(action string, params url.Values, out interface{}) error {
+	form := url.Values{"key": {p.key}, "action": {action}}
+	for name, values := range params {
+		form[name] = values
+	}
+	resp, err := p.client.PostForm(p.url, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("panel %s: unexpected status %s", action, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var failure panelError
+	if json.Unmarshal(body, &failure) == nil && failure.Error != "" {
+		return fmt.Errorf("panel %s: %s", action, failure.Error)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("panel %s: %w", action, err)
+	}
+	return nil
+}
+
+type panelService struct {
+	Service  panelNumber `json:"service"`
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Category string      `json:"category"`
+	Rate     panelNumber `json:"rate"`
+	Min      panelNumber `json:"min"`
+	Max      panelNumber `json:"max"`
+	Refill   bool        `json:"refill"`
+	Cancel   bool        `json:"cancel"`
+	Dripfeed bool        `json:"dripfeed"`
+}
+
+// Панель отдает плоский список услуг с названием категории, например "Instagram Followers".
+// Категория становится разделом, а ее первое слово - соцсетью
+func (p *Panel) Catalog() (Catalog, error) {
+	var services []panelService
+	if err := p.call("services", nil, &services); err != nil {
+		return Catalog{}, err
+	}
+
+	var catalog Catalog
+	seenCategories := make(map[string]bool)
+	seenSubcategories := make(map[string]bool)
+	for _, s := range services {
+		subcategoryName := strings.TrimSpace(s.Category)
+		network := subcategoryName
+		if fields := strings.Fields(subcategoryName); len(fields) > 0 {
+			network = fields[0]
+		}
+		categoryID := strings.ToLower(network)
+		if !seenCategories[categoryID] {
+			seenCategories[categoryID] = true
+			catalog.Categories = append(catalog.Categories, models.Category{ID: categoryID, Name: network})
+		}
+		if !seenSubcategories[subcategoryName] {
+			seenSubcategories[subcategoryName] = true
+			catalog.Subcategories = append(catalog.Subcategories, models.Subcategory{ID: subcategoryName, Name: subcategoryName, CategoryID: categoryID})
+		}
+		id := int(s.Service)
+		catalog.Services = append(catalog.Services, models.Services{
+			ID:         id,
+			ServiceID:  strconv.Itoa(id),
+			Name:       s.Name,
+			Type:       s.Type,
+			CategoryID: subcategoryName,
+			Rate:       float64(s.Rate),
+			Min:        int(s.Min),
+			Max:        int(s.Max),
+			Refill:     s.Refill,
+			Cancel:     s.Cancel,
+			Dripfeed:   s.Dripfeed,
+		})
+	}
+	return catalog, nil
+}
+
+func (p *Panel) AddOrder(order models.Order) (int, error) {
+	params := url.Values{
+		"service": {order.ServiceID},
+		"link":    {order.Link},
+	}
+	optional := map[string]string{
+		"keywords":  order.Keywords,
+		"comments":  order.Comments,
+		"usernames": order.Usernames,
+		"hashtags":  order.Hashtags,
+		"hashtag":   order.Hashtag,
+		"username":  order.Username,
+	}
+	for name, value := range optional {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	numbers := map[string]int{
+		"quantity":      order.Quantity,
+		"answer_number": order.AnswerNumber,
+		"min":           order.Min,
+		"max":           order.Max,
+		"delay":         order.Delay,
+	}
+	for name, value := range numbers {
+		if value != 0 {
+			params.Set(name, strconv.Itoa(value))
+		}
+	}
+
+	var resp struct {
+		Order panelNumber `json:"order"`
+	}
+	if err := p.call("add", params, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Order == 0 {
+		return 0, errors.New("panel add: no order id in response")
+	}
+	return int(resp.Order), nil
+}
+
+// Панель принимает до 100 заказов в одном запросе статуса
+const panelStatusBatch = 100
+
+func (p *Panel) OrderStatus(orderIDs []int) (map[int]OrderStatus, error) {
+	statuses := make(map[int]OrderStatus, len(orderIDs))
+	for start := 0; start < len(orderIDs); start += panelStatusBatch {
+		end := start + panelStatusBatch
+		if end > len(orderIDs) {
+			end = len(orderIDs)
+		}
+		ids := make([]string, 0, end-start)
+		for _, id := range orderIDs[start:end] {
+			ids = append(ids, strconv.Itoa(id))
+		}
+
+		// Неизвестный заказ приходит как {"error": "Incorrect order ID"} и пропускается
+		var resp map[string]struct {
+			Status     string      `json:"status"`
+			Charge     panelNumber `json:"charge"`
+			StartCount panelNumber `json:"start_count"`
+			Remains    panelNumber `json:"remains"`
+			Error      string      `json:"error"`
+		}
+		if err := p.call("status", url.Values{"orders": {strings.Join(ids, ",")}}, &resp); err != nil {
+			return nil, err
+		}
+		for key, status := range resp {
+			id, err := strconv.Atoi(key)
+			if err != nil || status.Error != "" {
+				continue
+			}
+			statuses[id] = OrderStatus{
+				Status:     normalizeStatus(status.Status),
+				Charge:     float64(status.Charge),
+				StartCount: int(status.StartCount),
+				Remains:    int(status.Remains),
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func (p *Panel) Cancel(orderID int) error {
+	var resp []struct {
+		Order  panelNumber     `json:"order"`
+		Cancel json.RawMessage `json:"cancel"`
+	}
+	if err := p.call("cancel", url.Values{"orders": {strconv.Itoa(orderID)}}, &resp); err != nil {
+		return err
+	}
+	for _, result := range resp {
+		var failure panelError
+		if json.Unmarshal(result.Cancel, &failure) == nil && failure.Error != "" {
+			return fmt.Errorf("panel cancel %d: %s", orderID, failure.Error)
+		}
+	}
+	return nil
+}
+
+func (p *Panel) Refill(orderID int) (int, error) {
+	var resp struct {
+		Refill panelNumber `json:"refill"`
+	}
+	if err := p.call("refill", url.Values{"order": {strconv.Itoa(orderID)}}, &resp); err != nil {
+		return 0, err
+	}
+	return int(resp.Refill), nil
+}
+
+func (p *Panel) Balance() (Balance, error) {
+	var resp struct {
+		Balance  panelNumber `json:"balance"`
+		Currency string      `json:"currency"`
+	}
+	if err := p.call("balance", nil, &resp); err != nil {
+		return Balance{}, err
+	}
+	return Balance{Amount: float64(resp.Balance), Currency: resp.Currency}, nil
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/models"
+)
+
+// Поставщик SMM-услуг: каталог, оформление заказов и их статусы
+type Provider interface {
+	Name() string
+	Catalog() (Catalog, error)
+	// Возвращает номер заказа у поставщика
+	AddOrder(order models.Order) (int, error)
+	// Статусы нескольких заказов за один запрос. Неизвестные поставщику заказы в ответ не попадают
+	OrderStatus(orderIDs []int) (map[int]OrderStatus, error)
+	Cancel(orderID int) error
+	// Возвращает номер заявки на докрутку
+	Refill(orderID int) (int, error)
+	Balance() (Balance, error)
+}
+
+// Операция не поддерживается API поставщика
+var ErrNotSupported = errors.New("not supported by provider")
+
+// Каталог поставщика: соцсети, разделы и услуги разделов
+type Catalog struct {
+	Categories    []models.Category
+	Subcategories []models.Subcategory
+	Services      []models.Services
+}
+
+type OrderStatus struct {
+	// PENDING, IN_PROGRESS, COMPLETED, PARTIAL или CANCELED
+	Status     string
+	Charge     float64
+	StartCount int
+	Remains    int
+}
+
+type Balance struct {
+	Amount   float64
+	Currency string
+}
+
+// Основной поставщик: его каталог показывается ботам, у него заказываются услуги без привязок
+var Default Provider
+
+// Подключенные поставщики по имени
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+func Get(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Имена подключенных поставщиков по алфавиту
+func Names() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Подключает панели из SMM_PANELS и PANEL_API_URL и выбирает основного поставщика
+// по SMM_PROVIDER. StageSMM подключается в Configure
+func ConfigureProvider(cfg config.Provider) error {
+	panels, err := cfg.Panels()
+	if err != nil {
+		return err
+	}
+	for _, panel := range panels {
+		Register(NewPanel(panel.Name, panel.URL, panel.Key))
+	}
+	p, ok := Get(cfg.Name)
+	if !ok {
+		return fmt.Errorf("unknown SMM provider %q", cfg.Name)
+	}
+	Default = p
+	return nil
+}
+
+// Услуга поставщика по номеру, который передается в AddOrder
+func FindService(p Provider, serviceID string) (models.Services, bool, error) {
+	catalog, err := p.Catalog()
+	if err != nil {
+		return models.Services{}, false, err
+	}
+	for _, service := range catalog.Services {
+		if strconv.Itoa(service.ID) == serviceID {
+			return service, true, nil
+		}
+	}
+	return models.Services{}, false, nil
+}
+
+// Приводит статусы вида "In progress" или "Cancelled" к статусам заказов бота
+func normalizeStatus(status string) string {
+	status = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(status), " ", "_"))
+	switch status {
+	case "PROCESSING":
+		return "IN_PROGRESS"
+	case "CANCELLED":
+		return "CANCELED"
+	}
+	return status
+}
@@ -0,0 +1,273 @@
+package callback
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Данные кнопки: Prefix + base64url(версия, вид действия, поля действия, подпись).
+// Если данные не помещаются в лимит Telegram, кадр действия сохраняется в Store,
+// а в кнопку попадает ссылка: версия с флагом refFlag и номер записи
+const (
+	Prefix     = "~"
+	Version    = byte(1)
+	MaxDataLen = 64
+
+	refFlag = byte(0x80)
+	macLen  = 8
+
+	// Срок кадра в хранилище после последней записи. Кнопки старше срока устаревают
+	DefaultTTL = 30 * 24 * time.Hour
+)
+
+// Вид действия. Значения попадают в уже отправленные клавиатуры, поэтому их нельзя менять
+type Kind byte
+
+// Действие кнопки - структура с экспортируемыми полями string, bool и целых типов.
+// Поля кодируются по порядку объявления
+type Action interface {
+	Kind() Kind
+}
+
+var (
+	ErrMalformed   = errors.New("callback: malformed data")
+	ErrVersion     = errors.New("callback: unsupported version")
+	ErrSignature   = errors.New("callback: bad signature")
+	ErrUnknownKind = errors.New("callback: unknown action kind")
+	ErrTooLarge    = errors.New("callback: data too large")
+)
+
+var registry = make(map[Kind]reflect.Type)
+
+// Регистрирует типы действий. Вызывается из init пакетов, которые строят клавиатуры
+func Register(actions ...Action) {
+	for _, action := range actions {
+		t := reflect.TypeOf(action)
+		if t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("callback: action %T must be a struct", action))
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if !supportedField(t.Field(i)) {
+				panic(fmt.Sprintf("callback: unsupported field %s in action %T", t.Field(i).Name, action))
+			}
+		}
+		if prev, ok := registry[action.Kind()]; ok && prev != t {
+			panic(fmt.Sprintf("callback: kind %d of %T is already used by %s", action.Kind(), action, prev))
+		}
+		registry[action.Kind()] = t
+	}
+}
+
+type Codec struct {
+	// Ключ HMAC. Без ключа данные не подписываются и не проверяются
+	key   []byte
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func NewCodec(key []byte, store Store) *Codec {
+	return &Codec{key: key, store: store, ttl: DefaultTTL, now: time.Now}
+}
+
+// Кодек всех ботов. main заменяет его кодеком с ключом и хранилищем в базе
+var Default = NewCodec(nil, NewMemoryStore())
+
+func IsEncoded(data string) bool {
+	return strings.HasPrefix(data, Prefix)
+}
+
+func (c *Codec) Encode(action Action) (string, error) {
+	t := reflect.TypeOf(action)
+	if registry[action.Kind()] != t {
+		return "", fmt.Errorf("%w: %T is not registered", ErrUnknownKind, action)
+	}
+	frame := appendFields([]byte{Version, byte(action.Kind())}, reflect.ValueOf(action))
+	if data := c.seal(frame); len(data) <= MaxDataLen {
+		return data, nil
+	}
+	if c.store == nil {
+		return "", ErrTooLarge
+	}
+	id, err := c.store.Put(frame, c.now().Add(c.ttl))
+	if err != nil {
+		return "", err
+	}
+	ref := binary.AppendUvarint([]byte{Version | refFlag}, id)
+	if data := c.seal(ref); len(data) <= MaxDataLen {
+		return data, nil
+	}
+	return "", ErrTooLarge
+}
+
+func (c *Codec) Decode(data string) (Action, error) {
+	if !IsEncoded(data) {
+		return nil, ErrMalformed
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data[len(Prefix):])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if c.key != nil {
+		if len(raw) < macLen+2 {
+			return nil, ErrMalformed
+		}
+		body, mac := raw[:len(raw)-macLen], raw[len(raw)-macLen:]
+		if !hmac.Equal(mac, c.mac(body)) {
+			return nil, ErrSignature
+		}
+		raw = body
+	}
+	if len(raw) < 2 {
+		return nil, ErrMalformed
+	}
+
+	if raw[0]&refFlag != 0 {
+		if raw[0]&^refFlag != Version {
+			return nil, ErrVersion
+		}
+		id, n := binary.Uvarint(raw[1:])
+		if n <= 0 || 1+n != len(raw) || c.store == nil {
+			return nil, ErrMalformed
+		}
+		// Кадры в хранилище записаны сервером, подпись у них не проверяется
+		var expiresAt time.Time
+		if raw, expiresAt, err = c.store.Get(id); err != nil {
+			return nil, err
+		}
+		if !expiresAt.After(c.now()) {
+			return nil, ErrNotFound
+		}
+		if len(raw) < 2 {
+			return nil, ErrMalformed
+		}
+	}
+
+	if raw[0] != Version {
+		return nil, ErrVersion
+	}
+	t, ok := registry[Kind(raw[1])]
+	if !ok {
+		return nil, ErrUnknownKind
+	}
+	v := reflect.New(t).Elem()
+	rest, err := readFields(raw[2:], v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	return v.Interface().(Action), nil
+}
+
+func (c *Codec) PurgeExpiredPeriodically(ctx context.Context, interval time.Duration) {
+	if c.store == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.store.DeleteExpired(c.now()); err != nil {
+			log.Printf("Error deleting expired callback payloads: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Codec) seal(frame []byte) string {
+	if c.key != nil {
+		frame = append(frame[:len(frame):len(frame)], c.mac(frame)...)
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(frame)
+}
+
+func (c *Codec) mac(body []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(body)
+	return h.Sum(nil)[:macLen]
+}
+
+func supportedField(f reflect.StructField) bool {
+	if !f.IsExported() {
+		return false
+	}
+	switch f.Type.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func appendFields(buf []byte, v reflect.Value) []byte {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			buf = binary.AppendUvarint(buf, uint64(f.Len()))
+			buf = append(buf, f.String()...)
+		case reflect.Bool:
+			if f.Bool() {
+				buf = append(buf, 1)
+			} else {
+				buf = append(buf, 0)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			buf = binary.AppendVarint(buf, f.Int())
+		default:
+			buf = binary.AppendUvarint(buf, f.Uint())
+		}
+	}
+	return buf
+}
+
+func readFields(buf []byte, v reflect.Value) ([]byte, error) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < size {
+				return nil, ErrMalformed
+			}
+			f.SetString(string(buf[n : n+int(size)]))
+			buf = buf[n+int(size):]
+		case reflect.Bool:
+			if len(buf) == 0 || buf[0] > 1 {
+				return nil, ErrMalformed
+			}
+			f.SetBool(buf[0] == 1)
+			buf = buf[1:]
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			x, n := binary.Varint(buf)
+			if n <= 0 || f.OverflowInt(x) {
+				return nil, ErrMalformed
+			}
+			f.SetInt(x)
+			buf = buf[n:]
+		default:
+			x, n := binary.Uvarint(buf)
+			if n <= 0 || f.OverflowUint(x) {
+				return nil, ErrMalformed
+			}
+			f.SetUint(x)
+			buf = buf[n:]
+		}
+	}
+	return buf, nil
+}
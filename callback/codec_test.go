@@ -0,0 +1,268 @@
+package callback
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	kindAllFields Kind = 200 + iota
+	kindEmpty
+	kindText
+	kindCount
+)
+
+type allFields struct {
+	S   string
+	B   bool
+	I   int
+	I8  int8
+	I16 int16
+	I32 int32
+	I64 int64
+	U   uint
+	U8  uint8
+	U16 uint16
+	U32 uint32
+	U64 uint64
+}
+
+type empty struct{}
+
+type text struct {
+	Text string
+	Page int
+}
+
+type count struct {
+	N uint8
+}
+
+func (allFields) Kind() Kind { return kindAllFields }
+func (empty) Kind() Kind     { return kindEmpty }
+func (text) Kind() Kind      { return kindText }
+func (count) Kind() Kind     { return kindCount }
+
+func init() {
+	Register(allFields{}, empty{}, text{}, count{})
+}
+
+// Данные кнопки из кадра без проверки полей
+func sealed(c *Codec, frame ...byte) string {
+	return c.seal(frame)
+}
+
+func TestRoundTrip(t *testing.T) {
+	actions := []Action{
+		empty{},
+		allFields{},
+		allFields{S: "привет", B: true, I: -1, I8: math.MinInt8, I16: math.MaxInt16, I32: math.MinInt32, I64: math.MaxInt64,
+			U: 1, U8: math.MaxUint8, U16: math.MaxUint16, U32: math.MaxUint32, U64: math.MaxUint64},
+		allFields{I64: math.MinInt64, U64: 0},
+		text{Text: "", Page: 0},
+		text{Text: "a:b{c}", Page: -7},
+	}
+	codecs := map[string]*Codec{
+		"без ключа": NewCodec(nil, NewMemoryStore()),
+		"с ключом":  NewCodec([]byte("secret"), NewMemoryStore()),
+	}
+	for name, c := range codecs {
+		for _, action := range actions {
+			data, err := c.Encode(action)
+			if err != nil {
+				t.Fatalf("%s: encode %+v: %v", name, action, err)
+			}
+			if len(data) > MaxDataLen || !IsEncoded(data) {
+				t.Fatalf("%s: data %q", name, data)
+			}
+			decoded, err := c.Decode(data)
+			if err != nil {
+				t.Fatalf("%s: decode %+v: %v", name, action, err)
+			}
+			if decoded != action {
+				t.Errorf("%s: decoded %+v, want %+v", name, decoded, action)
+			}
+		}
+	}
+}
+
+func TestEncodeUnregistered(t *testing.T) {
+	type unknown struct{ empty }
+	if _, err := NewCodec(nil, nil).Encode(unknown{}); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	c := NewCodec(nil, NewMemoryStore())
+	data, err := c.Encode(allFields{S: "строка", B: true, I: 300, I64: -5, U: 7, U64: 1 << 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := base64.RawURLEncoding.DecodeString(data[len(Prefix):])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Обрезанный кадр не разбирается, где бы он ни оборвался
+	for n := 0; n < len(frame); n++ {
+		if _, err := c.Decode(sealed(c, frame[:n]...)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("frame[:%d]: err = %v", n, err)
+		}
+	}
+
+	cases := map[string]string{
+		"без префикса":          data[len(Prefix):],
+		"не base64":             Prefix + "!!!",
+		"лишние байты":          sealed(c, append(append([]byte(nil), frame...), 0)...),
+		"длина строки больше":   sealed(c, Version, byte(kindText), 100, 'a', 'b', 0),
+		"огромная длина строки": sealed(c, Version, byte(kindText), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01),
+		"uvarint без конца":     sealed(c, Version, byte(kindCount), 0x80, 0x80),
+		"uvarint переполнен":    sealed(c, Version, byte(kindCount), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01),
+		"значение вне типа":     sealed(c, Version, byte(kindCount), 0x80, 0x02),
+		"bool не 0 и не 1":      sealed(c, append([]byte{Version, byte(kindAllFields), 0, 2}, make([]byte, 10)...)...),
+		"ссылка без номера":     sealed(c, Version|refFlag, 0x80),
+		"ссылка с хвостом":      sealed(c, Version|refFlag, 1, 0),
+	}
+	for name, data := range cases {
+		if _, err := c.Decode(data); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestDecodeVersionAndKind(t *testing.T) {
+	c := NewCodec(nil, NewMemoryStore())
+	if _, err := c.Decode(sealed(c, Version+1, byte(kindEmpty))); !errors.Is(err, ErrVersion) {
+		t.Errorf("version: err = %v", err)
+	}
+	if _, err := c.Decode(sealed(c, (Version+1)|refFlag, 1)); !errors.Is(err, ErrVersion) {
+		t.Errorf("ref version: err = %v", err)
+	}
+	if _, err := c.Decode(sealed(c, Version, 255)); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("kind: err = %v", err)
+	}
+}
+
+func TestDecodeSignature(t *testing.T) {
+	c := NewCodec([]byte("secret"), NewMemoryStore())
+	action := text{Text: "hello world", Page: 3}
+	data, err := c.Encode(action)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := base64.RawURLEncoding.DecodeString(data[len(Prefix):])
+	forged := func(i int) string {
+		b := append([]byte(nil), raw...)
+		b[i] ^= 1
+		return Prefix + base64.RawURLEncoding.EncodeToString(b)
+	}
+	if _, err := c.Decode(forged(len(raw) - 1)); !errors.Is(err, ErrSignature) {
+		t.Errorf("forged mac: err = %v", err)
+	}
+	if _, err := c.Decode(forged(len(raw) - macLen - 1)); !errors.Is(err, ErrSignature) {
+		t.Errorf("forged body: err = %v", err)
+	}
+
+	// Подписано другим ключом
+	other, _ := NewCodec([]byte("other"), nil).Encode(action)
+	if _, err := c.Decode(other); !errors.Is(err, ErrSignature) {
+		t.Errorf("other key: err = %v", err)
+	}
+
+	// Без подписи: длинный кадр не проходит проверку, короткий не разбирается
+	unsigned, _ := NewCodec(nil, nil).Encode(action)
+	if _, err := c.Decode(unsigned); !errors.Is(err, ErrSignature) {
+		t.Errorf("missing mac: err = %v", err)
+	}
+	short, _ := NewCodec(nil, nil).Encode(empty{})
+	if _, err := c.Decode(short); !errors.Is(err, ErrMalformed) {
+		t.Errorf("short without mac: err = %v", err)
+	}
+}
+
+func TestLargeActionGoesToStore(t *testing.T) {
+	action := text{Text: strings.Repeat("длинный запрос ", 10), Page: 2}
+	for name, key := range map[string][]byte{"без ключа": nil, "с ключом": []byte("secret")} {
+		store := NewMemoryStore()
+		c := NewCodec(key, store)
+		data, err := c.Encode(action)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		raw, _ := base64.RawURLEncoding.DecodeString(data[len(Prefix):])
+		if len(data) > MaxDataLen || raw[0]&refFlag == 0 {
+			t.Fatalf("%s: data %q is not a reference", name, data)
+		}
+		decoded, err := c.Decode(data)
+		if err != nil || decoded != action {
+			t.Fatalf("%s: decoded %+v, %v", name, decoded, err)
+		}
+
+		// Одинаковые кадры получают одну ссылку
+		again, _ := c.Encode(action)
+		if again != data || len(store.entries) != 1 {
+			t.Errorf("%s: %q != %q, %d entries", name, again, data, len(store.entries))
+		}
+	}
+
+	if _, err := NewCodec(nil, nil).Encode(action); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("without store: err = %v", err)
+	}
+	if _, err := NewCodec(nil, NewMemoryStore()).Decode(sealed(NewCodec(nil, nil), Version|refFlag, 42)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing ref: err = %v", err)
+	}
+}
+
+func TestStoredActionsExpire(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:callback_payloads?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.CallbackPayload{}); err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]Store{"memory": NewMemoryStore(), "db": NewDBStore(db)}
+	action := text{Text: strings.Repeat("x", 100)}
+	for name, store := range stores {
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := NewCodec(nil, store)
+		c.now = func() time.Time { return now }
+
+		data, err := c.Encode(action)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Повторная запись продлевает срок
+		now = now.Add(DefaultTTL - time.Hour)
+		if again, _ := c.Encode(action); again != data {
+			t.Fatalf("%s: reference changed", name)
+		}
+		now = now.Add(2 * time.Hour)
+		if _, err := c.Decode(data); err != nil {
+			t.Fatalf("%s: refreshed: %v", name, err)
+		}
+
+		now = now.Add(DefaultTTL)
+		if _, err := c.Decode(data); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expired: err = %v", name, err)
+		}
+		if deleted, err := store.DeleteExpired(now); err != nil || deleted != 1 {
+			t.Errorf("%s: deleted %d, %v", name, deleted, err)
+		}
+		if _, _, err := store.Get(1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: after purge: err = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package callback
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("callback: payload not found")
+
+// Хранилище кадров, которые не помещаются в данные кнопки. Одинаковые кадры
+// получают один номер, чтобы пересборка клавиатур не плодила записи, повторная
+// запись продлевает срок. Истекшие кадры хранилище может возвращать, их отбрасывает Codec
+type Store interface {
+	Put(frame []byte, expiresAt time.Time) (uint64, error)
+	Get(id uint64) (frame []byte, expiresAt time.Time, err error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type memoryEntry struct {
+	frame     []byte
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	lastID  uint64
+	entries map[uint64]memoryEntry
+	ids     map[string]uint64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[uint64]memoryEntry), ids: make(map[string]uint64)}
+}
+
+func (s *MemoryStore) Put(frame []byte, expiresAt time.Time) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.ids[string(frame)]
+	if !ok {
+		s.lastID++
+		id = s.lastID
+		s.ids[string(frame)] = id
+	}
+	s.entries[id] = memoryEntry{frame: append([]byte(nil), frame...), expiresAt: expiresAt}
+	return id, nil
+}
+
+func (s *MemoryStore) Get(id uint64) ([]byte, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, time.Time{}, ErrNotFound
+	}
+	return entry.frame, entry.expiresAt, nil
+}
+
+func (s *MemoryStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for id, entry := range s.entries {
+		if !entry.expiresAt.After(now) {
+			delete(s.entries, id)
+			delete(s.ids, string(entry.frame))
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Хранилище в таблице callback_payloads, кнопки работают и после перезапуска
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Put(frame []byte, expiresAt time.Time) (uint64, error) {
+	sum := sha256.Sum256(frame)
+	row := models.CallbackPayload{Hash: hex.EncodeToString(sum[:])}
+	err := s.db.Where(models.CallbackPayload{Hash: row.Hash}).
+		Attrs(models.CallbackPayload{Data: frame}).
+		Assign(models.CallbackPayload{ExpiresAt: expiresAt}).
+		FirstOrCreate(&row).Error
+	if err != nil {
+		return 0, err
+	}
+	return row.ID, nil
+}
+
+func (s *DBStore) Get(id uint64) ([]byte, time.Time, error) {
+	var row models.CallbackPayload
+	err := s.db.First(&row, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return row.Data, row.ExpiresAt, nil
+}
+
+func (s *DBStore) DeleteExpired(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at <= ?", now).Delete(&models.CallbackPayload{})
+	return result.RowsAffected, result.Error
+}
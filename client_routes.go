@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/payment"
+	"github.com/Cekretik/BoostBot/router"
+	"gorm.io/gorm"
+)
+
+func clientState(botID int64) router.StateResolver {
+	return func(c *router.Context) string {
+		state := fsm.Default.State(botID, c.ChatID())
+		if (state == functionality.StatePromoAwaitingCode || state == functionality.StateSearchResults) && c.Text() == "" {
+			return ""
+		}
+		return string(state)
+	}
+}
+
+// Уведомляет администраторов о первом сообщении нового пользователя
+func notifyNewUsers(db *gorm.DB, botID int64) router.Middleware {
+	return func(next router.HandlerFunc) router.HandlerFunc {
+		return func(c *router.Context) {
+			if msg := c.Message(); msg != nil && msg.From != nil {
+				functionality.NotifyAdminsAboutNewUser(c.Bot, msg.From, msg.From.IsPremium, db, botID)
+			}
+			next(c)
+		}
+	}
+}
+
+func subscriptionGate(db *gorm.DB, botID int64) router.Middleware {
+	isSubscribed := func(c *router.Context) bool {
+		from := c.Message().From
+		if from == nil {
+			return false
+		}
+		branding := functionality.GetBranding(db, botID, i18n.For(i18n.Default))
+		subscribed, err := functionality.CheckSubscriptionStatus(c.Bot, db, botID, branding.ChannelID, from.ID, 0.0, from.UserName, from.LanguageCode)
+		if err != nil {
+			log.Printf("Error checking subscription status: %v", err)
+			return false
+		}
+		return subscribed
+	}
+	deny := func(c *router.Context) {
+		tr := functionality.Tr(db, botID, c.ChatID())
+		functionality.SendSubscriptionMessage(c.Bot, c.ChatID(), tr, functionality.GetBranding(db, botID, tr))
+	}
+	return router.RequireSubscription(isSubscribed, deny)
+}
+
+// Отказ в доступе на языке пользователя
+func denyAccess(db *gorm.DB, botID int64) router.HandlerFunc {
+	return func(c *router.Context) {
+		tr := functionality.Tr(db, botID, c.ChatID())
+		if c.Callback() != nil {
+			c.Answer(tr.T("access.denied"))
+			return
+		}
+		c.Reply(tr.T("access.denied_command"))
+	}
+}
+
+// Обработка параметра /start: спец. ссылка или ID пригласившего пользователя
+func handleStartParam(c *router.Context, db *gorm.DB, botID int64) {
+	args := strings.Split(c.Text(), " ")
+	if len(args) < 2 {
+		return
+	}
+	param := args[1]
+	// Проверяем, является ли параметр специальной ссылкой
+	if strings.Contains(param, "_") {
+		functionality.ProcessSpecialLink(c.Bot, c.ChatID(), param, db, botID)
+		return
+	}
+
+	// Обработка реферального ID
+	referrerID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || referrerID == 0 {
+		return
+	}
+	referredID := c.UserID()
+	// Проверяем, существует ли пользователь-реферер
+	var referrer models.UserState
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, referrerID).First(&referrer).Error; err != nil {
+		return
+	}
+	// Проверяем, что реферер и реферал - разные люди
+	if referrer.UserID == referredID {
+		return
+	}
+	// Создаем запись о реферале, если она еще не существует
+	var existingReferral models.Referral
+	if err := db.Where("bot_id = ? AND referrer_id = ? AND referred_id = ?", botID, referrerID, referredID).First(&existingReferral).Error; err != nil {
+		newReferral := models.Referral{
+			BotID:        botID,
+			ReferrerID:   referrerID,
+			ReferredID:   referredID,
+			AmountEarned: 0,
+		}
+		db.Create(&newReferral)
+	}
+}
+
+func pendingService(c *router.Context, db *gorm.DB, botID int64) (models.Services, bool) {
+	tr := functionality.Tr(db, botID, c.ChatID())
+	_, conv, ok := fsm.Get[functionality.OrderConversation](fsm.Default, botID, c.ChatID())
+	if !ok {
+		c.Reply(tr.T("order.restart"))
+		return models.Services{}, false
+	}
+	if conv.ServiceID == 0 {
+		c.Reply(tr.T("order.no_service"))
+		return models.Services{}, false
+	}
+	service, err := database.GetService(db, conv.ServiceID)
+	if err != nil {
+		log.Printf("Error getting service %d: %v", conv.ServiceID, err)
+		c.Reply(tr.T("order.service_error"))
+		return models.Services{}, false
+	}
+	return service, true
+}
+
+// ctx отменяется при остановке бота
+func newClientRouter(ctx context.Context, db *gorm.DB, botID int64, botName string) *router.Router {
+	r := router.New()
+	r.Use(router.Recover(), router.Logging(botName), router.AnswerCallback(), notifyNewUsers(db, botID))
+	r.StateResolver(clientState(botID))
+	requireSubscription := subscriptionGate(db, botID)
+	requireAdmin := router.RequireAdmin(isChannelAdmin, denyAccess(db, botID))
+
+	// Главное меню: кнопки берутся из оформления бота, остальной текст открывает каталог
+	menu := func(c *router.Context) {
+		chatID := c.ChatID()
+		branding := functionality.GetBranding(db, botID, functionality.Tr(db, botID, chatID))
+		switch text := c.Text(); {
+		case text == branding.Menu.Balance:
+			functionality.HandleBalanceCommand(c.Bot, chatID, db, botID)
+		case text == branding.Menu.Referral:
+			functionality.ShowReferralStats(c.Bot, db, botID, chatID)
+		case text == branding.Menu.Profile:
+			functionality.HandleProfileCommand(c.Bot, chatID, db, botID)
+		case branding.ShowSite && text == branding.Menu.Site:
+			functionality.SendSiteMessage(c.Bot, chatID, branding)
+		default:
+			functionality.SendPromotionMessage(c.Bot, chatID, db, botID)
+		}
+	}
+	gatedMenu := requireSubscription(menu)
+	r.Fallback(gatedMenu)
+	r.Command("start", func(c *router.Context) {
+		handleStartParam(c, db, botID)
+		gatedMenu(c)
+	})
+
+	r.Command("search", func(c *router.Context) {
+		_, query, _ := strings.Cut(c.Text(), " ")
+		functionality.HandleSearchCommand(c.Bot, c.ChatID(), db, botID, query)
+	}, requireSubscription)
+
+	r.Command("createpromo", onUpdate(db, functionality.HandleCreatePromoCommand), requireAdmin)
+	r.Command("createurl", onUpdate(db, functionality.HandleCreateUrlCommand), requireAdmin)
+	r.Command("bonus", onUpdate(db, functionality.HandleBonusCommand), requireAdmin)
+	r.Command("broadcast", func(c *router.Context) {
+		functionality.HandleBroadcastCommand(ctx, c.Bot, c.Update, db, botID)
+	}, requireAdmin)
+
+	// "Отмена" на любом языке завершает активный диалог: заказ, пополнение или ввод промокода
+	cancel := func(c *router.Context) {
+		if !fsm.Default.Clear(botID, c.ChatID()) {
+			gatedMenu(c)
+			return
+		}
+		functionality.SendStandardKeyboard(c.Bot, c.ChatID(), db, botID)
+	}
+	for _, text := range i18n.All("common.cancel") {
+		r.Text(text, cancel)
+	}
+
+	r.State(string(functionality.StatePromoAwaitingCode), func(c *router.Context) {
+		fsm.Default.Clear(botID, c.ChatID())
+		functionality.ProcessPromoCodeInput(c.Bot, c.ChatID(), c.Text(), db, botID)
+	})
+	r.State(string(functionality.StateSearchAwaitingQuery), func(c *router.Context) {
+		functionality.HandleSearchInput(c.Bot, c.ChatID(), db, botID, c.Text())
+	})
+	// После /search текст уточняет поиск, кнопки меню закрывают его
+	r.State(string(functionality.StateSearchResults), func(c *router.Context) {
+		branding := functionality.GetBranding(db, botID, functionality.Tr(db, botID, c.ChatID()))
+		if branding.Menu.Has(c.Text()) {
+			fsm.Default.Clear(botID, c.ChatID())
+			gatedMenu(c)
+			return
+		}
+		functionality.HandleSearchInput(c.Bot, c.ChatID(), db, botID, c.Text())
+	})
+	r.State(string(payment.StatePaymentAwaitingAmount), func(c *router.Context) {
+		payment.HandlePaymentInput(db, botID, c.Bot, c.ChatID(), c.Text())
+	})
+	r.State(string(payment.StatePaymentAwaitingAmountAAIO), func(c *router.Context) {
+		payment.HandlePaymentInputAAIO(db, botID, c.Bot, c.ChatID(), c.Text())
+	})
+	orderInput := func(c *router.Context) {
+		if service, ok := pendingService(c, db, botID); ok {
+			functionality.HandleUserInput(db, botID, c.Bot, c.Update, service)
+		}
+	}
+	r.State(string(functionality.StateOrderAwaitingLink), orderInput)
+	r.State(string(functionality.StateOrderAwaitingQuantity), orderInput)
+
+	r.Action(functionality.Replenish{}, func(c *router.Context) {
+		payment.HandleReplenishCommand(c.Bot, db, botID, c.ChatID())
+	})
+	for _, data := range []string{"cryptomus_USDT", "cryptomus_BTC", "cryptomus_MATIC", "cryptomus_OTHER"} {
+		r.Callback(data, func(c *router.Context) {
+			payment.HandleCryptomusButton(c.Bot, c.ChatID(), db, botID)
+		})
+	}
+	for _, data := range []string{"AAIO_SBP", "AAIO_RU"} {
+		r.Callback(data, func(c *router.Context) {
+			payment.HandleAAIOButton(c.Bot, c.ChatID(), db, botID)
+		})
+	}
+	r.Action(functionality.SetCurrency{}, func(c *router.Context) {
+		code := c.Action.(functionality.SetCurrency).Currency
+		functionality.HandleChangeCurrency(c.Bot, c.ChatID(), db, botID, code)
+	})
+	r.Action(functionality.SetLanguage{}, func(c *router.Context) {
+		language := c.Action.(functionality.SetLanguage).Language
+		functionality.HandleChangeLanguage(c.Bot, c.ChatID(), db, botID, language)
+	})
+	r.Action(functionality.SetFavoriteAlerts{}, func(c *router.Context) {
+		muted := c.Action.(functionality.SetFavoriteAlerts).Muted
+		functionality.HandleFavoriteAlerts(c.Bot, c.ChatID(), db, botID, muted)
+	})
+	r.Action(functionality.ShowFavorites{}, func(c *router.Context) {
+		functionality.HandleFavoritesCommand(c.Bot, db, botID, c.ChatID())
+	})
+	r.Action(functionality.EnterPromo{}, func(c *router.Context) {
+		functionality.HandlePromoCommand(c.Bot, c.ChatID(), db, botID)
+	})
+	r.Action(functionality.ShowOrders{}, func(c *router.Context) {
+		functionality.HandleOrdersCommand(c.Bot, c.ChatID(), db, botID)
+	})
+	r.Action(functionality.ShowSettings{}, func(c *router.Context) {
+		functionality.SendSettingsKeyboard(c.Bot, c.ChatID(), db, botID)
+	})
+	r.Action(functionality.ShowSupport{}, func(c *router.Context) {
+		functionality.TechSupMessage(c.Bot, c.ChatID(), db, botID)
+	})
+	r.Action(functionality.Noop{}, func(c *router.Context) {})
+	// Кнопки старого формата и данные с неверной подписью
+	r.CallbackFallback(func(c *router.Context) {
+		c.Answer(functionality.Tr(db, botID, c.ChatID()).T("callback.stale"))
+	})
+
+	r.Action(functionality.Favorite{}, func(c *router.Context) {
+		functionality.HandleAddToFavoritesCallback(c.Bot, db, botID, c.Callback(), c.Action.(functionality.Favorite))
+		c.MarkAnswered()
+	})
+
+	r.Action(functionality.OpenCategory{}, func(c *router.Context) {
+		functionality.HandleOpenCategory(c.Bot, db, botID, c.ChatID(), c.MessageID(), c.Action.(functionality.OpenCategory).CategoryID)
+	})
+	r.Action(functionality.CategoryPage{}, func(c *router.Context) {
+		a := c.Action.(functionality.CategoryPage)
+		functionality.HandleCategoryPage(c.Bot, db, botID, c.ChatID(), c.MessageID(), a.CategoryID, a.Page)
+	})
+	r.Action(functionality.OpenSubcategory{}, func(c *router.Context) {
+		functionality.HandleOpenSubcategory(c.Bot, db, botID, c.ChatID(), c.MessageID(), c.Action.(functionality.OpenSubcategory).SubcategoryID)
+	})
+	r.Action(functionality.ServicePage{}, func(c *router.Context) {
+		a := c.Action.(functionality.ServicePage)
+		functionality.HandleServicePage(c.Bot, db, botID, c.ChatID(), c.MessageID(), a.SubcategoryID, a.Page)
+	})
+	r.Action(functionality.BackToSubcategories{}, func(c *router.Context) {
+		functionality.HandleBackToSubcategories(c.Bot, db, botID, c.ChatID(), c.MessageID(), c.Action.(functionality.BackToSubcategories).SubcategoryID)
+	})
+	r.Action(functionality.Search{}, func(c *router.Context) {
+		functionality.StartSearch(c.Bot, c.ChatID(), db, botID)
+	})
+	r.Action(functionality.SearchPage{}, func(c *router.Context) {
+		page := c.Action.(functionality.SearchPage).Page
+		if !functionality.HandleSearchPage(c.Bot, db, botID, c.ChatID(), c.MessageID(), page) {
+			c.Answer(functionality.Tr(db, botID, c.ChatID()).T("callback.stale"))
+		}
+	})
+	r.Action(functionality.ServiceInfo{}, func(c *router.Context) {
+		functionality.HandleServiceInfo(c.Bot, db, botID, c.ChatID(), c.MessageID(), c.Action.(functionality.ServiceInfo).ServiceID)
+	})
+
+	r.Action(functionality.OrderService{}, func(c *router.Context) {
+		serviceID := c.Action.(functionality.OrderService).ServiceID
+		service, err := database.GetService(db, serviceID)
+		if err != nil {
+			log.Printf("Error getting service %d: %v", serviceID, err)
+			c.Reply(functionality.Tr(db, botID, c.ChatID()).T("order.service_error"))
+			return
+		}
+		functionality.HandleOrderCommand(c.Bot, c.ChatID(), db, botID, service)
+	})
+	r.Action(functionality.Buy{}, func(c *router.Context) {
+		if service, ok := pendingService(c, db, botID); ok {
+			functionality.HandlePurchase(db, botID, c.Bot, c.ChatID(), service)
+		}
+	})
+
+	return r
+}
@@ -1,68 +1,94 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/supervisor"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
 
-var initializedBots map[string]bool = make(map[string]bool)
+const (
+	reconcileBotsInterval = 30 * time.Second
+	// Как часто работающий клон проверяет, что его токен не отозван
+	tokenCheckInterval = time.Minute
+)
 
-func InitializeBot(db *gorm.DB, token string) {
-	bot, err := tgbotapi.NewBotAPI(token)
-	if err != nil {
-		log.Printf("Ошибка при инициализации бота с токеном %s: %v", token, err)
-		if err := db.Model(&models.BotOwners{}).Where("token = ?", token).Update("running", false).Error; err != nil {
-			log.Printf("Не удалось обновить статус бота: %v", err)
-		}
-		return
-	}
+var (
+	botSupervisor *supervisor.Supervisor
+	// Бот-менеджер, через который владельцам отправляются уведомления о клонах
+	managerBot atomic.Pointer[tgbotapi.BotAPI]
+)
 
-	log.Printf("Бот с токеном %s успешно запущен", token)
-	ProcessMessages(bot, db)
+func NewBotSupervisor(db *gorm.DB) *supervisor.Supervisor {
+	return supervisor.New(db, func(ctx context.Context, bot *tgbotapi.BotAPI, botID int64) error {
+		return ProcessMessages(ctx, bot, db, botID)
+	}, NotifyTokenRevoked)
 }
 
-func LoadActiveTokens(db *gorm.DB) ([]models.BotOwners, error) {
-	var activeBots []models.BotOwners
-	err := db.Where("running = ?", true).Find(&activeBots).Error
-	if err != nil {
-		return nil, err
+func NotifyTokenRevoked(botOwner models.BotOwners) {
+	manager := managerBot.Load()
+	if manager == nil || botOwner.UserID == 0 {
+		return
+	}
+	// Уведомление приходит вне диалога, поэтому язык владельца неизвестен
+	tr := i18n.For(i18n.Default)
+	msg := tgbotapi.NewMessage(botOwner.UserID, tr.T("manager.token.revoked", i18n.Args{"bot": botOwner.BotName}))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.token.replace"), fmt.Sprintf("bottoken:%d", botOwner.ID)),
+		),
+	)
+	if _, err := sender.Send(manager, msg); err != nil {
+		log.Printf("Не удалось уведомить владельца бота %d об отозванном токене: %v", botOwner.ID, err)
 	}
-	return activeBots, nil
 }
 
-func RunBots(db *gorm.DB) {
-	go func() {
-		for token := range NewTokensChannel {
-			if _, ok := initializedBots[token]; !ok {
-				InitializeBot(db, token)
-				initializedBots[token] = true
-			}
-		}
-	}()
+func IsBotRunning(botID int64) bool {
+	return botSupervisor.IsRunning(botID)
+}
 
-	for {
-		activeBots, err := LoadActiveTokens(db)
-		if err != nil {
-			log.Printf("Ошибка при загрузке активных ботов: %v", err)
-			time.Sleep(10 * time.Second) // Пауза перед следующей попыткой
-			continue
-		}
+func StopBot(botID int64) {
+	botSupervisor.Stop(botID)
+}
+
+func StartBot(botOwner models.BotOwners) {
+	botSupervisor.Start(botOwner)
+}
 
-		for _, botOwner := range activeBots {
-			if _, ok := initializedBots[botOwner.Token]; !ok {
-				go InitializeBot(db, botOwner.Token)
-				initializedBots[botOwner.Token] = true
-			}
-		}
+func RestartBot(botOwner models.BotOwners) {
+	botSupervisor.Restart(botOwner)
+}
 
-		time.Sleep(10 * time.Minute)
+func DescribeBotState(tr i18n.Localizer, botID int64) string {
+	status, _ := botSupervisor.Status(botID)
+	switch status.State {
+	case supervisor.StateStarting:
+		return tr.T("manager.state.starting")
+	case supervisor.StateRunning:
+		return tr.T("manager.state.running")
+	case supervisor.StateBackoff:
+		return tr.T("manager.state.backoff", i18n.Args{"error": status.LastError})
+	case supervisor.StateFailed:
+		return tr.T("manager.state.failed", i18n.Args{"error": status.LastError})
+	case supervisor.StateRevoked:
+		return tr.T("manager.state.revoked")
+	default:
+		return tr.T("manager.state.stopped")
 	}
 }
 
+func RunBots(ctx context.Context) {
+	botSupervisor.Run(ctx, reconcileBotsInterval)
+}
+
 func CountUserBots(db *gorm.DB, userID int64) (int64, error) {
 	var count int64
 	err := db.Model(&models.BotOwners{}).Where("user_id = ?", userID).Count(&count).Error
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Настройки приложения. Значения читаются из переменных окружения, необязательного
+// файла .env и необязательного YAML-файла, ключ в YAML - имя переменной в любом регистре.
+// Приоритет: окружение, затем .env, затем YAML, затем значение по умолчанию
+type Config struct {
+	Telegram Telegram
+	Database Database
+	StageSMM StageSMM
+	Provider Provider
+	Currency Currency
+	Payment  Payment
+	Delivery Delivery
+	Updates  Updates
+	// Меняется без перезапуска
+	Runtime Runtime
+}
+
+type Telegram struct {
+	Token string `env:"TOKEN_BOT" required:"true"`
+	// Ссылка на основного бота для реферальных ссылок, например https://t.me/boost_bot
+	BotLink string `env:"BOT_LINK"`
+	// Канал администраторов платформы и канал для обязательной подписки по умолчанию
+	ChannelID   int64  `env:"CHANNEL_ID" required:"true"`
+	ChannelLink string `env:"CHANNEL_LINK"`
+	// Подпись данных кнопок, без нее данные не подписываются
+	CallbackSecret string `env:"CALLBACK_SECRET"`
+}
+
+type Database struct {
+	DSN string `env:"DSN" required:"true"`
+	// Ключ AES-256 для токенов клонов в base64 или hex
+	TokenEncryptionKey string `env:"TOKEN_ENCRYPTION_KEY" required:"true"`
+}
+
+type StageSMM struct {
+	APIURL         string `env:"STAGESMM_API_URL" default:"https://api.stagesmm.com"`
+	Token          string `env:"STAGESMM_TOKEN" required:"true"`
+	OrdersEndpoint string `env:"API_ORDERS_ENDPOINT" required:"true"`
+	// Одновременных запросов при загрузке каталога
+	FetchWorkers int `env:"STAGESMM_FETCH_WORKERS" default:"4"`
+}
+
+type Provider struct {
+	// Основной поставщик: stagesmm, panel или имя панели из SMM_PANELS
+	Name string `env:"SMM_PROVIDER" default:"stagesmm"`
+	// Панель со стандартным API v2 под именем panel
+	PanelURL string `env:"PANEL_API_URL"`
+	PanelKey string `env:"PANEL_API_KEY"`
+	// Дополнительные панели в формате "имя|адрес|ключ" через точку с запятой
+	ExtraPanels string `env:"SMM_PANELS"`
+}
+
+type Panel struct {
+	Name string
+	URL  string
+	Key  string
+}
+
+// Все панели со стандартным API: PANEL_API_URL и записи SMM_PANELS
+func (p Provider) Panels() ([]Panel, error) {
+	var panels []Panel
+	if p.PanelURL != "" || p.PanelKey != "" {
+		if p.PanelURL == "" || p.PanelKey == "" {
+			return nil, errors.New("PANEL_API_URL and PANEL_API_KEY must be set together")
+		}
+		panels = append(panels, Panel{Name: "panel", URL: p.PanelURL, Key: p.PanelKey})
+	}
+	seen := map[string]bool{"stagesmm": true, "panel": len(panels) > 0}
+	for _, entry := range strings.Split(p.ExtraPanels, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("SMM_PANELS: expected name|url|key, got %q", entry)
+		}
+		panel := Panel{Name: strings.TrimSpace(parts[0]), URL: strings.TrimSpace(parts[1]), Key: strings.TrimSpace(parts[2])}
+		if panel.Name == "" || panel.URL == "" || panel.Key == "" {
+			return nil, fmt.Errorf("SMM_PANELS: empty field in %q", entry)
+		}
+		if seen[panel.Name] {
+			return nil, fmt.Errorf("SMM_PANELS: duplicate provider name %q", panel.Name)
+		}
+		seen[panel.Name] = true
+		panels = append(panels, panel)
+	}
+	return panels, nil
+}
+
+type Currency struct {
+	// Курсы валют к доллару в формате {"rates": {"EUR": 0.92}}. Курс рубля берется из StageSMM
+	ExchangeRatesURL string `env:"EXCHANGE_RATES_URL" default:"https://open.er-api.com/v6/latest/USD"`
+}
+
+type Payment struct {
+	Port string `env:"PORT" required:"true"`
+	// Публичный адрес HTTP-сервера для уведомлений платежных систем
+	URLCallback       string `env:"URL_CALLBACK"`
+	CryptomusMerchant string `env:"CRYPTOMUS_MERCHANT"`
+	CryptomusAPIKey   string `env:"CRYPTOMUS_APIKEY"`
+	AAIOShopID        string `env:"AAIO_SHOPID"`
+	AAIOKey1          string `env:"AAIO_KEY1"`
+	AAIOKey2          string `env:"AAIO_KEY2"`
+}
+
+type Delivery struct {
+	// polling или webhook
+	Mode string `env:"BOT_DELIVERY_MODE" default:"polling"`
+	// По умолчанию совпадает с URL_CALLBACK
+	WebhookURL string `env:"WEBHOOK_URL"`
+}
+
+type Updates struct {
+	Workers    int `env:"BOT_WORKERS" default:"4"`
+	QueueLimit int `env:"BOT_UPDATE_QUEUE" default:"100"`
+	// defer или drop
+	Overflow string `env:"BOT_UPDATE_OVERFLOW" default:"defer"`
+}
+
+type Runtime struct {
+	// Наценка платформы в процентах, общая для всех ботов
+	PricePercent float64 `env:"PRICE_PERCENT" default:"0"`
+	// Бонус за подписку в рублях и сколько пользователей получат его после /bonus
+	SubscriptionBonusRUB   float64       `env:"SUBSCRIPTION_BONUS_RUB" default:"25"`
+	SubscriptionBonusLimit int64         `env:"SUBSCRIPTION_BONUS_LIMIT" default:"1"`
+	CatalogSyncInterval    time.Duration `env:"CATALOG_SYNC_INTERVAL" default:"1h"`
+	OrdersSyncInterval     time.Duration `env:"ORDERS_SYNC_INTERVAL" default:"30m"`
+	CurrencyRateInterval   time.Duration `env:"CURRENCY_RATE_INTERVAL" default:"1h"`
+	// Выбор поставщика для услуги с привязками: cheapest - самый дешевый,
+	// preferred - по приоритету привязок. При ошибке заказ уходит следующему
+	RoutingPolicy string `env:"SMM_ROUTING" default:"cheapest"`
+	// Приоритет основного поставщика при SMM_ROUTING=preferred. Привязки по умолчанию
+	// получают приоритет 1 и идут раньше основного поставщика
+	DefaultProviderPriority int `env:"SMM_DEFAULT_PRIORITY" default:"10"`
+	// Изменение цены в процентах, о котором сообщается добавившим услугу в избранное
+	FavoriteAlertThreshold float64 `env:"FAVORITE_ALERT_THRESHOLD" default:"5"`
+}
+
+// Файлы конфигурации. Отсутствующий файл пропускается
+type Sources struct {
+	EnvFile  string
+	YAMLFile string
+}
+
+// CONFIG_FILE задает путь к YAML-файлу, по умолчанию config.yaml
+func DefaultSources() Sources {
+	yamlFile := os.Getenv("CONFIG_FILE")
+	if yamlFile == "" {
+		yamlFile = "config.yaml"
+	}
+	return Sources{EnvFile: ".env", YAMLFile: yamlFile}
+}
+
+// Ошибки всех невалидных ключей сразу, чтобы не исправлять их по одной
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+func Load(src Sources) (*Config, error) {
+	values, err := readSources(src)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	var problems []string
+	decode(reflect.ValueOf(cfg).Elem(), values, &problems)
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func readSources(src Sources) (map[string]string, error) {
+	values := make(map[string]string)
+	if src.YAMLFile != "" {
+		fromYAML, err := readYAMLFile(src.YAMLFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for key, value := range fromYAML {
+			values[key] = value
+		}
+	}
+	if src.EnvFile != "" {
+		fromEnv, err := godotenv.Read(src.EnvFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", src.EnvFile, err)
+		}
+		for key, value := range fromEnv {
+			values[strings.ToUpper(key)] = value
+		}
+	}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok && value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func decode(v reflect.Value, values map[string]string, problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		name, ok := field.Tag.Lookup("env")
+		if !ok {
+			if fv.Kind() == reflect.Struct {
+				decode(fv, values, problems)
+			}
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok || strings.TrimSpace(raw) == "" {
+			if field.Tag.Get("required") == "true" {
+				*problems = append(*problems, fmt.Sprintf("%s is required", name))
+				continue
+			}
+			raw, ok = field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+		}
+		if err := setValue(fv, strings.TrimSpace(raw)); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+}
+
+func setValue(fv reflect.Value, raw string) error {
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected duration like 30m or 1h, got %q", raw)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected integer, got %q", raw)
+		}
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected number, got %q", raw)
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	switch c.Delivery.Mode {
+	case "polling":
+	case "webhook":
+		if c.Delivery.WebhookURL == "" && c.Payment.URLCallback == "" {
+			problems = append(problems, "WEBHOOK_URL or URL_CALLBACK is required when BOT_DELIVERY_MODE=webhook")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("BOT_DELIVERY_MODE: expected polling or webhook, got %q", c.Delivery.Mode))
+	}
+	if panels, err := c.Provider.Panels(); err != nil {
+		problems = append(problems, err.Error())
+	} else if c.Provider.Name != "stagesmm" {
+		found := false
+		for _, panel := range panels {
+			found = found || panel.Name == c.Provider.Name
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("SMM_PROVIDER: expected stagesmm or a configured panel, got %q", c.Provider.Name))
+		}
+	}
+	if c.Updates.Overflow != "defer" && c.Updates.Overflow != "drop" {
+		problems = append(problems, fmt.Sprintf("BOT_UPDATE_OVERFLOW: expected defer or drop, got %q", c.Updates.Overflow))
+	}
+	if c.StageSMM.FetchWorkers <= 0 {
+		problems = append(problems, "STAGESMM_FETCH_WORKERS must be positive")
+	}
+	if c.Updates.Workers <= 0 {
+		problems = append(problems, "BOT_WORKERS must be positive")
+	}
+	if c.Updates.QueueLimit <= 0 {
+		problems = append(problems, "BOT_UPDATE_QUEUE must be positive")
+	}
+	return append(problems, c.Runtime.validate()...)
+}
+
+func (r Runtime) validate() []string {
+	var problems []string
+	if r.PricePercent < 0 {
+		problems = append(problems, "PRICE_PERCENT must not be negative")
+	}
+	if r.SubscriptionBonusRUB < 0 {
+		problems = append(problems, "SUBSCRIPTION_BONUS_RUB must not be negative")
+	}
+	if r.SubscriptionBonusLimit < 0 {
+		problems = append(problems, "SUBSCRIPTION_BONUS_LIMIT must not be negative")
+	}
+	if r.FavoriteAlertThreshold < 0 {
+		problems = append(problems, "FAVORITE_ALERT_THRESHOLD must not be negative")
+	}
+	if r.RoutingPolicy != "cheapest" && r.RoutingPolicy != "preferred" {
+		problems = append(problems, fmt.Sprintf("SMM_ROUTING: expected cheapest or preferred, got %q", r.RoutingPolicy))
+	}
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"CATALOG_SYNC_INTERVAL", r.CatalogSyncInterval},
+		{"ORDERS_SYNC_INTERVAL", r.OrdersSyncInterval},
+		{"CURRENCY_RATE_INTERVAL", r.CurrencyRateInterval},
+	}
+	for _, interval := range intervals {
+		if interval.value < time.Minute {
+			problems = append(problems, fmt.Sprintf("%s must be at least 1m", interval.name))
+		}
+	}
+	return problems
+}
+
+// Текущие значения настроек, меняющихся без перезапуска. До загрузки конфигурации
+// возвращаются значения по умолчанию
+func Current() Runtime {
+	return *current.Load()
+}
+
+var current atomic.Pointer[Runtime]
+
+func init() {
+	var defaults Runtime
+	var problems []string
+	decode(reflect.ValueOf(&defaults).Elem(), nil, &problems)
+	current.Store(&defaults)
+}
+
+// Делает настройки cfg.Runtime текущими
+func Apply(cfg *Config) {
+	runtime := cfg.Runtime
+	current.Store(&runtime)
+}
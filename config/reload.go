@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// Перечитывает источники и применяет новые значения Runtime. Остальные настройки
+// действуют до перезапуска, их изменение только логируется. При ошибке валидации
+// остаются прежние значения. Окружение процесса не меняется, поэтому перезагружаемые
+// настройки нужно задавать в .env или YAML-файле
+func Reload(src Sources, loaded *Config) error {
+	cfg, err := Load(src)
+	if err != nil {
+		return err
+	}
+	if changed := changedKeys(reflect.ValueOf(*loaded), reflect.ValueOf(*cfg)); len(changed) > 0 {
+		log.Printf("Изменены настройки %v, они вступят в силу после перезапуска", changed)
+	}
+	old := Current()
+	Apply(cfg)
+	if old != cfg.Runtime {
+		log.Printf("Настройки обновлены: %+v", cfg.Runtime)
+	}
+	return nil
+}
+
+// Ключи изменившихся настроек, кроме Runtime
+func changedKeys(old, new reflect.Value) []string {
+	var keys []string
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == reflect.TypeOf(Runtime{}) {
+			continue
+		}
+		name, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				keys = append(keys, changedKeys(old.Field(i), new.Field(i))...)
+			}
+			continue
+		}
+		if !old.Field(i).Equal(new.Field(i)) {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// Перезагружает настройки по SIGHUP и при изменении файлов конфигурации
+func Watch(ctx context.Context, src Sources, loaded *Config, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	modified := modTimes(src)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			current := modTimes(src)
+			if current == modified {
+				continue
+			}
+			modified = current
+		}
+		if err := Reload(src, loaded); err != nil {
+			log.Printf("Настройки не перезагружены: %v", err)
+		}
+	}
+}
+
+func modTimes(src Sources) [2]time.Time {
+	var times [2]time.Time
+	for i, path := range []string{src.EnvFile, src.YAMLFile} {
+		if info, err := os.Stat(path); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
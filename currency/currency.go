@@ -0,0 +1,196 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
+)
+
+// Балансы и цены хранятся в долларах, валюта пользователя влияет только на показ и ввод сумм
+const (
+	USD     = "USD"
+	Default = "RUB"
+)
+
+// Курс валюты: сколько единиц валюты стоит один доллар
+type RateSource func(code string) (float64, error)
+
+type Currency struct {
+	Code   string
+	Symbol string
+	// Символ пишется после суммы, например "12.50 USDT"
+	SymbolAfter bool
+	// Знаков после запятой при показе суммы
+	Decimals int
+	Source   RateSource
+}
+
+var exchangeRatesURL string
+
+func Configure(cfg config.Currency) {
+	exchangeRatesURL = cfg.ExchangeRatesURL
+}
+
+var (
+	// Валюта привязана к доллару
+	Fixed RateSource = func(string) (float64, error) { return 1, nil }
+	// Курс рубля из API StageSMM
+	StageSMM RateSource = func(string) (float64, error) { return api.GetCurrencyRate() }
+	// Курс из таблицы внешнего источника EXCHANGE_RATES_URL
+	Exchange RateSource = func(code string) (float64, error) {
+		rates, err := api.GetExchangeRates(exchangeRatesURL)
+		if err != nil {
+			return 0, err
+		}
+		rate, ok := rates[code]
+		if !ok {
+			return 0, fmt.Errorf("no exchange rate for %s", code)
+		}
+		return rate, nil
+	}
+)
+
+// Валюты в порядке показа в настройках
+var registry = []Currency{
+	{Code: "RUB", Symbol: "₽", Decimals: 2, Source: StageSMM},
+	{Code: "USD", Symbol: "$", Decimals: 2, Source: Fixed},
+	{Code: "EUR", Symbol: "€", Decimals: 2, Source: Exchange},
+	{Code: "UAH", Symbol: "₴", Decimals: 2, Source: Exchange},
+	{Code: "KZT", Symbol: "₸", Decimals: 0, Source: Exchange},
+	// Стейблкоин считается равным доллару
+	{Code: "USDT", Symbol: " USDT", SymbolAfter: true, Decimals: 2, Source: Fixed},
+}
+
+func Get(code string) (Currency, bool) {
+	for _, c := range registry {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Currency{}, false
+}
+
+// Коды поддерживаемых валют в порядке показа
+func Codes() []string {
+	codes := make([]string, 0, len(registry))
+	for _, c := range registry {
+		codes = append(codes, c.Code)
+	}
+	return codes
+}
+
+// Неизвестная или пустая валюта заменяется валютой по умолчанию
+func Normalize(code string) string {
+	if _, ok := Get(code); ok {
+		return code
+	}
+	return Default
+}
+
+// Последние полученные курсы. Курс запрашивается при первом обращении
+// и затем обновляется UpdateRatesPeriodically
+var (
+	ratesMu sync.RWMutex
+	rates   = make(map[string]float64)
+)
+
+func SetRate(code string, rate float64) {
+	ratesMu.Lock()
+	defer ratesMu.Unlock()
+	rates[code] = rate
+}
+
+func Rate(code string) (float64, error) {
+	c, ok := Get(code)
+	if !ok {
+		return 0, fmt.Errorf("unknown currency %q", code)
+	}
+	ratesMu.RLock()
+	rate, ok := rates[code]
+	ratesMu.RUnlock()
+	if ok {
+		return rate, nil
+	}
+	return refresh(c)
+}
+
+func refresh(c Currency) (float64, error) {
+	rate, err := c.Source(c.Code)
+	if err != nil {
+		return 0, fmt.Errorf("%s rate: %w", c.Code, err)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("%s rate: invalid value %v", c.Code, rate)
+	}
+	SetRate(c.Code, rate)
+	return rate, nil
+}
+
+func FromUSD(usd float64, code string) (float64, error) {
+	rate, err := Rate(code)
+	if err != nil {
+		return 0, err
+	}
+	return usd * rate, nil
+}
+
+func ToUSD(amount float64, code string) (float64, error) {
+	rate, err := Rate(code)
+	if err != nil {
+		return 0, err
+	}
+	return amount / rate, nil
+}
+
+// Сумма в валюте code с ее символом и числом знаков, например "₽1250.50" или "12.50 USDT"
+func Format(amount float64, code string) string {
+	c, ok := Get(code)
+	if !ok {
+		return strconv.FormatFloat(amount, 'f', 2, 64) + " " + code
+	}
+	value := strconv.FormatFloat(amount, 'f', c.Decimals, 64)
+	if c.SymbolAfter {
+		return value + c.Symbol
+	}
+	return c.Symbol + value
+}
+
+// Сумма в долларах, показанная в валюте code. Пока курс недоступен, сумма показывается в долларах
+func FormatUSD(usd float64, code string) string {
+	amount, err := FromUSD(usd, code)
+	if err != nil {
+		log.Printf("Error converting to %s: %v", code, err)
+		return Format(usd, USD)
+	}
+	return Format(amount, code)
+}
+
+// Обновляет курсы всех валют с интервалом CURRENCY_RATE_INTERVAL
+func UpdateRatesPeriodically(ctx context.Context) {
+	for {
+		var updated []string
+		for _, c := range registry {
+			rate, err := refresh(c)
+			if err != nil {
+				log.Printf("Error getting currency rate: %v", err)
+				continue
+			}
+			updated = append(updated, fmt.Sprintf("%s=%f", c.Code, rate))
+		}
+		if len(updated) > 0 {
+			log.Printf("Updated currency rates: %s", strings.Join(updated, " "))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.Current().CurrencyRateInterval):
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"log"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+// Таблицы, строки которых принадлежат конкретному боту
+var tenantTables = []interface{}{
+	&models.UserState{},
+	&models.UserOrders{},
+	&models.Payments{},
+	&models.Referral{},
+	&models.UsedPromoCode{},
+}
+
+func GetBotByToken(db *gorm.DB, token string) (models.BotOwners, error) {
+	var botOwner models.BotOwners
+	result := db.Where("token_hash = ?", HashToken(token)).First(&botOwner)
+	return botOwner, result.Error
+}
+
+// Основной бот (TOKEN_BOT) хранится как обычный арендатор. Флаг running не
+// выставляется, чтобы RunBots не запускал для него второй цикл обновлений.
+func EnsureMainBot(db *gorm.DB, token string) (models.BotOwners, error) {
+	botOwner, err := GetBotByToken(db, token)
+	if err == nil {
+		return botOwner, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return botOwner, err
+	}
+
+	botOwner = models.BotOwners{
+		Running: false,
+	}
+	if err := SetBotToken(&botOwner, token); err != nil {
+		return botOwner, err
+	}
+	if err := db.Create(&botOwner).Error; err != nil {
+		return botOwner, err
+	}
+	log.Printf("Created main bot tenant with ID %d", botOwner.ID)
+	return botOwner, nil
+}
+
+// Привязка строк, созданных до разделения ботов, к указанному боту
+func MigrateLegacyRowsToBot(db *gorm.DB, botID int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tenantTables {
+			result := tx.Model(table).Where("bot_id = 0 OR bot_id IS NULL").Update("bot_id", botID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("Migrated %d legacy rows of %T to bot %d", result.RowsAffected, table, botID)
+			}
+		}
+		return nil
+	})
+}
+
+func GetBotByID(db *gorm.DB, botID int64) (models.BotOwners, error) {
+	var botOwner models.BotOwners
+	result := db.Where("id = ?", botID).First(&botOwner)
+	return botOwner, result.Error
+}
+
+// Изменение баланса владельца бота с записью в журнал начислений
+func AddOwnerEarning(tx *gorm.DB, botID int64, orderID int, amount float64, earningType string) error {
+	if amount == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.BotOwners{}).Where("id = ?", botID).Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.OwnerEarnings{
+		BotID:   botID,
+		OrderID: orderID,
+		Amount:  amount,
+		Type:    earningType,
+	}).Error
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+// Настройки оформления бота. Если владелец ничего не менял, возвращается пустая запись
+func GetBotSettings(db *gorm.DB, botID int64) (models.BotSettings, error) {
+	var settings models.BotSettings
+	err := db.Where("bot_id = ?", botID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.BotSettings{BotID: botID}, nil
+	}
+	return settings, err
+}
+
+func UpdateBotSettings(db *gorm.DB, botID int64, values map[string]interface{}) error {
+	var settings models.BotSettings
+	if err := db.Where(models.BotSettings{BotID: botID}).FirstOrCreate(&settings).Error; err != nil {
+		return err
+	}
+	return db.Model(&settings).Updates(values).Error
+}
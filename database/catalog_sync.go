@@ -0,0 +1,325 @@
+package database
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/models"
+)
+
+// Строк в одном INSERT при записи каталога
+const catalogBatchSize = 500
+
+// Изменения каталога одной соцсети, применяются одной транзакцией
+type categoryDiff struct {
+	category models.Category
+	// Новая соцсеть или восстановленная либо переименованная
+	createCategory bool
+	updateCategory bool
+	// Соцсети больше нет у поставщика, она удаляется вместе с разделами и услугами
+	deleteCategory bool
+
+	createSubcategories []models.Subcategory
+	updateSubcategories []models.Subcategory
+	deleteSubcategories []string
+
+	upsertServices []models.Services
+	deleteServices []int
+	changes        []models.CatalogChange
+}
+
+// Снимок каталога в БД. Соцсети и разделы включают удаленные, чтобы их можно было восстановить
+type catalogSnapshot struct {
+	categories    map[string]models.Category
+	subcategories map[string]models.Subcategory
+	services      map[int]models.Services
+}
+
+func loadCatalogSnapshot(db *gorm.DB) (catalogSnapshot, error) {
+	var categories []models.Category
+	if err := db.Unscoped().Find(&categories).Error; err != nil {
+		return catalogSnapshot{}, err
+	}
+	var subcategories []models.Subcategory
+	if err := db.Unscoped().Find(&subcategories).Error; err != nil {
+		return catalogSnapshot{}, err
+	}
+	var services []models.Services
+	if err := db.Find(&services).Error; err != nil {
+		return catalogSnapshot{}, err
+	}
+
+	snapshot := catalogSnapshot{
+		categories:    make(map[string]models.Category, len(categories)),
+		subcategories: make(map[string]models.Subcategory, len(subcategories)),
+		services:      make(map[int]models.Services, len(services)),
+	}
+	for _, category := range categories {
+		// Из дублей предпочитается неудаленная запись
+		if old, ok := snapshot.categories[category.ID]; !ok || old.DeletedAt.Valid {
+			snapshot.categories[category.ID] = category
+		}
+	}
+	for _, subcategory := range subcategories {
+		if old, ok := snapshot.subcategories[subcategory.ID]; !ok || old.DeletedAt.Valid {
+			snapshot.subcategories[subcategory.ID] = subcategory
+		}
+	}
+	for _, service := range services {
+		snapshot.services[service.ID] = service
+	}
+	return snapshot, nil
+}
+
+// Сравнивает каталог поставщика с БД
+func diffCatalog(catalog api.Catalog, snapshot catalogSnapshot) []categoryDiff {
+	subcategoriesByCategory := make(map[string][]models.Subcategory)
+	categoryOfSubcategory := make(map[string]string)
+	for _, subcategory := range catalog.Subcategories {
+		subcategoriesByCategory[subcategory.CategoryID] = append(subcategoriesByCategory[subcategory.CategoryID], subcategory)
+		categoryOfSubcategory[subcategory.ID] = subcategory.CategoryID
+	}
+	servicesByCategory := make(map[string][]models.Services)
+	offered := make(map[int]bool, len(catalog.Services))
+	for _, service := range catalog.Services {
+		categoryID, ok := categoryOfSubcategory[service.CategoryID]
+		if !ok {
+			continue
+		}
+		servicesByCategory[categoryID] = append(servicesByCategory[categoryID], service)
+		offered[service.ID] = true
+	}
+
+	// Услуги БД по соцсетям их разделов
+	existingByCategory := make(map[string][]models.Services)
+	for _, service := range snapshot.services {
+		if subcategory, ok := snapshot.subcategories[service.CategoryID]; ok {
+			existingByCategory[subcategory.CategoryID] = append(existingByCategory[subcategory.CategoryID], service)
+		}
+	}
+
+	categories := append([]models.Category(nil), catalog.Categories...)
+	listedCategories := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		listedCategories[category.ID] = true
+	}
+	for id, old := range snapshot.categories {
+		if !old.DeletedAt.Valid && !listedCategories[id] {
+			categories = append(categories, old)
+		}
+	}
+
+	diffs := make([]categoryDiff, 0, len(categories))
+	for _, category := range categories {
+		diff := categoryDiff{category: category}
+		if !listedCategories[category.ID] {
+			diff.deleteCategory = true
+		} else if old, ok := snapshot.categories[category.ID]; !ok {
+			diff.createCategory = true
+		} else if old.DeletedAt.Valid || old.Name != category.Name {
+			diff.updateCategory = true
+		}
+
+		listed := make(map[string]bool)
+		for _, subcategory := range subcategoriesByCategory[category.ID] {
+			listed[subcategory.ID] = true
+			old, ok := snapshot.subcategories[subcategory.ID]
+			switch {
+			case !ok:
+				diff.createSubcategories = append(diff.createSubcategories, subcategory)
+			case old.DeletedAt.Valid || old.Name != subcategory.Name || old.CategoryID != subcategory.CategoryID:
+				diff.updateSubcategories = append(diff.updateSubcategories, subcategory)
+			}
+		}
+		for id, old := range snapshot.subcategories {
+			if old.CategoryID == category.ID && !old.DeletedAt.Valid && !listed[id] {
+				diff.deleteSubcategories = append(diff.deleteSubcategories, id)
+			}
+		}
+
+		for _, service := range servicesByCategory[category.ID] {
+			old, ok := snapshot.services[service.ID]
+			if !ok {
+				diff.upsertServices = append(diff.upsertServices, service)
+				diff.changes = append(diff.changes, models.CatalogChange{
+					ServiceID: service.ID, Name: service.Name, Kind: "added",
+					NewRate: service.Rate, NewMin: service.Min, NewMax: service.Max,
+				})
+				continue
+			}
+			if !serviceChanged(old, service) {
+				continue
+			}
+			diff.upsertServices = append(diff.upsertServices, service)
+			priceChanged := old.Rate != service.Rate
+			limitsChanged := old.Min != service.Min || old.Max != service.Max
+			if priceChanged || limitsChanged {
+				diff.changes = append(diff.changes, models.CatalogChange{
+					ServiceID: service.ID, Name: service.Name, Kind: "updated",
+					PriceChanged: priceChanged, LimitsChanged: limitsChanged,
+					OldRate: old.Rate, NewRate: service.Rate,
+					OldMin: old.Min, NewMin: service.Min,
+					OldMax: old.Max, NewMax: service.Max,
+				})
+			}
+		}
+		for _, old := range existingByCategory[category.ID] {
+			if offered[old.ID] {
+				continue
+			}
+			diff.deleteServices = append(diff.deleteServices, old.ID)
+			diff.changes = append(diff.changes, models.CatalogChange{
+				ServiceID: old.ID, Name: old.Name, Kind: "removed",
+				OldRate: old.Rate, OldMin: old.Min, OldMax: old.Max,
+			})
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func serviceChanged(old, new models.Services) bool {
+	return old.Name != new.Name ||
+		old.CategoryID != new.CategoryID ||
+		old.Min != new.Min ||
+		old.Max != new.Max ||
+		old.Dripfeed != new.Dripfeed ||
+		old.Refill != new.Refill ||
+		old.Cancel != new.Cancel ||
+		old.ServiceID != new.ServiceID ||
+		old.Rate != new.Rate ||
+		old.Type != new.Type
+}
+
+func (d categoryDiff) empty() bool {
+	return !d.createCategory && !d.updateCategory && !d.deleteCategory &&
+		len(d.createSubcategories) == 0 && len(d.updateSubcategories) == 0 && len(d.deleteSubcategories) == 0 &&
+		len(d.upsertServices) == 0 && len(d.deleteServices) == 0
+}
+
+// Столбцы услуги, которые перезаписывает upsert. deleted_at сбрасывается, чтобы вернувшаяся услуга восстановилась
+var serviceUpsertColumns = []string{"name", "category_id", "min", "max", "dripfeed", "refill", "cancel", "service_id", "rate", "type", "updated_at", "deleted_at"}
+
+func applyCategoryDiff(tx *gorm.DB, d categoryDiff) error {
+	switch {
+	case d.createCategory:
+		if err := tx.Create(&d.category).Error; err != nil {
+			return err
+		}
+	case d.updateCategory:
+		err := tx.Unscoped().Model(&models.Category{}).Where("category_id = ?", d.category.ID).
+			Updates(map[string]interface{}{"name": d.category.Name, "deleted_at": nil}).Error
+		if err != nil {
+			return err
+		}
+	case d.deleteCategory:
+		if err := tx.Where("category_id = ?", d.category.ID).Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(d.createSubcategories) > 0 {
+		if err := tx.CreateInBatches(&d.createSubcategories, catalogBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	for _, subcategory := range d.updateSubcategories {
+		err := tx.Unscoped().Model(&models.Subcategory{}).Where("subcategory_id = ?", subcategory.ID).
+			Updates(map[string]interface{}{"name": subcategory.Name, "category_id": subcategory.CategoryID, "deleted_at": nil}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(d.deleteSubcategories) > 0 {
+		if err := tx.Where("category_id = ? AND subcategory_id IN ?", d.category.ID, d.deleteSubcategories).Delete(&models.Subcategory{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(d.upsertServices) > 0 {
+		err := tx.Omit("Users").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns(serviceUpsertColumns),
+		}).CreateInBatches(&d.upsertServices, catalogBatchSize).Error
+		if err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(d.deleteServices); start += catalogBatchSize {
+		end := start + catalogBatchSize
+		if end > len(d.deleteServices) {
+			end = len(d.deleteServices)
+		}
+		if err := tx.Where("id IN ?", d.deleteServices[start:end]).Delete(&models.Services{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(d.changes) > 0 {
+		if err := tx.CreateInBatches(&d.changes, catalogBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Применяет каталог поставщика: разница с БД записывается по одной транзакции на соцсеть.
+// Возвращает изменения услуг из успешно записанных соцсетей
+func syncCatalog(ctx context.Context, db *gorm.DB, catalog api.Catalog) []models.CatalogChange {
+	// Пустой ответ скорее сбой поставщика, чем пустой каталог
+	if len(catalog.Services) == 0 {
+		log.Printf("Provider returned an empty catalog, sync skipped")
+		return nil
+	}
+	snapshot, err := loadCatalogSnapshot(db)
+	if err != nil {
+		log.Printf("Error loading catalog from DB: %v", err)
+		return nil
+	}
+
+	var applied []models.CatalogChange
+	var added, removed, updated int
+	for _, diff := range diffCatalog(catalog, snapshot) {
+		// Текущая соцсеть дописывается в своей транзакции, следующие пропускаются
+		if ctx.Err() != nil {
+			break
+		}
+		if diff.empty() {
+			continue
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error { return applyCategoryDiff(tx, diff) }); err != nil {
+			log.Printf("Error updating catalog of category %s: %v", diff.category.Name, err)
+			continue
+		}
+		applied = append(applied, diff.changes...)
+		for _, change := range diff.changes {
+			switch change.Kind {
+			case "added":
+				added++
+			case "removed":
+				removed++
+			default:
+				updated++
+			}
+		}
+	}
+	if added+removed+updated > 0 {
+		log.Printf("Catalog synced: %d services added, %d removed, %d changed price or limits", added, removed, updated)
+	}
+	return applied
+}
+
+// Последние изменения каталога, по всем услугам при serviceID = 0
+func GetCatalogChanges(db *gorm.DB, serviceID int, limit int) ([]models.CatalogChange, error) {
+	query := db.Order("id DESC").Limit(limit)
+	if serviceID != 0 {
+		query = query.Where("service_id = ?", serviceID)
+	}
+	var changes []models.CatalogChange
+	err := query.Find(&changes).Error
+	return changes, err
+}
@@ -1,39 +1,33 @@
 package database
 
 import (
-	"log"
-	"os"
-	"time"
-
+	"github.com/Cekretik/BoostBot/config"
 	"github.com/Cekretik/BoostBot/models"
-	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-const (
-	updateCategoriesInterval    = time.Hour
-	updateSubcategoriesInterval = time.Hour
-	updateServicesInterval      = time.Hour
-)
-
-func InitDB() (*gorm.DB, error) {
-	err := godotenv.Load()
+func InitDB(cfg config.Database) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return nil, err
 	}
-	dsn := os.Getenv("DSN")
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
-	if err != nil {
+	if err := Migrate(db); err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.UserState{}, &models.Category{}, &models.Subcategory{}, &models.Services{}, &models.UserOrders{}, &models.RefundedOrder{}, &models.Payments{}, &models.Referral{}, &models.PromoCode{}, &models.UsedPromoCode{}, &models.BotOwners{})
-	if err != nil {
+	if err := InitTokenCipher(cfg.TokenEncryptionKey); err != nil {
+		return nil, err
+	}
+	if err := MigrateTokenEncryption(db); err != nil {
 		return nil, err
 	}
 
 	return db, nil
 }
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&models.UserState{}, &models.Category{}, &models.Subcategory{}, &models.Services{}, &models.CatalogChange{}, &models.ServiceLink{}, &models.UserOrders{}, &models.RefundedOrder{}, &models.Payments{}, &models.Referral{}, &models.PromoCode{}, &models.UsedPromoCode{}, &models.BotOwners{}, &models.OwnerEarnings{}, &models.Withdrawals{}, &models.BotSettings{}, &models.Conversation{}, &models.CallbackPayload{})
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/models"
+)
+
+// Привязки услуги каталога к услугам других поставщиков
+func GetServiceLinks(db *gorm.DB, catalogServiceID int) ([]models.ServiceLink, error) {
+	var links []models.ServiceLink
+	err := db.Where("catalog_service_id = ?", catalogServiceID).Order("priority, provider").Find(&links).Error
+	return links, err
+}
+
+// Создает привязку или заменяет привязку к тому же поставщику
+func SaveServiceLink(db *gorm.DB, link models.ServiceLink) error {
+	var existing models.ServiceLink
+	err := db.Where("catalog_service_id = ? AND provider = ?", link.CatalogServiceID, link.Provider).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Create(&link).Error
+	}
+	if err != nil {
+		return err
+	}
+	link.ID = existing.ID
+	link.CreatedAt = existing.CreatedAt
+	return db.Save(&link).Error
+}
+
+// false, если привязки не было
+func DeleteServiceLink(db *gorm.DB, catalogServiceID int, provider string) (bool, error) {
+	result := db.Where("catalog_service_id = ? AND provider = ?", catalogServiceID, provider).Delete(&models.ServiceLink{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Обновляет ставки и лимиты привязанных услуг по каталогам их поставщиков
+func syncServiceLinks(db *gorm.DB) {
+	var names []string
+	if err := db.Model(&models.ServiceLink{}).Distinct().Pluck("provider", &names).Error; err != nil {
+		log.Printf("Error fetching linked providers: %v", err)
+		return
+	}
+	for _, name := range names {
+		provider, ok := api.Get(name)
+		if !ok {
+			log.Printf("Service links refer to unknown provider %s", name)
+			continue
+		}
+		catalog, err := provider.Catalog()
+		if err != nil {
+			log.Printf("Error fetching catalog from %s: %v", name, err)
+			continue
+		}
+		// Заказы принимают числовой номер услуги
+		services := make(map[string]models.Services, len(catalog.Services))
+		for _, service := range catalog.Services {
+			services[strconv.Itoa(service.ID)] = service
+		}
+
+		var links []models.ServiceLink
+		db.Where("provider = ?", name).Find(&links)
+		for _, link := range links {
+			service, found := services[link.ProviderServiceID]
+			updates := map[string]interface{}{"unavailable": !found}
+			if found {
+				updates["rate"] = service.Rate
+				updates["min"] = service.Min
+				updates["max"] = service.Max
+			}
+			if err := db.Model(&link).Updates(updates).Error; err != nil {
+				log.Printf("Error updating service link %d: %v", link.ID, err)
+			}
+		}
+	}
+}
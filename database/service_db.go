@@ -1,131 +1,53 @@
 package database
 
 import (
-	"errors"
+	"context"
 	"log"
 	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
 	"github.com/Cekretik/BoostBot/models"
 )
 
-// Update categories, subcategories and services in DB
-func UpdateCategoriesInDB(db *gorm.DB, done chan bool) {
-	for {
-		categories, err := api.FetchCategoriesFromAPI()
-		if err != nil {
-			log.Printf("Error fetching categories from API: %v", err)
-		} else {
-			tx := db.Begin()
-			defer func() {
-				if r := recover(); r != nil {
-					tx.Rollback()
-				}
-			}()
-
-			for _, category := range categories {
-				if err := updateCategory(tx, category); err != nil {
-					log.Printf("Error updating category with ID %s: %v", category.ID, err)
-					tx.Rollback()
-					break
-				}
-			}
-
-			if err := tx.Commit().Error; err != nil {
-				log.Printf("Error committing transaction for categories: %v", err)
-			} else {
-				//log.Println("Categories updated in the database.")
-				done <- true
-			}
-		}
-
-		time.Sleep(updateCategoriesInterval)
+// Пауза между синхронизациями. false - приложение останавливается
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
 
-func UpdateSubcategoriesInDB(db *gorm.DB, done chan bool) {
+// Update categories, subcategories and services in DB
+// onChanges получает изменения услуг после каждой синхронизации, в которой они были
+func UpdateCatalogPeriodically(ctx context.Context, db *gorm.DB, onChanges func([]models.CatalogChange)) {
 	for {
-		<-done
-		var categories []models.Category
-		db.Find(&categories)
-
-		for _, category := range categories {
-			apiSubcategories, err := api.FetchSubcategoriesFromAPI(category.ID)
-			if err != nil {
-				log.Printf("Error fetching subcategories from API for category %s: %v", category.Name, err)
-				continue
-			}
-
-			apiSubcategoryIDs := make(map[string]bool)
-			for _, subcategory := range apiSubcategories {
-				apiSubcategoryIDs[subcategory.ID] = true
-				if err := updateSubcategory(db, subcategory); err != nil {
-					log.Printf("Error updating subcategory with ID %s: %v", subcategory.ID, err)
-				}
-			}
-
-			// Удаление подкатегорий, которые есть в БД, но отсутствуют в API
-			var existingSubcategories []models.Subcategory
-			db.Where("category_id = ?", category.ID).Find(&existingSubcategories)
-			for _, existingSubcategory := range existingSubcategories {
-				if _, found := apiSubcategoryIDs[existingSubcategory.ID]; !found {
-					db.Delete(&existingSubcategory)
-				}
-			}
+		if changes := SyncCatalog(ctx, db); len(changes) > 0 {
+			onChanges(changes)
+		}
+		if !sleepContext(ctx, config.Current().CatalogSyncInterval) {
+			return
 		}
-
-		time.Sleep(updateSubcategoriesInterval)
 	}
 }
 
-func UpdateServicesInDB(db *gorm.DB, done chan bool) {
-	for {
-
-		var subcategories []models.Subcategory
-		if err := db.Find(&subcategories).Error; err != nil {
-			log.Printf("Error fetching subcategories: %v", err)
-			continue
-		}
-
-		for _, subcategory := range subcategories {
-			apiServices, err := api.FetchServicesFromAPI(subcategory.ID)
-			if err != nil {
-				log.Printf("Error fetching services from API for subcategory %s: %v", subcategory.Name, err)
-				continue
-			}
-
-			err = db.Transaction(func(tx *gorm.DB) error {
-				apiServiceIDs := make(map[string]bool)
-				for _, service := range apiServices {
-					apiServiceIDs[service.ServiceID] = true
-					if err := updateService(tx, service); err != nil {
-						return err
-					}
-				}
-
-				var existingServices []models.Services
-				if err := tx.Where("category_id = ?", subcategory.ID).Find(&existingServices).Error; err != nil {
-					return err
-				}
-				for _, existingService := range existingServices {
-					if _, found := apiServiceIDs[existingService.ServiceID]; !found {
-						if err := tx.Delete(&existingService).Error; err != nil {
-							return err
-						}
-					}
-				}
-
-				return nil
-			})
-
-			if err != nil {
-				log.Printf("Error updating services for subcategory %s: %v", subcategory.Name, err)
-			}
-		}
-		time.Sleep(updateServicesInterval)
+// Каталог основного поставщика и ставки привязанных услуг. Возвращает записанные изменения услуг
+func SyncCatalog(ctx context.Context, db *gorm.DB) []models.CatalogChange {
+	var changes []models.CatalogChange
+	catalog, err := api.Default.Catalog()
+	if err != nil {
+		log.Printf("Error fetching catalog from %s: %v", api.Default.Name(), err)
+	} else {
+		changes = syncCatalog(ctx, db, catalog)
 	}
+	syncServiceLinks(db)
+	return changes
 }
 
 // Get categories, subcategories and services from DB
@@ -188,156 +110,161 @@ func GetSubcategoryByID(db *gorm.DB, subcategoryID string) (models.Subcategory,
 	return subcategory, result.Error
 }
 
-// Updating category, subcategory and service
-func updateCategory(tx *gorm.DB, newCategory models.Category) error {
-	var existingCategory models.Category
-	result := tx.Where("category_id = ?", newCategory.ID).First(&existingCategory)
+func GetService(db *gorm.DB, id int) (models.Services, error) {
+	var service models.Services
+	result := db.First(&service, "id = ?", id)
+	return service, result.Error
+}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return tx.Create(&newCategory).Error
+// Услуги по номерам в порядке ids, отсутствующие пропускаются
+func GetServicesByIDs(db *gorm.DB, ids []int) ([]models.Services, error) {
+	var services []models.Services
+	if err := db.Where("id IN ?", ids).Find(&services).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Services, len(services))
+	for _, service := range services {
+		if _, ok := byID[service.ID]; !ok {
+			byID[service.ID] = service
 		}
-		return result.Error
 	}
-
-	if existingCategory.Name != newCategory.Name {
-		return tx.Model(&existingCategory).Updates(models.Category{Name: newCategory.Name}).Error
+	ordered := make([]models.Services, 0, len(ids))
+	for _, id := range ids {
+		if service, ok := byID[id]; ok {
+			ordered = append(ordered, service)
+		}
 	}
-
-	return nil
+	return ordered, nil
 }
 
-func updateSubcategory(tx *gorm.DB, newSubcategory models.Subcategory) error {
-	var existingSubcategory models.Subcategory
-	result := tx.Where("subcategory_id = ?", newSubcategory.ID).First(&existingSubcategory)
-
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return tx.Create(&newSubcategory).Error
+func UpdateOrdersPeriodically(ctx context.Context, db *gorm.DB) {
+	for {
+		if err := updateOrders(db); err != nil {
+			log.Printf("Error fetching orders from API: %v", err)
+		}
+		if !sleepContext(ctx, config.Current().OrdersSyncInterval) {
+			return
 		}
-		return result.Error
-	}
-
-	if existingSubcategory.Name != newSubcategory.Name || existingSubcategory.CategoryID != newSubcategory.CategoryID {
-		return tx.Model(&existingSubcategory).Updates(models.Subcategory{Name: newSubcategory.Name, CategoryID: newSubcategory.CategoryID}).Error
 	}
-
-	return nil
 }
 
-func GetService(db *gorm.DB, id int) (models.Services, error) {
-	var service models.Services
-	result := db.First(&service, "id = ?", id)
-	return service, result.Error
-}
+// Заказы в конечном статусе больше не опрашиваются
+var finalOrderStatuses = []string{"COMPLETED", "PARTIAL", "CANCELED"}
 
-func updateService(tx *gorm.DB, newService models.Services) error {
-	var existingService models.Services
-	result := tx.Where("service_id = ?", newService.ServiceID).First(&existingService)
+func updateOrders(db *gorm.DB) error {
+	var orders []models.UserOrders
+	if err := db.Where("status NOT IN ?", finalOrderStatuses).Find(&orders).Error; err != nil {
+		return err
+	}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return tx.Create(&newService).Error
+	// Статусы запрашиваются у поставщика, выполняющего заказ
+	byProvider := make(map[string][]models.UserOrders)
+	for _, order := range orders {
+		name := order.Provider
+		if name == "" {
+			name = api.Default.Name()
 		}
-		return result.Error
+		byProvider[name] = append(byProvider[name], order)
 	}
-
-	if existingService.ID != newService.ID ||
-		existingService.Name != newService.Name ||
-		existingService.CategoryID != newService.CategoryID ||
-		existingService.Min != newService.Min ||
-		existingService.Max != newService.Max ||
-		existingService.Dripfeed != newService.Dripfeed ||
-		existingService.Refill != newService.Refill ||
-		existingService.Cancel != newService.Cancel ||
-		existingService.ServiceID != newService.ServiceID ||
-		existingService.Rate != newService.Rate ||
-		existingService.Type != newService.Type {
-		return tx.Model(&existingService).Updates(models.Services{ID: newService.ID, Name: newService.Name, CategoryID: newService.CategoryID, Min: newService.Min,
-			Max: newService.Max, Dripfeed: newService.Dripfeed, Refill: newService.Refill, Cancel: newService.Cancel, ServiceID: newService.ServiceID, Rate: newService.Rate,
-			Type: newService.Type}).Error
+	for name, providerOrders := range byProvider {
+		provider, ok := api.Get(name)
+		if !ok {
+			log.Printf("Orders refer to unknown provider %s", name)
+			continue
+		}
+		if err := updateProviderOrders(db, provider, providerOrders); err != nil {
+			log.Printf("Error fetching orders from %s: %v", name, err)
+		}
 	}
-
 	return nil
 }
 
-func UpdateOrdersPeriodically(db *gorm.DB, done chan bool) {
-	for {
-		serviceDetails, err := api.FetchOrders()
-		if err != nil {
-			log.Printf("Error fetching orders from API: %v", err)
+func updateProviderOrders(db *gorm.DB, provider api.Provider, orders []models.UserOrders) error {
+	orderIDs := make([]int, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+	statuses, err := provider.OrderStatus(orderIDs)
+	if err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+
+	for _, order := range orders {
+		detail, ok := statuses[order.OrderID]
+		if !ok {
 			continue
 		}
 
-		tx := db.Begin()
+		// Обновляем поля заказа, если они изменились
+		if order.Status != detail.Status || order.Remains != detail.Remains ||
+			order.Charge != detail.Charge || order.StartCount != detail.StartCount {
+			order.Status = detail.Status
+			order.Remains = detail.Remains
+			order.Charge = detail.Charge
+			order.StartCount = detail.StartCount
+			tx.Save(&order)
+		}
 
-		for _, detail := range serviceDetails {
-			var order models.UserOrders
-			if err := tx.Where("order_id = ?", detail.ID).First(&order).Error; err != nil {
+		if order.Status != "PARTIAL" && order.Status != "CANCELED" && order.Status != "COMPLETED" && order.Status != "IN_PROGRESS" {
+			order.Status = "PENDING"
+			tx.Save(&order)
+		}
+
+		// Возврат средств
+		if order.Status == "CANCELED" || order.Status == "PARTIAL" {
+			// Проверяем, был ли этот заказ уже возвращен
+			var refundedOrder models.RefundedOrder
+			if err := tx.Where("order_id = ?", order.ID).First(&refundedOrder).Error; err == nil {
+				// Заказ уже возвращен, пропускаем его
 				continue
 			}
 
-			// Обновляем поля заказа, если они изменились
-			if order.Status != detail.Status || order.Remains != detail.Remains ||
-				order.Charge != detail.Charge || order.StartCount != detail.StartCount {
-				order.Status = detail.Status
-				order.Remains = detail.Remains
-				order.Charge = detail.Charge
-				order.StartCount = detail.StartCount
-				tx.Save(&order)
+			var user models.UserState
+			if err := tx.Where("bot_id = ? AND user_id = ?", order.BotID, order.ChatID).First(&user).Error; err != nil {
+				log.Printf("Error finding user with ChatID %s: %v", order.ChatID, err)
+				continue
 			}
 
-			if order.Status != "PARTIAL" && order.Status != "CANCELED" && order.Status != "COMPLETED" && order.Status != "IN_PROGRESS" {
-				order.Status = "PENDING"
-				tx.Save(&order)
+			// Возвращается доля невыполненного количества: и стоимости для клиента, и доли владельца бота
+			share := refundShare(order.Status, detail.Remains, order.Quantity)
+			if err := AddUserBalance(tx, order.BotID, user.UserID, order.Cost*share); err != nil {
+				log.Printf("Error refunding order %d: %v", order.OrderID, err)
 			}
-
-			// Возврат средств
-			if order.Status == "CANCELED" || order.Status == "PARTIAL" {
-				// Проверяем, был ли этот заказ уже возвращен
-				var refundedOrder models.RefundedOrder
-				if err := tx.Where("order_id = ?", order.ID).First(&refundedOrder).Error; err == nil {
-					// Заказ уже возвращен, пропускаем его
-					continue
-				}
-
-				var user models.UserState
-				if err := tx.Where("user_id = ?", order.ChatID).First(&user).Error; err != nil {
-					log.Printf("Error finding user with ChatID %s: %v", order.ChatID, err)
-					continue
-				}
-
-				var refundAmount float64
-				if order.Status == "CANCELED" {
-					refundAmount = order.Cost
-				} else if order.Status == "PARTIAL" {
-					refundAmount = (float64(detail.Remains) / 1000.0) * detail.Charge
-				}
-
-				user.Balance += refundAmount
-				tx.Save(&user)
-
-				// Добавляем запись о возврате заказа в базу данных
-				tx.Create(&models.RefundedOrder{OrderID: order.ID})
+			if err := AddOwnerEarning(tx, order.BotID, order.OrderID, -order.OwnerMargin*share, "refund"); err != nil {
+				log.Printf("Error reversing owner earnings for order %d: %v", order.OrderID, err)
 			}
-		}
 
-		if err := tx.Commit().Error; err != nil {
-			log.Printf("Error committing transaction for updating orders: %v", err)
-			tx.Rollback()
-		}
-		select {
-		case <-done:
-			return
-		default:
-			time.Sleep(30 * time.Minute)
+			// Добавляем запись о возврате заказа в базу данных
+			tx.Create(&models.RefundedOrder{OrderID: order.ID})
 		}
 	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction for updating orders: %v", err)
+		tx.Rollback()
+	}
+	return nil
+}
+
+// Доля заказа к возврату: весь отмененный заказ или остаток частично выполненного
+func refundShare(status string, remains, quantity int) float64 {
+	if status == "CANCELED" {
+		return 1
+	}
+	if quantity <= 0 || remains <= 0 {
+		return 0
+	}
+	if remains >= quantity {
+		return 1
+	}
+	return float64(remains) / float64(quantity)
 }
 
-func AddServiceToFavorites(db *gorm.DB, userID int64, serviceID int) error {
+func AddServiceToFavorites(db *gorm.DB, botID, userID int64, serviceID int) error {
 	var user models.UserState
-	if err := db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error; err != nil {
 		log.Printf("User not found with userID %d: %v", userID, err)
 		return err
 	}
@@ -350,10 +277,10 @@ func AddServiceToFavorites(db *gorm.DB, userID int64, serviceID int) error {
 	return db.Model(&user).Association("Favorites").Append(&service)
 }
 
-func RemoveServiceFromFavorites(db *gorm.DB, userID int64, serviceID int) error {
+func RemoveServiceFromFavorites(db *gorm.DB, botID, userID int64, serviceID int) error {
 	var user models.UserState
 	var service models.Services
-	if err := db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error; err != nil {
 		return err
 	}
 	if err := db.Where("id = ?", serviceID).First(&service).Error; err != nil {
@@ -362,3 +289,35 @@ func RemoveServiceFromFavorites(db *gorm.DB, userID int64, serviceID int) error
 
 	return db.Model(&user).Association("Favorites").Delete(&service)
 }
+
+// Пользователи, добавившие услугу в избранное и не отключившие уведомления о ней
+func GetFavoriteFollowers(db *gorm.DB, serviceID int) ([]models.UserState, error) {
+	var users []models.UserState
+	err := db.Joins("JOIN user_favorites ON user_favorites.user_state_id = user_states.id").
+		Where("user_favorites.services_id = ? AND user_states.favorite_alerts_muted = ? AND user_states.unreachable = ?", serviceID, false, false).
+		Find(&users).Error
+	return users, err
+}
+
+// Услуга каталога с названием соцсети своего раздела
+type PlatformService struct {
+	models.Services
+	Platform string `gorm:"column:platform"`
+}
+
+// Услуги для поиска. refill и cancel оставляют только услуги с докруткой или отменой
+func GetSearchableServices(db *gorm.DB, refill, cancel bool) ([]PlatformService, error) {
+	query := db.Model(&models.Services{}).
+		Select("services.*, categories.name AS platform").
+		Joins("JOIN subcategories ON subcategories.subcategory_id = services.category_id AND subcategories.deleted_at IS NULL").
+		Joins("JOIN categories ON categories.category_id = subcategories.category_id AND categories.deleted_at IS NULL")
+	if refill {
+		query = query.Where("services.refill = ?", true)
+	}
+	if cancel {
+		query = query.Where("services.cancel = ?", true)
+	}
+	var services []PlatformService
+	err := query.Order("services.id").Scan(&services).Error
+	return services, err
+}
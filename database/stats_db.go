@@ -0,0 +1,98 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+const topServicesLimit = 5
+
+// Статусы платежей Cryptomus и AAIO, при которых баланс уже пополнен
+var paidPaymentStatuses = []string{"paid", "success"}
+
+type GroupTotal struct {
+	Key    string  `gorm:"column:key"`
+	Count  int64   `gorm:"column:count"`
+	Amount float64 `gorm:"column:amount"`
+}
+
+type ServiceTotal struct {
+	ServiceID string  `gorm:"column:service_id"`
+	Name      string  `gorm:"column:name"`
+	Orders    int64   `gorm:"column:orders"`
+	Revenue   float64 `gorm:"column:revenue"`
+}
+
+type BotStats struct {
+	NewUsers     int64
+	ActiveUsers  int64
+	Deposits     []GroupTotal
+	Orders       []GroupTotal
+	GrossRevenue float64
+	OwnerMargin  float64
+	TopServices  []ServiceTotal
+}
+
+// Статистика бота с момента since. Нулевое since означает все время
+func GetBotStats(db *gorm.DB, botID int64, since time.Time) (BotStats, error) {
+	var stats BotStats
+
+	period := func(column string) *gorm.DB {
+		query := db.Where("bot_id = ?", botID)
+		if !since.IsZero() {
+			query = query.Where(column+" >= ?", since)
+		}
+		return query
+	}
+
+	if err := period("created_at").Model(&models.UserState{}).Count(&stats.NewUsers).Error; err != nil {
+		return stats, err
+	}
+	// Запись пользователя обновляется при каждом сообщении боту
+	if err := period("updated_at").Model(&models.UserState{}).Count(&stats.ActiveUsers).Error; err != nil {
+		return stats, err
+	}
+
+	err := period("updated_at").Model(&models.Payments{}).
+		Select("type AS key, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("status IN ?", paidPaymentStatuses).
+		Group("type").Order("amount DESC").Scan(&stats.Deposits).Error
+	if err != nil {
+		return stats, err
+	}
+
+	err = period("created_at").Model(&models.UserOrders{}).
+		Select("status AS key, COUNT(*) AS count, COALESCE(SUM(cost), 0) AS amount").
+		Group("status").Order("count DESC").Scan(&stats.Orders).Error
+	if err != nil {
+		return stats, err
+	}
+
+	var totals struct {
+		Revenue float64 `gorm:"column:revenue"`
+		Margin  float64 `gorm:"column:margin"`
+	}
+	err = period("created_at").Model(&models.UserOrders{}).
+		Select("COALESCE(SUM(cost), 0) AS revenue, COALESCE(SUM(owner_margin), 0) AS margin").
+		Scan(&totals).Error
+	if err != nil {
+		return stats, err
+	}
+	stats.GrossRevenue = totals.Revenue
+	stats.OwnerMargin = totals.Margin
+
+	err = period("user_orders.created_at").Table("user_orders").
+		Select("user_orders.service_id, COALESCE(MAX(services.name), user_orders.service_id) AS name, COUNT(*) AS orders, COALESCE(SUM(user_orders.cost), 0) AS revenue").
+		// В заказе хранится номер услуги каталога строкой
+		Joins("LEFT JOIN services ON CAST(services.id AS TEXT) = user_orders.service_id").
+		Where("user_orders.deleted_at IS NULL").
+		Group("user_orders.service_id").Order("orders DESC, revenue DESC").
+		Limit(topServicesLimit).Scan(&stats.TopServices).Error
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
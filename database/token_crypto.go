@@ -0,0 +1,121 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+const encryptedTokenPrefix = "v1:"
+
+var (
+	tokenAEAD cipher.AEAD
+	// Токен бота вида 123456789:AA... в логах и текстах ошибок
+	botTokenPattern = regexp.MustCompile(`\d{5,}:[A-Za-z0-9_-]{30,}`)
+)
+
+// Ключ AES-256 задается в TOKEN_ENCRYPTION_KEY в base64 или hex
+func InitTokenCipher(key string) error {
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != 32 {
+		rawKey, err = hex.DecodeString(key)
+	}
+	if err != nil || len(rawKey) != 32 {
+		return errors.New("TOKEN_ENCRYPTION_KEY must be a 32-byte key encoded in base64 or hex")
+	}
+
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return err
+	}
+	tokenAEAD, err = cipher.NewGCM(block)
+	return err
+}
+
+func EncryptToken(token string) (string, error) {
+	if tokenAEAD == nil {
+		return "", errors.New("token cipher is not initialized")
+	}
+	nonce := make([]byte, tokenAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := tokenAEAD.Seal(nonce, nonce, []byte(token), nil)
+	return encryptedTokenPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptToken(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedTokenPrefix) {
+		return stored, nil
+	}
+	if tokenAEAD == nil {
+		return "", errors.New("token cipher is not initialized")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedTokenPrefix))
+	if err != nil {
+		return "", err
+	}
+	nonceSize := tokenAEAD.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("encrypted token is too short")
+	}
+	token, err := tokenAEAD.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting token: %w", err)
+	}
+	return string(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Заполняет зашифрованный токен и хеш для поиска
+func SetBotToken(botOwner *models.BotOwners, token string) error {
+	encrypted, err := EncryptToken(token)
+	if err != nil {
+		return err
+	}
+	botOwner.Token = encrypted
+	botOwner.TokenHash = HashToken(token)
+	return nil
+}
+
+func RedactTokens(text string) string {
+	return botTokenPattern.ReplaceAllString(text, "***")
+}
+
+// Однократное шифрование токенов, сохраненных открытым текстом
+func MigrateTokenEncryption(db *gorm.DB) error {
+	var bots []models.BotOwners
+	if err := db.Where("token != '' AND token NOT LIKE ?", encryptedTokenPrefix+"%").Find(&bots).Error; err != nil {
+		return err
+	}
+
+	for _, botOwner := range bots {
+		if err := SetBotToken(&botOwner, botOwner.Token); err != nil {
+			return err
+		}
+		err := db.Model(&models.BotOwners{}).Where("id = ?", botOwner.ID).
+			Updates(map[string]interface{}{"token": botOwner.Token, "token_hash": botOwner.TokenHash}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(bots) > 0 {
+		log.Printf("Encrypted %d plaintext bot tokens", len(bots))
+	}
+	return nil
+}
@@ -9,13 +9,14 @@ import (
 )
 
 // Get user state
-func GetUserState(db *gorm.DB, userID, channelID int64, subscribed bool, balance float64, userName string) (*models.UserState, error) {
+func GetUserState(db *gorm.DB, botID, userID, channelID int64, subscribed bool, balance float64, userName string) (*models.UserState, error) {
 	var userState models.UserState
-	result := db.Where("user_id = ? AND channel_id = ?", userID, channelID).First(&userState)
+	result := db.Where("bot_id = ? AND user_id = ? AND channel_id = ?", botID, userID, channelID).First(&userState)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			userState = models.UserState{
+				BotID:      botID,
 				UserID:     userID,
 				UserName:   userName,
 				ChannelID:  channelID,
@@ -26,7 +27,7 @@ func GetUserState(db *gorm.DB, userID, channelID int64, subscribed bool, balance
 				log.Printf("Error creating new user state: %v", err)
 				return nil, err
 			}
-			log.Printf("Created new user state for chat ID %v and channel ID %v", userID, channelID)
+			log.Printf("Created new user state for bot %v, chat ID %v and channel ID %v", botID, userID, channelID)
 			return &userState, nil
 		}
 		log.Printf("Error finding user state: %v", result.Error)
@@ -37,8 +38,8 @@ func GetUserState(db *gorm.DB, userID, channelID int64, subscribed bool, balance
 }
 
 // Update user subscription status
-func UpdateUserState(db *gorm.DB, userID, channelID int64, subscribed bool, balance float64, userName string) error {
-	userState, err := GetUserState(db, userID, channelID, true, balance, userName)
+func UpdateUserState(db *gorm.DB, botID, userID, channelID int64, subscribed bool, balance float64, userName string) error {
+	userState, err := GetUserState(db, botID, userID, channelID, true, balance, userName)
 	if err != nil {
 		return err
 	}
@@ -57,37 +58,58 @@ func UpdateUserState(db *gorm.DB, userID, channelID int64, subscribed bool, bala
 	return nil
 }
 
-func UpdateUserBalance(db *gorm.DB, userID int64, amount float64) error {
+func UpdateUserBalance(db *gorm.DB, botID, userID int64, amount float64) error {
 	var user models.UserState
-	if err := db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error; err != nil {
 		return err
 	}
 
 	var activePromoCode models.UsedPromoCode
-	if err := db.Where("user_id = ? AND used = ?", userID, false).First(&activePromoCode).Error; err == nil {
+	if err := db.Where("bot_id = ? AND user_id = ? AND used = ?", botID, userID, false).First(&activePromoCode).Error; err == nil {
 		var promo models.PromoCode
 		if err := db.Where("code = ?", activePromoCode.PromoCode).First(&promo).Error; err == nil {
 			bonus := amount * promo.Discount / 100
 			amount += bonus
 
-			db.Model(&models.UsedPromoCode{}).Where("user_id = ? AND promo_code = ?", userID, activePromoCode.PromoCode).Update("used", true)
+			db.Model(&models.UsedPromoCode{}).Where("bot_id = ? AND user_id = ? AND promo_code = ?", botID, userID, activePromoCode.PromoCode).Update("used", true)
 		}
 	}
 
-	user.Balance += amount
-	if err := db.Save(&user).Error; err != nil {
+	if err := AddUserBalance(db, botID, userID, amount); err != nil {
 		return err
 	}
 
 	var referral models.Referral
-	if err := db.Where("referred_id = ?", userID).First(&referral).Error; err == nil {
+	if err := db.Where("bot_id = ? AND referred_id = ?", botID, userID).First(&referral).Error; err == nil {
 		commission := amount * 0.10
-		db.Model(&models.UserState{}).Where("user_id = ?", referral.ReferrerID).Update("balance", gorm.Expr("balance + ?", commission))
+		db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, referral.ReferrerID).Update("balance", gorm.Expr("balance + ?", commission))
 		db.Model(&models.Referral{}).Where("id = ?", referral.ID).Update("amount_earned", gorm.Expr("amount_earned + ?", commission))
 	}
 	return nil
 }
 
+// Баланс меняется одним UPDATE, чтобы параллельные обработчики и вебхуки не затирали изменения друг друга
+func AddUserBalance(db *gorm.DB, botID, userID int64, amount float64) error {
+	return db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).Update("balance", gorm.Expr("balance + ?", amount)).Error
+}
+
+// Списывает amount, только если на балансе хватает средств
+func DebitUserBalance(db *gorm.DB, botID, userID int64, amount float64) error {
+	result := db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ? AND balance >= ?", botID, userID, amount).Update("balance", gorm.Expr("balance - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// Telegram ответил 403: пользователь заблокировал бота или удалил аккаунт
+func MarkUserUnreachable(db *gorm.DB, botID, userID int64) error {
+	return db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).Update("unreachable", true).Error
+}
+
 func UpdatePaymentStatusInDB(db *gorm.DB, orderID, status string) error {
 	var payment models.Payments
 	if err := db.Model(&payment).Where("order_id = ?", orderID).Update("status", status).Error; err != nil {
@@ -96,25 +118,49 @@ func UpdatePaymentStatusInDB(db *gorm.DB, orderID, status string) error {
 	return nil
 }
 
-func UserIsNew(db *gorm.DB, userID int64) bool {
+func UserIsNew(db *gorm.DB, botID, userID int64) bool {
 	var user models.UserState
-	result := db.Where("user_id = ?", userID).First(&user)
+	result := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user)
 	return errors.Is(result.Error, gorm.ErrRecordNotFound)
 }
 
-func GetUserFavorites(db *gorm.DB, userID int64) ([]models.Services, error) {
+func GetUserFavorites(db *gorm.DB, botID, userID int64) ([]models.Services, error) {
 	var user models.UserState
-	if err := db.Preload("Favorites").Where("user_id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Preload("Favorites").Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return user.Favorites, nil
 }
 
-func GetUserCurrency(db *gorm.DB, userID int64) (string, error) {
+func GetUserCurrency(db *gorm.DB, botID, userID int64) (string, error) {
 	var user models.UserState
-	if err := db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
 		return "", err
 	}
 	return user.Currency, nil
 }
+
+// Язык пользователя, пустая строка - язык не выбран
+func GetUserLanguage(db *gorm.DB, botID, userID int64) (string, error) {
+	var language string
+	err := db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).
+		Select("language").Limit(1).Scan(&language).Error
+	return language, err
+}
+
+func SetUserLanguage(db *gorm.DB, botID, userID int64, language string) error {
+	return db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).Update("language", language).Error
+}
+
+// Отключены ли уведомления об избранных услугах
+func FavoriteAlertsMuted(db *gorm.DB, botID, userID int64) (bool, error) {
+	var muted bool
+	err := db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).
+		Select("favorite_alerts_muted").Limit(1).Scan(&muted).Error
+	return muted, err
+}
+
+func SetFavoriteAlertsMuted(db *gorm.DB, botID, userID int64, muted bool) error {
+	return db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", botID, userID).Update("favorite_alerts_muted", muted).Error
+}
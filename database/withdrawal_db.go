@@ -0,0 +1,90 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+const (
+	WithdrawalPending  = "pending"
+	WithdrawalApproved = "approved"
+	WithdrawalRejected = "rejected"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrWithdrawalResolved  = errors.New("withdrawal already resolved")
+)
+
+// Создание заявки с резервированием суммы на балансе бота
+func CreateWithdrawal(db *gorm.DB, botID, userID int64, amount float64, destination string) (models.Withdrawals, error) {
+	withdrawal := models.Withdrawals{
+		BotID:       botID,
+		UserID:      userID,
+		Amount:      amount,
+		Destination: destination,
+		Status:      WithdrawalPending,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.BotOwners{}).
+			Where("id = ? AND user_id = ? AND balance >= ?", botID, userID, amount).
+			Update("balance", gorm.Expr("balance - ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientBalance
+		}
+
+		if err := tx.Create(&withdrawal).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OwnerEarnings{
+			BotID:  botID,
+			Amount: -amount,
+			Type:   "withdrawal",
+		}).Error
+	})
+
+	return withdrawal, err
+}
+
+// Одобрение или отклонение заявки. При отклонении резерв возвращается на баланс
+func ResolveWithdrawal(db *gorm.DB, withdrawalID uint, adminID int64, approve bool) (models.Withdrawals, error) {
+	var withdrawal models.Withdrawals
+	status := WithdrawalRejected
+	if approve {
+		status = WithdrawalApproved
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", withdrawalID).First(&withdrawal).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.Withdrawals{}).
+			Where("id = ? AND status = ?", withdrawalID, WithdrawalPending).
+			Updates(map[string]interface{}{"status": status, "reviewed_by": adminID, "reviewed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWithdrawalResolved
+		}
+		withdrawal.Status = status
+		withdrawal.ReviewedBy = adminID
+		withdrawal.ReviewedAt = &now
+
+		if approve {
+			return nil
+		}
+		return AddOwnerEarning(tx, withdrawal.BotID, 0, withdrawal.Amount, "withdrawal_refund")
+	})
+
+	return withdrawal, err
+}
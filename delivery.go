@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/webhook"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+const (
+	deliveryPolling = "polling"
+	deliveryWebhook = "webhook"
+)
+
+// Способ получения обновлений: BOT_DELIVERY_MODE=polling (по умолчанию) или webhook
+func deliveryMode() string {
+	if settings.Delivery.Mode == deliveryWebhook {
+		return deliveryWebhook
+	}
+	return deliveryPolling
+}
+
+// Публичный адрес HTTP-сервера, по умолчанию совпадает с URL_CALLBACK платежей
+func webhookBaseURL() string {
+	baseURL := settings.Delivery.WebhookURL
+	if baseURL == "" {
+		baseURL = settings.Payment.URLCallback
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+func ensureWebhookSecret(db *gorm.DB, botID int64) (string, error) {
+	botOwner, err := database.GetBotByID(db, botID)
+	if err != nil {
+		return "", err
+	}
+	if botOwner.WebhookSecret != "" {
+		return botOwner.WebhookSecret, nil
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := db.Model(&models.BotOwners{}).Where("id = ?", botID).Update("webhook_secret", secret).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Открывает канал обновлений бота в выбранном режиме. Вебхук устанавливается
+// или удаляется автоматически, возвращаемая функция прекращает прием обновлений
+func OpenUpdatesChannel(db *gorm.DB, bot *tgbotapi.BotAPI, botID int64) (tgbotapi.UpdatesChannel, func(), error) {
+	if deliveryMode() == deliveryWebhook {
+		secret, err := ensureWebhookSecret(db, botID)
+		if err != nil {
+			return nil, nil, err
+		}
+		updates := webhook.DefaultDispatcher.Register(secret, botID)
+		if err := webhook.SetWebhook(bot, webhookBaseURL()+webhook.PathPrefix+secret, secret); err != nil {
+			webhook.DefaultDispatcher.Unregister(secret, updates)
+			return nil, nil, err
+		}
+		return updates, func() { webhook.DefaultDispatcher.Unregister(secret, updates) }, nil
+	}
+
+	if err := webhook.DeleteWebhook(bot); err != nil {
+		return nil, nil, err
+	}
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	return bot.GetUpdatesChan(u), bot.StopReceivingUpdates, nil
+}
@@ -0,0 +1,847 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/callback"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/payment"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	"github.com/Cekretik/BoostBot/telegram/telegramtest"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testBotID     = 7
+	testChannelID = -1001234567890
+	// Курс рубля, который отдает поддельный API
+	testRate = 100.0
+)
+
+// Кнопки главного меню без настроек оформления
+var defaultMenu = functionality.DefaultMenuLabels(i18n.For(i18n.Default))
+
+// Бот-клон с поддельным Telegram, базой SQLite в памяти и поддельным API StageSMM
+type testEnv struct {
+	t      *testing.T
+	db     *gorm.DB
+	fake   *telegramtest.Fake
+	handle telegramtest.Handler
+	// Адрес поддельного API, /panel отвечает по стандартному API v2 панелей
+	apiURL string
+
+	mu     sync.Mutex
+	orders []map[string]interface{}
+	// Ответ панели на action=services
+	panelServices string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := &testEnv{t: t, fake: telegramtest.New(), panelServices: "[]"}
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	env.db = db
+
+	if err := db.Create(&models.BotOwners{ID: testBotID, UserID: 1, BotName: "test_bot", Running: true}).Error; err != nil {
+		t.Fatalf("create bot: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rates":
+			fmt.Fprint(w, testRate)
+		case "/orders":
+			var order map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&order)
+			env.mu.Lock()
+			env.orders = append(env.orders, order)
+			id := len(env.orders)
+			env.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":        id,
+				"serviceId": order["serviceId"],
+				"link":      order["link"],
+				"quantity":  order["quantity"],
+				"status":    "PENDING",
+			})
+		case "/panel":
+			r.ParseForm()
+			if r.PostForm.Get("key") != "panel-key" {
+				fmt.Fprint(w, `{"error": "Invalid API key"}`)
+				return
+			}
+			switch r.PostForm.Get("action") {
+			case "add":
+				env.mu.Lock()
+				env.orders = append(env.orders, map[string]interface{}{
+					"serviceId": r.PostForm.Get("service"),
+					"link":      r.PostForm.Get("link"),
+					"quantity":  r.PostForm.Get("quantity"),
+				})
+				id := 9000 + len(env.orders)
+				env.mu.Unlock()
+				fmt.Fprintf(w, `{"order": %d}`, id)
+			case "services":
+				env.mu.Lock()
+				fmt.Fprint(w, env.panelServices)
+				env.mu.Unlock()
+			default:
+				fmt.Fprint(w, `{"error": "Incorrect request"}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	env.apiURL = srv.URL
+
+	api.Configure(config.StageSMM{APIURL: srv.URL, Token: "test", OrdersEndpoint: srv.URL + "/orders"})
+	currency.SetRate("RUB", testRate)
+	functionality.Configure(config.Telegram{ChannelID: testChannelID, BotLink: "https://t.me/test_bot"})
+	payment.Configure(config.Payment{AAIOShopID: "shop", AAIOKey1: "key1", AAIOKey2: "key2"})
+	fsm.Default = fsm.New(fsm.NewMemoryStore(), fsm.DefaultTTL)
+	callback.Default = callback.NewCodec(nil, callback.NewMemoryStore())
+
+	// Без ограничений скорости, чтобы тесты не ждали
+	sender.Register(env.fake, sender.Config{
+		GlobalPerSecond: 1 << 20,
+		ChatInterval:    time.Nanosecond,
+		ChatBurst:       1 << 20,
+		GroupPerMinute:  1 << 20,
+		OnBlocked: func(chatID int64) {
+			database.MarkUserUnreachable(db, testBotID, chatID)
+		},
+	})
+	t.Cleanup(func() { sender.Unregister(env.fake) })
+	telegram.Register(testBotID, env.fake)
+	t.Cleanup(func() { telegram.Unregister(testBotID, env.fake) })
+
+	env.handle = newClientRouter(context.Background(), db, testBotID, "test_bot").Handle
+	return env
+}
+
+func (env *testEnv) user(id int64, name string) *telegramtest.User {
+	return env.fake.User(env.t, env.handle, tgbotapi.User{ID: id, FirstName: name, UserName: name})
+}
+
+// Заказы, полученные поддельным API
+func (env *testEnv) sentOrders() []map[string]interface{} {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	return append([]map[string]interface{}(nil), env.orders...)
+}
+
+func (env *testEnv) userState(id int64) models.UserState {
+	env.t.Helper()
+	var user models.UserState
+	if err := env.db.Where("bot_id = ? AND user_id = ?", testBotID, id).First(&user).Error; err != nil {
+		env.t.Fatalf("user %d: %v", id, err)
+	}
+	return user
+}
+
+// Каталог: категория Telegram с двумя страницами подкатегорий и услугами в первой из них
+func (env *testEnv) seedCatalog() {
+	env.t.Helper()
+	records := []interface{}{
+		&models.Category{ID: "tg", Name: "Telegram"},
+		&models.Category{ID: "yt", Name: "YouTube"},
+	}
+	for i := 1; i <= functionality.ItemsPerPage+2; i++ {
+		records = append(records, &models.Subcategory{ID: fmt.Sprintf("tg-%d", i), Name: fmt.Sprintf("Подписчики %d", i), CategoryID: "tg"})
+	}
+	records = append(records,
+		&models.Services{ID: 101, ServiceID: "s101", Name: "Живые подписчики", CategoryID: "tg-1", Min: 100, Max: 10000, Rate: 2},
+		&models.Services{ID: 102, ServiceID: "s102", Name: "Просмотры", CategoryID: "tg-1", Min: 10, Max: 1000, Rate: 0.5},
+	)
+	for _, record := range records {
+		if err := env.db.Create(record).Error; err != nil {
+			env.t.Fatalf("seed %T: %v", record, err)
+		}
+	}
+}
+
+func TestStartShowsMenuAndCatalog(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	alice := env.user(1001, "alice")
+
+	alice.Send("/start")
+
+	greeting := alice.ExpectAny("Привет, alice!")
+	if got := greeting.ReplyButtons(); len(got) == 0 || got[0][0] != defaultMenu.Balance {
+		t.Fatalf("greeting keyboard = %v", got)
+	}
+	catalog := alice.Expect("Выберите социальную сеть")
+	if _, ok := catalog.Button("💎 Telegram"); !ok {
+		t.Fatalf("no Telegram button\n%s", alice.Transcript())
+	}
+	if _, ok := catalog.Button("❤️‍🔥Избранное"); !ok {
+		t.Fatalf("no favorites button\n%s", alice.Transcript())
+	}
+	if user := env.userState(1001); !user.Subscribed || user.UserName != "alice" {
+		t.Fatalf("user state = %+v", user)
+	}
+}
+
+func TestEnglishUserCanSwitchLanguage(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	bob := env.fake.User(t, env.handle, tgbotapi.User{ID: 1002, FirstName: "bob", UserName: "bob", LanguageCode: "en-GB"})
+
+	bob.Send("/start")
+
+	greeting := bob.ExpectAny("Hi, bob!")
+	if got := greeting.ReplyButtons(); len(got) == 0 || got[0][0] != "💳 Balance" {
+		t.Fatalf("greeting keyboard = %v", got)
+	}
+	bob.Expect("Choose a social network")
+	if lang := env.userState(1002).Language; lang != "en" {
+		t.Fatalf("language = %q, want en", lang)
+	}
+
+	bob.Send("🧩Profile")
+	bob.Press("⚙️Settings")
+	bob.Press("🇷🇺 Русский")
+
+	changed := bob.Expect("Язык изменен на русский.")
+	if got := changed.ReplyButtons(); len(got) == 0 || got[0][0] != defaultMenu.Balance {
+		t.Fatalf("menu keyboard = %v", got)
+	}
+	bob.Send(defaultMenu.Balance)
+	bob.Expect("Ваш баланс")
+}
+
+func TestUnsubscribedUserIsAskedToSubscribe(t *testing.T) {
+	env := newTestEnv(t)
+	env.fake.SetMember(testChannelID, 1001, "left")
+	alice := env.user(1001, "alice")
+
+	alice.Send("/start")
+
+	if m := alice.Last(); strings.Contains(m.Text, "Выберите социальную сеть") {
+		t.Fatalf("catalog shown to unsubscribed user\n%s", alice.Transcript())
+	}
+	if env.userState(1001).Subscribed {
+		t.Fatal("user marked as subscribed")
+	}
+}
+
+func TestCatalogPaginationAndServiceCard(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.Press("💎 Telegram")
+	page := alice.Expect("Выберите категорию:")
+	if _, ok := page.Button("Страница 1 из 2"); !ok {
+		t.Fatalf("no page counter\n%s", alice.Transcript())
+	}
+	if _, ok := page.Button(fmt.Sprintf("Подписчики %d", functionality.ItemsPerPage+1)); ok {
+		t.Fatal("second page item shown on the first page")
+	}
+
+	alice.Press("➡️ Вперед")
+	page = alice.Last()
+	if _, ok := page.Button(fmt.Sprintf("Подписчики %d", functionality.ItemsPerPage+1)); !ok {
+		t.Fatalf("second page not shown\n%s", alice.Transcript())
+	}
+	alice.Press("⬅️ Назад")
+
+	alice.Press("Подписчики 1")
+	alice.Expect("Выберите услугу:")
+	alice.Press("Живые подписчики")
+	card := alice.Last()
+	for _, button := range []string{"🔙Вернуться к услугам", "➕Заказать", "✅Добавить в избранное"} {
+		if _, ok := card.Button(button); !ok {
+			t.Fatalf("service card has no %q button\n%s", button, alice.Transcript())
+		}
+	}
+}
+
+func TestPromoCodeCreditsBalance(t *testing.T) {
+	env := newTestEnv(t)
+	env.db.Create(&models.PromoCode{Code: "BONUS500", Discount: 500, MaxActivations: 1, Type: "fixed"})
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.Send(defaultMenu.Balance)
+	alice.Expect("Ваш баланс")
+	alice.Press("🎁Промокод")
+	alice.Expect("Введите ваш промокод")
+	alice.Send("BONUS500")
+
+	alice.ExpectAny("Поздравляем, Вы активировали промокод")
+	alice.Expect("Промокод успешно применен.")
+	if balance := env.userState(1001).Balance; balance != 500/testRate {
+		t.Fatalf("balance = %v, want %v", balance, 500/testRate)
+	}
+
+	// Повторная активация не начисляет бонус
+	alice.Send(defaultMenu.Balance)
+	alice.Press("🎁Промокод")
+	alice.Send("BONUS500")
+	alice.Expect("максимальное количество раз")
+	if balance := env.userState(1001).Balance; balance != 500/testRate {
+		t.Fatalf("balance after second activation = %v", balance)
+	}
+}
+
+func TestBalanceIsShownInChosenCurrency(t *testing.T) {
+	env := newTestEnv(t)
+	currency.SetRate("EUR", 0.5)
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+	env.db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", testBotID, 1001).Update("balance", 10)
+
+	alice.Send(defaultMenu.Balance)
+	alice.Expect("Ваш баланс: ₽1000.00")
+
+	alice.Send(defaultMenu.Profile)
+	alice.Press("⚙️Настройки")
+	alice.Press("EUR")
+	alice.Expect("Валюта изменена на евро.")
+	if code := env.userState(1001).Currency; code != "EUR" {
+		t.Fatalf("currency = %q, want EUR", code)
+	}
+
+	alice.Send(defaultMenu.Balance)
+	alice.Expect("Ваш баланс: €5.00")
+}
+
+func TestPurchaseDebitsBalanceAndCreatesOrder(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+	env.db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", testBotID, 1001).Update("balance", 10)
+
+	alice.Press("💎 Telegram")
+	alice.Press("Подписчики 1")
+	alice.Press("Живые подписчики")
+	alice.Press("➕Заказать")
+	alice.Expect("Для оформления заказа укажите ссылку")
+
+	alice.Send("not a link")
+	alice.Expect("Введите ссылку корректно.")
+	alice.Send("https://t.me/alice_channel")
+	alice.Expect("Введите количество")
+	alice.Send("50")
+	alice.Expect("Количество должно быть в диапазоне от 100 до 10000.")
+	alice.Send("1000")
+	alice.Expect("Цена услуги: ₽200")
+
+	alice.Press("💰Купить")
+	alice.ExpectAny("Заказ успешно создан. ID услуги: 101")
+	alice.Expect("Заказ создан, ожидайте.")
+
+	if orders := env.sentOrders(); len(orders) != 1 || orders[0]["link"] != "https://t.me/alice_channel" {
+		t.Fatalf("orders sent to provider = %v", orders)
+	}
+	if balance := env.userState(1001).Balance; balance != 8 {
+		t.Fatalf("balance = %v, want 8", balance)
+	}
+	var order models.UserOrders
+	if err := env.db.Where("bot_id = ? AND user_id = ?", testBotID, "1001").First(&order).Error; err != nil {
+		t.Fatalf("order not saved: %v", err)
+	}
+	if order.Quantity != 1000 || order.Status != "PENDING" {
+		t.Fatalf("order = %+v", order)
+	}
+
+	// В статистике владельца популярная услуга показана по названию
+	env.db.Model(&models.BotOwners{}).Where("id = ?", testBotID).Update("token", "token")
+	owner := env.fake.User(t, newManagerRouter(env.db, "manager_bot").Handle, tgbotapi.User{ID: 1, FirstName: "owner"})
+	owner.Send("/start")
+	owner.PressData(owner.Last(), fmt.Sprintf("botstats:%d:all", testBotID))
+	owner.Expect("1. Живые подписчики — 1 заказ")
+
+	// Заказ попадает и в статистику за сегодня, где отбор идет по дате создания
+	owner.PressData(owner.Last(), fmt.Sprintf("botstats:%d:today", testBotID))
+	today := owner.Expect("1. Живые подписчики — 1 заказ")
+	if !strings.Contains(today.Text, "💰 Оборот: $2.00") {
+		t.Fatalf("today stats:\n%s", today.Text)
+	}
+}
+
+func TestPurchaseThroughPanelProvider(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	api.Default = api.NewPanel("panel", env.apiURL+"/panel", "panel-key")
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+	env.db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", testBotID, 1001).Update("balance", 10)
+
+	alice.Press("💎 Telegram")
+	alice.Press("Подписчики 1")
+	alice.Press("Живые подписчики")
+	alice.Press("➕Заказать")
+	alice.Send("https://t.me/alice_channel")
+	alice.Send("1000")
+	alice.Press("💰Купить")
+	alice.ExpectAny("Заказ успешно создан. ID услуги: 101")
+
+	orders := env.sentOrders()
+	if len(orders) != 1 || orders[0]["serviceId"] != "101" || orders[0]["quantity"] != "1000" {
+		t.Fatalf("orders sent to panel = %v", orders)
+	}
+	var order models.UserOrders
+	if err := env.db.Where("bot_id = ? AND user_id = ?", testBotID, "1001").First(&order).Error; err != nil {
+		t.Fatalf("order not saved: %v", err)
+	}
+	if order.OrderID != 9001 || order.Cost != 2 {
+		t.Fatalf("order = %+v", order)
+	}
+}
+
+func TestPurchaseRoutesToCheapestLinkedProvider(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	api.Register(api.NewPanel("cheap", env.apiURL+"/panel", "panel-key"))
+	api.Register(api.NewPanel("broken", env.apiURL+"/panel", "wrong-key"))
+	env.db.Create(&models.ServiceLink{CatalogServiceID: 101, Provider: "cheap", ProviderServiceID: "555", Priority: 1, Rate: 1, Min: 100, Max: 10000})
+	// Самый дешевый, но не подходит по лимитам
+	env.db.Create(&models.ServiceLink{CatalogServiceID: 101, Provider: "broken", ProviderServiceID: "777", Priority: 1, Rate: 0.1, Min: 5000, Max: 10000})
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+	env.db.Model(&models.UserState{}).Where("bot_id = ? AND user_id = ?", testBotID, 1001).Update("balance", 10)
+
+	buy := func() {
+		alice.Send("/start")
+		alice.Press("💎 Telegram")
+		alice.Press("Подписчики 1")
+		alice.Press("Живые подписчики")
+		alice.Press("➕Заказать")
+		alice.Send("https://t.me/alice_channel")
+		alice.Send("1000")
+		alice.Press("💰Купить")
+		alice.ExpectAny("Заказ успешно создан. ID услуги: 101")
+	}
+	buy()
+	if orders := env.sentOrders(); len(orders) != 1 || orders[0]["serviceId"] != "555" {
+		t.Fatalf("orders sent = %v", orders)
+	}
+
+	// Ошибка дешевого поставщика - заказ уходит следующему
+	env.db.Model(&models.ServiceLink{}).Where("provider = ?", "broken").Updates(map[string]interface{}{"min": 100})
+	buy()
+	if orders := env.sentOrders(); len(orders) != 2 || orders[1]["serviceId"] != "555" {
+		t.Fatalf("orders sent = %v", orders)
+	}
+
+	var orders []models.UserOrders
+	env.db.Where("bot_id = ?", testBotID).Order("id").Find(&orders)
+	if len(orders) != 2 || orders[0].Provider != "cheap" || orders[0].OrderID != 9001 || orders[1].Provider != "cheap" {
+		t.Fatalf("orders = %+v", orders)
+	}
+
+	// При SMM_ROUTING=preferred привязка с приоритетом по умолчанию идет раньше основного поставщика, даже дороже него
+	defaults := config.Current()
+	preferred := defaults
+	preferred.RoutingPolicy = "preferred"
+	config.Apply(&config.Config{Runtime: preferred})
+	t.Cleanup(func() { config.Apply(&config.Config{Runtime: defaults}) })
+	env.db.Where("provider = ?", "broken").Delete(&models.ServiceLink{})
+	env.db.Model(&models.ServiceLink{}).Where("provider = ?", "cheap").Update("rate", 5)
+	buy()
+	if orders := env.sentOrders(); len(orders) != 3 || orders[2]["serviceId"] != "555" {
+		t.Fatalf("orders sent = %v", orders)
+	}
+	// Количество меньше минимума основного поставщика принимает привязка с более широкими лимитами
+	env.db.Model(&models.ServiceLink{}).Where("provider = ?", "cheap").Update("min", 50)
+	alice.Send("/start")
+	alice.Press("💎 Telegram")
+	alice.Press("Подписчики 1")
+	alice.Press("Живые подписчики")
+	alice.Press("➕Заказать")
+	alice.Send("https://t.me/alice_channel")
+	alice.Expect("Минимальное: 50, максимальное: 10000.")
+	alice.Send("10")
+	alice.Expect("Количество должно быть в диапазоне от 50 до 10000.")
+	alice.Send("50")
+	alice.Press("💰Купить")
+	alice.ExpectAny("Заказ успешно создан. ID услуги: 101")
+	if orders := env.sentOrders(); len(orders) != 4 || orders[3]["serviceId"] != "555" || orders[3]["quantity"] != "50" {
+		t.Fatalf("orders sent = %v", orders)
+	}
+}
+
+func (env *testEnv) setPanelServices(services string) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	env.panelServices = services
+}
+
+func TestCatalogSyncAppliesDiffAndLogsChanges(t *testing.T) {
+	env := newTestEnv(t)
+	api.Default = api.NewPanel("panel", env.apiURL+"/panel", "panel-key")
+	env.setPanelServices(`[
+		{"service": 1, "name": "Followers", "category": "Instagram Followers", "rate": "0.90", "min": "50", "max": "10000"},
+		{"service": 2, "name": "Likes", "category": "Instagram Likes", "rate": 0.5, "min": 10, "max": 5000},
+		{"service": 3, "name": "Views", "category": "Telegram Views", "rate": "0.10", "min": "100", "max": "100000"}
+	]`)
+	database.SyncCatalog(context.Background(), env.db)
+
+	var categories []models.Category
+	env.db.Order("category_id").Find(&categories)
+	if len(categories) != 2 || categories[0].ID != "instagram" || categories[1].Name != "Telegram" {
+		t.Fatalf("categories = %+v", categories)
+	}
+	if service, err := database.GetService(env.db, 1); err != nil || service.Rate != 0.9 || service.CategoryID != "Instagram Followers" {
+		t.Fatalf("service 1 = %+v, %v", service, err)
+	}
+
+	env.setPanelServices(`[
+		{"service": 1, "name": "Followers", "category": "Instagram Followers", "rate": "1.20", "min": "50", "max": "10000"},
+		{"service": 2, "name": "Likes", "category": "Instagram Likes", "rate": 0.5, "min": 10, "max": 8000}
+	]`)
+	database.SyncCatalog(context.Background(), env.db)
+
+	if _, err := database.GetService(env.db, 3); err == nil {
+		t.Fatalf("removed service is still visible")
+	}
+	var subcategory models.Subcategory
+	if err := env.db.Where("subcategory_id = ?", "Telegram Views").First(&subcategory).Error; err == nil {
+		t.Fatalf("empty subcategory is still visible")
+	}
+	changes, err := database.GetCatalogChanges(env.db, 0, 10)
+	if err != nil || len(changes) != 6 {
+		t.Fatalf("changes = %+v, %v", changes, err)
+	}
+	kinds := make(map[int]models.CatalogChange)
+	for _, change := range changes[:3] {
+		kinds[change.ServiceID] = change
+	}
+	if c := kinds[1]; c.Kind != "updated" || !c.PriceChanged || c.LimitsChanged || c.OldRate != 0.9 || c.NewRate != 1.2 {
+		t.Fatalf("change of service 1 = %+v", c)
+	}
+	if c := kinds[2]; c.Kind != "updated" || c.PriceChanged || !c.LimitsChanged || c.NewMax != 8000 {
+		t.Fatalf("change of service 2 = %+v", c)
+	}
+	if c := kinds[3]; c.Kind != "removed" {
+		t.Fatalf("change of service 3 = %+v", c)
+	}
+
+	// Без изменений у поставщика журнал не пополняется
+	database.SyncCatalog(context.Background(), env.db)
+	if changes, _ := database.GetCatalogChanges(env.db, 0, 10); len(changes) != 6 {
+		t.Fatalf("changes after idle sync = %d", len(changes))
+	}
+
+	// Вернувшаяся услуга восстанавливается вместе с разделом
+	env.setPanelServices(`[
+		{"service": 1, "name": "Followers", "category": "Instagram Followers", "rate": "1.20", "min": "50", "max": "10000"},
+		{"service": 2, "name": "Likes", "category": "Instagram Likes", "rate": 0.5, "min": 10, "max": 8000},
+		{"service": 3, "name": "Views", "category": "Telegram Views", "rate": "0.10", "min": "100", "max": "100000"}
+	]`)
+	database.SyncCatalog(context.Background(), env.db)
+	if _, err := database.GetService(env.db, 3); err != nil {
+		t.Fatalf("service 3 not restored: %v", err)
+	}
+	if _, err := database.GetSubcategoryByID(env.db, "Telegram Views"); err != nil {
+		t.Fatalf("subcategory not restored: %v", err)
+	}
+	if changes, _ := database.GetCatalogChanges(env.db, 3, 10); len(changes) != 3 || changes[0].Kind != "added" {
+		t.Fatalf("changes of service 3 = %+v", changes)
+	}
+}
+
+func TestPurchaseWithoutFundsOffersTopUp(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.Press("💎 Telegram")
+	alice.Press("Подписчики 1")
+	alice.Press("Живые подписчики")
+	alice.Press("➕Заказать")
+	alice.Send("https://t.me/alice_channel")
+	alice.Send("1000")
+
+	m := alice.Last()
+	if _, ok := m.Button("⚡️Пополнить баланс"); !ok {
+		t.Fatalf("no top up button\n%s", alice.Transcript())
+	}
+	if orders := env.sentOrders(); len(orders) != 0 {
+		t.Fatalf("orders sent to provider = %v", orders)
+	}
+}
+
+func TestAAIOPaymentLink(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.Send(defaultMenu.Balance)
+	alice.Press("⚡️Пополнить баланс")
+	alice.Expect("Выберите платежную систему")
+	alice.Press("СБП|RUB")
+	alice.Expect("Введите желаемую сумму в рублях.")
+	alice.Send("abc")
+	alice.Expect("Введите корректную сумму.")
+	alice.Send("500")
+
+	m := alice.ExpectAny("Для пополнения на сумму ₽500.00")
+	button, ok := m.Button("Оплатить")
+	if !ok || button.URL == nil || !strings.Contains(*button.URL, "merchant_id=shop") {
+		t.Fatalf("payment button = %+v\n%s", button, alice.Transcript())
+	}
+	var p models.Payments
+	if err := env.db.Where("bot_id = ? AND user_id = ?", testBotID, 1001).First(&p).Error; err != nil {
+		t.Fatalf("payment not saved: %v", err)
+	}
+	if p.Type != "aaio" || p.Amount != 5 || p.Status != "check" {
+		t.Fatalf("payment = %+v", p)
+	}
+	if state := fsm.Default.State(testBotID, 1001); state != "" {
+		t.Fatalf("conversation not finished: %q", state)
+	}
+}
+
+func TestCancelEndsConversation(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.Send(defaultMenu.Balance)
+	alice.Press("🎁Промокод")
+	alice.Send("Отмена")
+
+	if state := fsm.Default.State(testBotID, 1001); state != "" {
+		t.Fatalf("conversation not cleared: %q", state)
+	}
+	if got := alice.Last().ReplyButtons(); len(got) == 0 {
+		t.Fatalf("menu keyboard not restored\n%s", alice.Transcript())
+	}
+}
+
+func TestStaleButtonIsAnswered(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	alice.PressData(alice.Last(), "category:tg")
+
+	answers := env.fake.Answers()
+	if len(answers) == 0 || answers[len(answers)-1].Text != "Кнопка устарела, откройте меню заново." {
+		t.Fatalf("answers = %+v", answers)
+	}
+}
+
+func TestBroadcastMarksBlockedUsers(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.user(1, "admin")
+	alice := env.user(1001, "alice")
+	bob := env.user(1002, "bob")
+	for _, u := range []*telegramtest.User{admin, alice, bob} {
+		u.Send("/start")
+	}
+	env.fake.SetMember(testChannelID, 1, "administrator")
+
+	alice.Send("/broadcast Новые услуги")
+	alice.Expect("У вас нет прав доступа к этой команде.")
+
+	env.fake.FailChat(1002, telegramtest.ErrBlocked)
+
+	admin.Send("/broadcast Новые услуги")
+	admin.ExpectAny("Рассылка началась.")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !env.userState(1002).Unreachable {
+		if time.Now().After(deadline) {
+			t.Fatal("blocked user not marked unreachable")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	alice.Expect("Новые услуги")
+	if env.userState(1001).Unreachable {
+		t.Fatal("reachable user marked unreachable")
+	}
+}
+
+func TestFavoriteFollowersAreNotifiedAboutCatalogChanges(t *testing.T) {
+	env := newTestEnv(t)
+	api.Default = api.NewPanel("panel", env.apiURL+"/panel", "panel-key")
+	env.setPanelServices(`[{"service": 1, "name": "Followers", "category": "Telegram Followers", "rate": "1.00", "min": "50", "max": "10000"}]`)
+	database.SyncCatalog(context.Background(), env.db)
+
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+	if err := database.AddServiceToFavorites(env.db, testBotID, 1001, 1); err != nil {
+		t.Fatalf("add favorite: %v", err)
+	}
+	sync := func(services string) {
+		env.setPanelServices(services)
+		functionality.NotifyFavoriteChanges(context.Background(), env.db, database.SyncCatalog(context.Background(), env.db))
+	}
+
+	// Изменение меньше порога не сообщается
+	before := len(env.fake.Messages(alice.ChatID()))
+	sync(`[{"service": 1, "name": "Followers", "category": "Telegram Followers", "rate": "1.02", "min": "50", "max": "10000"}]`)
+	if got := len(env.fake.Messages(alice.ChatID())); got != before {
+		t.Fatalf("alert for a small price change:\n%s", alice.Transcript())
+	}
+
+	sync(`[{"service": 1, "name": "Followers", "category": "Telegram Followers", "rate": "2.00", "min": "50", "max": "10000"}]`)
+	alice.Expect("Цена услуги «Followers» из избранного выросла: ₽102.00 → ₽200.00")
+	alice.Press("➕Заказать")
+	alice.Expect("Для оформления заказа укажите ссылку")
+	alice.Send("Отмена")
+
+	sync(`[{"service": 2, "name": "Likes", "category": "Telegram Likes", "rate": "0.50", "min": "10", "max": "5000"}]`)
+	alice.Expect("Услуга «Followers» из избранного больше недоступна")
+
+	// После отмены рассылка прекращается
+	env.setPanelServices(`[{"service": 1, "name": "Followers", "category": "Telegram Followers", "rate": "2.00", "min": "50", "max": "10000"}]`)
+	changes := database.SyncCatalog(context.Background(), env.db)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	before = len(env.fake.Messages(alice.ChatID()))
+	functionality.NotifyFavoriteChanges(canceled, env.db, changes)
+	if got := len(env.fake.Messages(alice.ChatID())); len(changes) == 0 || got != before {
+		t.Fatalf("alert sent after cancel (%d changes):\n%s", len(changes), alice.Transcript())
+	}
+
+	alice.Send(defaultMenu.Profile)
+	alice.Press("⚙️Настройки")
+	alice.Press("🔔 Уведомления об избранном: вкл")
+	alice.Expect("Уведомления об избранных услугах отключены")
+	if !env.userState(1001).FavoriteAlertsMuted {
+		t.Fatalf("alerts are not muted")
+	}
+
+	before = len(env.fake.Messages(alice.ChatID()))
+	sync(`[{"service": 2, "name": "Likes", "category": "Telegram Likes", "rate": "0.50", "min": "10", "max": "5000"}]`)
+	if got := len(env.fake.Messages(alice.ChatID())); got != before {
+		t.Fatalf("alert sent to a muted user:\n%s", alice.Transcript())
+	}
+}
+
+func TestSearchFindsServicesBySynonymsWithFilters(t *testing.T) {
+	env := newTestEnv(t)
+	env.seedCatalog()
+	records := []interface{}{
+		&models.Category{ID: "ig", Name: "Instagram"},
+		&models.Subcategory{ID: "ig-1", Name: "Подписчики Instagram", CategoryID: "ig"},
+		&models.Subcategory{ID: "yt-1", Name: "YouTube", CategoryID: "yt"},
+		&models.Services{ID: 201, ServiceID: "s201", Name: "YouTube Subscribers", CategoryID: "yt-1", Min: 10, Max: 1000, Rate: 1, Refill: true},
+		&models.Services{ID: 301, ServiceID: "s301", Name: "Instagram Followers HQ", CategoryID: "ig-1", Min: 10, Max: 1000, Rate: 3, Refill: true},
+		&models.Services{ID: 302, ServiceID: "s302", Name: "Followers [no refill]", CategoryID: "ig-1", Min: 10, Max: 1000, Rate: 1},
+	}
+	for i := 1; i <= functionality.ItemsPerPage+1; i++ {
+		records = append(records, &models.Services{ID: 400 + i, ServiceID: fmt.Sprintf("s%d", 400+i), Name: fmt.Sprintf("Views %d", i), CategoryID: "yt-1", Min: 10, Max: 1000, Rate: 0.1})
+	}
+	for _, record := range records {
+		if err := env.db.Create(record).Error; err != nil {
+			t.Fatalf("seed %T: %v", record, err)
+		}
+	}
+	alice := env.user(1001, "alice")
+	alice.Send("/start")
+
+	// "subs" находит и русские, и английские названия, дешевые выше
+	alice.Send("/search subs")
+	results := alice.Expect("По запросу «subs» найдено 4 услуги")
+	if buttons := results.Buttons(); len(buttons) != 5 || buttons[0][0] != "YouTube Subscribers · ₽100.00" || buttons[3][0] != "Instagram Followers HQ · ₽300.00" {
+		t.Fatalf("results = %v", buttons)
+	}
+
+	alice.Send("/search подписчиков #instagram refill")
+	alice.Expect("найдена 1 услуга")
+	alice.Send("/search followers до 150")
+	alice.Expect("найдено 2 услуги")
+	// Кнопки страниц несут только номер, запрос хранится в состоянии
+	alice.Send("/search просмотры")
+	alice.Expect("найдено 12 услуг")
+	alice.Press("➡️ Вперед")
+	if buttons := alice.Last().Buttons(); len(buttons) != 3 || buttons[1][0] != "Просмотры · ₽50.00" || buttons[2][1] != "Страница 2 из 2" {
+		t.Fatalf("second page after /search = %v", buttons)
+	}
+	// Следующий текст уточняет поиск, кнопка меню закрывает его
+	alice.Send("youtube subs")
+	alice.Expect("По запросу «youtube subs» найдена 1 услуга")
+	alice.Send(defaultMenu.Balance)
+	alice.Expect("Ваш баланс")
+	if state := fsm.Default.State(testBotID, alice.ChatID()); state != "" {
+		t.Fatalf("search state after menu = %q", state)
+	}
+
+	// Кнопка поиска: каждое сообщение - новый запрос, предыдущие результаты удаляются
+	alice.Send("/start")
+	alice.Press("🔍 Поиск")
+	alice.Expect("Напишите, какую услугу ищете")
+	alice.Send("инстаграм лайки")
+	alice.Expect("ничего не найдено")
+	alice.Send("просмотры")
+	alice.Expect("найдено 12 услуг")
+	alice.Press("➡️ Вперед")
+	if buttons := alice.Last().Buttons(); buttons[len(buttons)-1][1] != "Страница 2 из 2" {
+		t.Fatalf("second page = %v", buttons)
+	}
+	alice.Send("тг живые подписчики")
+	alice.Expect("найдена 1 услуга")
+	for _, message := range env.fake.Messages(alice.ChatID()) {
+		if strings.Contains(message.Text, "найдено 12 услуг") && !message.Deleted {
+			t.Fatalf("previous results were not replaced")
+		}
+	}
+	alice.Press("Живые подписчики · ₽200.00")
+	alice.Expect("ID услуги: 101")
+}
+
+func TestManagerBotCardAndBranding(t *testing.T) {
+	env := newTestEnv(t)
+	botSupervisor = NewBotSupervisor(env.db)
+	t.Cleanup(func() { botSupervisor = nil })
+	env.db.Model(&models.BotOwners{}).Where("id = ?", testBotID).Update("token", "token")
+	owner := env.fake.User(t, newManagerRouter(env.db, "manager_bot").Handle, tgbotapi.User{ID: 1, FirstName: "owner"})
+	owner.Send("/start")
+
+	owner.PressData(owner.Last(), fmt.Sprintf("botinfo:%d", testBotID))
+	owner.Expect("🤖 @test_bot")
+	owner.Press("🎨Оформление")
+	owner.Press("👋Приветствие")
+	owner.Expect("Отправьте текст приветствия")
+	owner.Send("Здравствуйте, {name}!")
+	owner.Expect("@test_bot")
+	if settings, err := database.GetBotSettings(env.db, testBotID); err != nil || settings.Greeting != "Здравствуйте, {name}!" {
+		t.Fatalf("settings = %+v, %v", settings, err)
+	}
+
+	// Чужой бот не открывается
+	stranger := env.fake.User(t, newManagerRouter(env.db, "manager_bot").Handle, tgbotapi.User{ID: 2, FirstName: "stranger"})
+	stranger.Send("/start")
+	stranger.PressData(stranger.Last(), fmt.Sprintf("botinfo:%d", testBotID))
+	stranger.Expect("Бот не найден")
+}
@@ -0,0 +1,54 @@
+package fsm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Хранилище в таблице conversations, диалоги переживают перезапуск
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Load(key Key) (Conversation, bool, error) {
+	var row models.Conversation
+	err := s.db.Where("bot_id = ? AND chat_id = ?", key.BotID, key.ChatID).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Conversation{}, false, nil
+	}
+	if err != nil {
+		return Conversation{}, false, err
+	}
+	return Conversation{State: State(row.State), Payload: []byte(row.Payload), ExpiresAt: row.ExpiresAt}, true, nil
+}
+
+func (s *DBStore) Save(key Key, conv Conversation) error {
+	row := models.Conversation{
+		BotID:     key.BotID,
+		ChatID:    key.ChatID,
+		State:     string(conv.State),
+		Payload:   string(conv.Payload),
+		ExpiresAt: conv.ExpiresAt,
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bot_id"}, {Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"state", "payload", "expires_at", "updated_at"}),
+	}).Create(&row).Error
+}
+
+func (s *DBStore) Delete(key Key) error {
+	return s.db.Where("bot_id = ? AND chat_id = ?", key.BotID, key.ChatID).Delete(&models.Conversation{}).Error
+}
+
+func (s *DBStore) DeleteExpired(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at <= ?", now).Delete(&models.Conversation{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,121 @@
+package fsm
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Состояние диалога, например "order:awaitingLink". Пустое состояние - диалога нет
+type State string
+
+const DefaultTTL = 30 * time.Minute
+
+// Диалог пользователя с конкретным ботом
+type Key struct {
+	BotID  int64
+	ChatID int64
+}
+
+type Conversation struct {
+	State     State
+	Payload   json.RawMessage
+	ExpiresAt time.Time
+}
+
+// Хранилище диалогов. Истекшие диалоги хранилище может возвращать, их отбрасывает Machine
+type Store interface {
+	Load(key Key) (Conversation, bool, error)
+	Save(key Key, conv Conversation) error
+	Delete(key Key) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// У каждого пользователя бота активен не больше чем один диалог. Новый диалог
+// заменяет предыдущий, неактивный диалог истекает через ttl
+type Machine struct {
+	store Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+func New(store Store, ttl time.Duration) *Machine {
+	return &Machine{store: store, ttl: ttl, now: time.Now}
+}
+
+// Диалоги всех ботов. main заменяет хранилище в памяти на хранилище в базе
+var Default = New(NewMemoryStore(), DefaultTTL)
+
+func (m *Machine) Set(botID, chatID int64, state State, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return m.store.Save(Key{botID, chatID}, Conversation{
+		State:     state,
+		Payload:   data,
+		ExpiresAt: m.now().Add(m.ttl),
+	})
+}
+
+func (m *Machine) load(botID, chatID int64) (Conversation, bool) {
+	key := Key{botID, chatID}
+	conv, ok, err := m.store.Load(key)
+	if err != nil {
+		log.Printf("Error loading conversation %d/%d: %v", botID, chatID, err)
+		return Conversation{}, false
+	}
+	if !ok {
+		return Conversation{}, false
+	}
+	if !conv.ExpiresAt.After(m.now()) {
+		m.store.Delete(key)
+		return Conversation{}, false
+	}
+	return conv, true
+}
+
+func (m *Machine) State(botID, chatID int64) State {
+	conv, _ := m.load(botID, chatID)
+	return conv.State
+}
+
+// Удаляет активный диалог. Возвращает false, если диалога не было
+func (m *Machine) Clear(botID, chatID int64) bool {
+	if _, ok := m.load(botID, chatID); !ok {
+		return false
+	}
+	if err := m.store.Delete(Key{botID, chatID}); err != nil {
+		log.Printf("Error deleting conversation %d/%d: %v", botID, chatID, err)
+	}
+	return true
+}
+
+func (m *Machine) PurgeExpiredPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.store.DeleteExpired(m.now()); err != nil {
+			log.Printf("Error deleting expired conversations: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Состояние и данные диалога. ok = false, если диалога нет или данные другого типа
+func Get[T any](m *Machine, botID, chatID int64) (state State, payload T, ok bool) {
+	conv, found := m.load(botID, chatID)
+	if !found {
+		return "", payload, false
+	}
+	if err := json.Unmarshal(conv.Payload, &payload); err != nil {
+		log.Printf("Error decoding conversation %d/%d in state %s: %v", botID, chatID, conv.State, err)
+		return conv.State, payload, false
+	}
+	return conv.State, payload, true
+}
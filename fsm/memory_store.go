@@ -0,0 +1,50 @@
+package fsm
+
+import (
+	"sync"
+	"time"
+)
+
+// Хранилище в памяти для тестов и запуска без базы
+type MemoryStore struct {
+	mu    sync.Mutex
+	convs map[Key]Conversation
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{convs: make(map[Key]Conversation)}
+}
+
+func (s *MemoryStore) Load(key Key) (Conversation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv, ok := s.convs[key]
+	return conv, ok, nil
+}
+
+func (s *MemoryStore) Save(key Key, conv Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.convs[key] = conv
+	return nil
+}
+
+func (s *MemoryStore) Delete(key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.convs, key)
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for key, conv := range s.convs {
+		if !conv.ExpiresAt.After(now) {
+			delete(s.convs, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,152 @@
+package functionality
+
+import (
+	"log"
+
+	"github.com/Cekretik/BoostBot/callback"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Виды действий кнопок клиентского бота. Номера хранятся в уже отправленных
+// клавиатурах: новые действия получают новый номер, старые номера не переиспользуются
+const (
+	KindNoop                callback.Kind = 1
+	KindOpenCategory        callback.Kind = 2
+	KindCategoryPage        callback.Kind = 3
+	KindOpenSubcategory     callback.Kind = 4
+	KindServicePage         callback.Kind = 5
+	KindBackToSubcategories callback.Kind = 6
+	KindServiceInfo         callback.Kind = 7
+	KindOrderService        callback.Kind = 8
+	KindFavorite            callback.Kind = 9
+	KindShowFavorites       callback.Kind = 10
+	KindReplenish           callback.Kind = 11
+	KindEnterPromo          callback.Kind = 12
+	KindShowOrders          callback.Kind = 13
+	KindShowSettings        callback.Kind = 14
+	KindShowSupport         callback.Kind = 15
+	KindSetCurrency         callback.Kind = 16
+	KindBuy                 callback.Kind = 17
+	KindSetLanguage         callback.Kind = 18
+	KindSetFavoriteAlerts   callback.Kind = 19
+	KindSearch              callback.Kind = 20
+	KindSearchPage          callback.Kind = 21
+)
+
+// Кнопка без действия, например номер страницы
+type Noop struct{}
+
+// Подкатегории социальной сети
+type OpenCategory struct {
+	CategoryID string
+}
+
+type CategoryPage struct {
+	CategoryID string
+	Page       int
+}
+
+// Услуги подкатегории, первая страница
+type OpenSubcategory struct {
+	SubcategoryID string
+}
+
+type ServicePage struct {
+	SubcategoryID string
+	Page          int
+}
+
+// Возврат из списка услуг к подкатегориям той же социальной сети
+type BackToSubcategories struct {
+	SubcategoryID string
+}
+
+type ServiceInfo struct {
+	ServiceID string
+}
+
+// Начало оформления заказа, ServiceID - ID записи услуги в базе
+type OrderService struct {
+	ServiceID int
+}
+
+type Favorite struct {
+	ServiceID int
+	Add       bool
+}
+
+type ShowFavorites struct{}
+
+type Replenish struct{}
+
+type EnterPromo struct{}
+
+type ShowOrders struct{}
+
+type ShowSettings struct{}
+
+type ShowSupport struct{}
+
+type SetCurrency struct {
+	Currency string
+}
+
+type Buy struct{}
+
+type SetLanguage struct {
+	Language string
+}
+
+// Уведомления об изменении цен и наличия избранных услуг
+type SetFavoriteAlerts struct {
+	Muted bool
+}
+
+type Search struct{}
+
+// Страница результатов последнего поиска, запрос хранится в SearchConversation
+type SearchPage struct {
+	Page int
+}
+
+func (Noop) Kind() callback.Kind                { return KindNoop }
+func (OpenCategory) Kind() callback.Kind        { return KindOpenCategory }
+func (CategoryPage) Kind() callback.Kind        { return KindCategoryPage }
+func (OpenSubcategory) Kind() callback.Kind     { return KindOpenSubcategory }
+func (ServicePage) Kind() callback.Kind         { return KindServicePage }
+func (BackToSubcategories) Kind() callback.Kind { return KindBackToSubcategories }
+func (ServiceInfo) Kind() callback.Kind         { return KindServiceInfo }
+func (OrderService) Kind() callback.Kind        { return KindOrderService }
+func (Favorite) Kind() callback.Kind            { return KindFavorite }
+func (ShowFavorites) Kind() callback.Kind       { return KindShowFavorites }
+func (Replenish) Kind() callback.Kind           { return KindReplenish }
+func (EnterPromo) Kind() callback.Kind          { return KindEnterPromo }
+func (ShowOrders) Kind() callback.Kind          { return KindShowOrders }
+func (ShowSettings) Kind() callback.Kind        { return KindShowSettings }
+func (ShowSupport) Kind() callback.Kind         { return KindShowSupport }
+func (SetCurrency) Kind() callback.Kind         { return KindSetCurrency }
+func (Buy) Kind() callback.Kind                 { return KindBuy }
+func (SetLanguage) Kind() callback.Kind         { return KindSetLanguage }
+func (SetFavoriteAlerts) Kind() callback.Kind   { return KindSetFavoriteAlerts }
+func (Search) Kind() callback.Kind              { return KindSearch }
+func (SearchPage) Kind() callback.Kind          { return KindSearchPage }
+
+func init() {
+	callback.Register(
+		Noop{}, OpenCategory{}, CategoryPage{}, OpenSubcategory{}, ServicePage{},
+		BackToSubcategories{}, ServiceInfo{}, OrderService{}, Favorite{}, ShowFavorites{},
+		Replenish{}, EnterPromo{}, ShowOrders{}, ShowSettings{}, ShowSupport{}, SetCurrency{}, Buy{},
+		SetLanguage{}, SetFavoriteAlerts{}, Search{}, SearchPage{},
+	)
+}
+
+// Inline-кнопка с закодированным действием
+func ActionButton(text string, action callback.Action) tgbotapi.InlineKeyboardButton {
+	data, err := callback.Default.Encode(action)
+	if err != nil {
+		log.Printf("Error encoding callback %T: %v", action, err)
+		// Noop всегда помещается в данные кнопки
+		data, _ = callback.Default.Encode(Noop{})
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
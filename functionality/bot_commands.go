@@ -1,25 +1,25 @@
 package functionality
 
 import (
+	"context"
 	"fmt"
 	"html"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 
-	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/currency"
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 )
 
-type UserPromoStatus struct {
-	ChatID     int64
-	PromoState string
-}
+const StatePromoAwaitingCode fsm.State = "promo:awaitingPromoCode"
 
 type Entity struct {
 	Type   string
@@ -28,8 +28,6 @@ type Entity struct {
 	Length int
 }
 
-var UserPromoStatuses = make(map[int64]*UserPromoStatus)
-
 func ConvertEntities(tgEntities []tgbotapi.MessageEntity) []Entity {
 	var entities []Entity
 	for _, e := range tgEntities {
@@ -43,63 +41,70 @@ func ConvertEntities(tgEntities []tgbotapi.MessageEntity) []Entity {
 	return entities
 }
 
-func HandlePromoCommand(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
-	messageText := "✍️Введите ваш промокод:"
-	cancelKeyboard := tgbotapi.NewReplyKeyboard(
+func HandlePromoCommand(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	if err := fsm.Default.Set(botID, chatID, StatePromoAwaitingCode, struct{}{}); err != nil {
+		log.Printf("Error saving promo conversation: %v", err)
+	}
+	msg := tgbotapi.NewMessage(chatID, tr.T("promo.enter"))
+	msg.ReplyMarkup = CancelKeyboard(tr)
+	sender.Send(bot, msg)
+
+}
+
+// Обычная клавиатура с одной кнопкой отмены диалога
+func CancelKeyboard(tr i18n.Localizer) tgbotapi.ReplyKeyboardMarkup {
+	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Отмена"),
+			tgbotapi.NewKeyboardButton(tr.T("common.cancel")),
 		),
 	)
-	UserPromoStatuses[chatID] = &UserPromoStatus{
-		ChatID:     chatID,
-		PromoState: "awaitingPromoCode",
-	}
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	msg.ReplyMarkup = cancelKeyboard
-	bot.Send(msg)
-
 }
 
-func ProcessPromoCodeInput(bot *tgbotapi.BotAPI, chatID int64, promoCode string, db *gorm.DB) {
-	if promoCode == "Отмена" {
-		SendStandardKeyboard(bot, chatID)
+func ProcessPromoCodeInput(bot telegram.Client, chatID int64, promoCode string, db *gorm.DB, botID int64) {
+	if i18n.Matches("common.cancel", promoCode) {
+		SendStandardKeyboard(bot, chatID, db, botID)
 		return
 	}
 
+	tr := Tr(db, botID, chatID)
+	menu := CreateQuickReplyMarkup(GetBranding(db, botID, tr))
+
 	var promo models.PromoCode
 	if err := db.Where("code = ?", promoCode).First(&promo).Error; err != nil {
-		msg := tgbotapi.NewMessage(chatID, "Промокод не найден.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, tr.T("promo.not_found"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
 	if promo.Activations >= promo.MaxActivations {
-		msg := tgbotapi.NewMessage(chatID, "Этот промокод уже использован максимальное количество раз.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, tr.T("promo.exhausted"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
 
 	var usedPromo models.UsedPromoCode
-	if err := db.Where("user_id = ? AND promo_code = ?", chatID, promoCode).First(&usedPromo).Error; err == nil {
-		msg := tgbotapi.NewMessage(chatID, "Вы уже использовали этот промокод.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+	if err := db.Where("bot_id = ? AND user_id = ? AND promo_code = ?", botID, chatID, promoCode).First(&usedPromo).Error; err == nil {
+		msg := tgbotapi.NewMessage(chatID, tr.T("promo.already_used"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
-	rate, err := api.GetCurrencyRate()
+	// Сумма промокода задается в рублях, баланс хранится в долларах
+	bonusUSD, err := currency.ToUSD(promo.Discount, "RUB")
 	if err != nil {
 		log.Printf("Error getting currency rate: %v", err)
 		return
 	}
-	bonusInRubles := promo.Discount / rate
 	switch promo.Type {
 	case "fixed":
-		database.UpdateUserBalance(db, chatID, bonusInRubles)
-		congratulationMessage := fmt.Sprintf("🎁 Поздравляем, Вы активировали промокод!\n\n🌟 Ваш баланс пополнен на %.2fр", promo.Discount)
-		bot.Send(tgbotapi.NewMessage(chatID, congratulationMessage))
+		database.UpdateUserBalance(db, botID, chatID, bonusUSD)
+		congratulationMessage := tr.T("promo.activated", i18n.Args{"amount": fmt.Sprintf("%.2f", promo.Discount)})
+		sender.Send(bot, tgbotapi.NewMessage(chatID, congratulationMessage))
 	}
 	newUsedPromo := models.UsedPromoCode{
+		BotID:     botID,
 		UserID:    chatID,
 		PromoCode: promoCode,
 		Used:      true,
@@ -109,34 +114,31 @@ func ProcessPromoCodeInput(bot *tgbotapi.BotAPI, chatID int64, promoCode string,
 	promo.Activations++
 	db.Save(&promo)
 
-	msg := tgbotapi.NewMessage(chatID, "Промокод успешно применен.")
-	msg.ReplyMarkup = CreateQuickReplyMarkup()
-	bot.Send(msg)
+	msg := tgbotapi.NewMessage(chatID, tr.T("promo.applied"))
+	msg.ReplyMarkup = menu
+	sender.Send(bot, msg)
 }
 
-func HandleCreatePromoCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *gorm.DB) {
-	if !IsAdmin(bot, int64(update.Message.From.ID)) {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "У вас нет прав доступа к этой команде."))
-		return
-	}
-
+// Права администратора проверяются маршрутизатором
+func HandleCreatePromoCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	tr := i18n.For(i18n.Match(update.Message.From.LanguageCode))
 	args := strings.Split(update.Message.Text, " ")
 
 	if len(args) != 4 {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверный формат. Используйте: /createpromo [название] [скидка] [максимальное количество использований]"))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createpromo.usage")))
 		return
 	}
 
 	promoName := args[1]
 	discount, err := strconv.ParseFloat(args[2], 64)
 	if err != nil || discount <= 0 {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверный формат скидки."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createpromo.bad_discount")))
 		return
 	}
 
 	maxActivations, err := strconv.ParseInt(args[3], 10, 64)
 	if err != nil || maxActivations <= 0 {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверный формат количества использований."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createpromo.bad_limit")))
 		return
 	}
 
@@ -149,26 +151,16 @@ func HandleCreatePromoCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *
 	}
 
 	if err := db.Create(&promo).Error; err != nil {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Промокод с таким названием уже существует."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createpromo.exists")))
 		return
 	}
 
-	bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Промокод создан: %s", promo.Code)))
+	sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createpromo.created", i18n.Args{"code": promo.Code})))
 }
-func IsAdmin(bot *tgbotapi.BotAPI, userID int64) bool {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	channelIDStr := os.Getenv("CHANNEL_ID")
-	channelID, err := strconv.ParseInt(channelIDStr, 10, 64)
-	if err != nil {
-		log.Fatalf("Error parsing CHANNEL_ID: %v", err)
-	}
-
+func IsAdmin(bot telegram.Client, userID int64) bool {
 	chatMemberConfig := tgbotapi.GetChatMemberConfig{
 		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
-			ChatID: channelID,
+			ChatID: platform.ChannelID,
 			UserID: userID,
 		},
 	}
@@ -181,39 +173,32 @@ func IsAdmin(bot *tgbotapi.BotAPI, userID int64) bool {
 	return member.Status == "administrator" || member.Status == "creator"
 }
 
-func HandleCreateUrlCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *gorm.DB) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	botLink := os.Getenv("BOT_LINK")
-	if !IsAdmin(bot, int64(update.Message.From.ID)) {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "У вас не достаточно прав"))
-		return
-	}
+func HandleCreateUrlCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	botLink := platform.BotLink
+	tr := i18n.For(i18n.Match(update.Message.From.LanguageCode))
 
 	args := strings.Split(update.Message.Text, " ")
 	if len(args) != 4 {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Неверный формат. Используйте: /createurl [название] [сумма] [кол-во переходов]"))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createurl.usage")))
 		return
 	}
 
 	linkName, amountStr, maxClicksStr := args[1], args[2], args[3]
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка в формате суммы."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createurl.bad_amount")))
 		return
 	}
 	maxClicks, err := strconv.ParseInt(maxClicksStr, 10, 64)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка в формате количества переходов."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createurl.bad_clicks")))
 		return
 	}
 
 	linkCode := GenerateSpecialLink(linkName)
 	var existingPromo models.PromoCode
 	if db.Where("code = ?", linkCode).First(&existingPromo).Error == nil {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ссылка с таким названием уже была создана ранее."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createurl.exists")))
 		return
 	}
 	promo := models.PromoCode{
@@ -225,51 +210,54 @@ func HandleCreateUrlCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *go
 	}
 	db.Create(&promo)
 	specialLink := fmt.Sprintf(botLink+"?start=%s", linkCode)
-	bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, fmt.Sprintf("Ссылка создана: %s", specialLink)))
+	sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.createurl.created", i18n.Args{"link": specialLink})))
 }
 
 func GenerateSpecialLink(linkName string) string {
 	return fmt.Sprint(linkName) + "_"
 }
-func ProcessSpecialLink(bot *tgbotapi.BotAPI, chatID int64, linkCode string, db *gorm.DB) {
+func ProcessSpecialLink(bot telegram.Client, chatID int64, linkCode string, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	menu := CreateQuickReplyMarkup(GetBranding(db, botID, tr))
 	var promo models.PromoCode
 
 	if err := db.Where("code = ?", linkCode).First(&promo).Error; err != nil {
-		msg := tgbotapi.NewMessage(chatID, "Спец. ссылка не найдена.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, tr.T("link.not_found"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
 
 	if promo.Activations >= promo.MaxActivations {
-		msg := tgbotapi.NewMessage(chatID, "Эта спец. ссылка уже использована максимальное количество раз.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, tr.T("link.exhausted"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
 
 	var usedPromo models.UsedPromoCode
-	if err := db.Where("user_id = ? AND promo_code = ?", chatID, linkCode).First(&usedPromo).Error; err == nil {
-		msg := tgbotapi.NewMessage(chatID, "Вы уже переходили по этой спец. ссылке.")
-		msg.ReplyMarkup = CreateQuickReplyMarkup()
-		bot.Send(msg)
+	if err := db.Where("bot_id = ? AND user_id = ? AND promo_code = ?", botID, chatID, linkCode).First(&usedPromo).Error; err == nil {
+		msg := tgbotapi.NewMessage(chatID, tr.T("link.already_used"))
+		msg.ReplyMarkup = menu
+		sender.Send(bot, msg)
 		return
 	}
 
-	rate, err := api.GetCurrencyRate()
+	// Сумма промокода задается в рублях, баланс хранится в долларах
+	bonusUSD, err := currency.ToUSD(promo.Discount, "RUB")
 	if err != nil {
 		log.Printf("Error getting currency rate: %v", err)
 		return
 	}
-	bonusInRubles := promo.Discount / rate
 
-	database.UpdateUserBalance(db, chatID, bonusInRubles)
-	congratulationMessage := fmt.Sprintf("🎁 Поздравляем, Вы активировали промокод!\n\n🌟 Ваш баланс пополнен на %.2fр", promo.Discount)
-	bot.Send(tgbotapi.NewMessage(chatID, congratulationMessage))
+	database.UpdateUserBalance(db, botID, chatID, bonusUSD)
+	congratulationMessage := tr.T("promo.activated", i18n.Args{"amount": fmt.Sprintf("%.2f", promo.Discount)})
+	sender.Send(bot, tgbotapi.NewMessage(chatID, congratulationMessage))
 	promo.Activations++
 	db.Save(&promo)
 
 	newUsedPromo := models.UsedPromoCode{
+		BotID:     botID,
 		UserID:    chatID,
 		PromoCode: linkCode,
 		Used:      true,
@@ -277,31 +265,22 @@ func ProcessSpecialLink(bot *tgbotapi.BotAPI, chatID int64, linkCode string, db
 	db.Create(&newUsedPromo)
 }
 
-func HandleBonusCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *gorm.DB) {
-	if IsAdmin(bot, int64(update.Message.From.ID)) {
-		bonusActive = !bonusActive
-		message := "Бонус за подписку деактивирован."
-
-		if bonusActive {
-			bonusGiven = 0
-			message = "Бонус за подписку активирован."
-		}
-
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, message))
-	} else {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "У вас нет прав доступа к этой команде."))
+func HandleBonusCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	tr := i18n.For(i18n.Match(update.Message.From.LanguageCode))
+	message := tr.T("admin.bonus.off")
+	if bonus.toggle() {
+		message = tr.T("admin.bonus.on")
 	}
-}
 
-func HandleBroadcastCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *gorm.DB) {
-	if !IsAdmin(bot, int64(update.Message.From.ID)) {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "У вас нет прав для выполнения этой команды."))
-		return
-	}
+	sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, message))
+}
 
+// Рассылка прекращается при остановке бота (botCtx) или приложения
+func HandleBroadcastCommand(botCtx context.Context, bot telegram.Client, update tgbotapi.Update, db *gorm.DB, botID int64) {
+	tr := i18n.For(i18n.Match(update.Message.From.LanguageCode))
 	parts := strings.SplitN(update.Message.Text, " ", 2)
 	if len(parts) < 2 || len(parts[1]) == 0 {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Пожалуйста, укажите сообщение для рассылки."))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.broadcast.empty")))
 		return
 	}
 
@@ -322,27 +301,42 @@ func HandleBroadcastCommand(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *go
 
 	formattedMessage, err := FormatBroadcastMessage(message, entities)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Ошибка форматирования сообщения: "+err.Error()))
+		sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.broadcast.format_error", i18n.Args{"error": err})))
 		return
 	}
 
-	go BroadcastMessage(bot, db, formattedMessage)
-	bot.Send(tgbotapi.NewMessage(update.Message.Chat.ID, "Рассылка началась."))
+	startTask(fmt.Sprintf("broadcast of bot %d", botID), func(appCtx context.Context) {
+		ctx, cancel := withEither(botCtx, appCtx)
+		defer cancel()
+		BroadcastMessage(ctx, bot, db, botID, formattedMessage)
+	})
+	sender.Send(bot, tgbotapi.NewMessage(update.Message.Chat.ID, tr.T("admin.broadcast.started")))
 }
-func BroadcastMessage(bot *tgbotapi.BotAPI, db *gorm.DB, message string) {
+func BroadcastMessage(ctx context.Context, bot telegram.Client, db *gorm.DB, botID int64, message string) {
 	var users []models.UserState
-	db.Find(&users).Where("previously_subscribed = ? AND subscribed = ?", true, true)
-
-	for _, user := range users {
+	db.Where("bot_id = ? AND unreachable = ?", botID, false).Find(&users)
+
+	// Скорость рассылки ограничивает sender, заблокировавшие бота пользователи отмечаются в его OnBlocked
+	var delivered, blocked, failed int
+	for i, user := range users {
+		if ctx.Err() != nil {
+			log.Printf("Рассылка бота %d прервана: доставлено %d, заблокировали бота %d, ошибок %d, не отправлено %d.", botID, delivered, blocked, failed, len(users)-i)
+			return
+		}
 		msg := tgbotapi.NewMessage(user.UserID, message)
 		msg.ParseMode = tgbotapi.ModeHTML
-		_, err := bot.Send(msg)
-		if err != nil {
-			log.Printf("Не удалось отправить сообщение пользователю с chat ID %d: %v", user.UserID, err)
+		_, err := sender.Send(bot, msg)
+		switch {
+		case err == nil:
+			delivered++
+		case sender.IsBlocked(err):
+			blocked++
+		default:
+			failed++
 		}
 	}
 
-	log.Println("Рассылка завершена.")
+	log.Printf("Рассылка бота %d завершена: доставлено %d, заблокировали бота %d, ошибок %d.", botID, delivered, blocked, failed)
 }
 
 func FormatBroadcastMessage(message string, entities []Entity) (string, error) {
@@ -390,23 +384,13 @@ func FormatBroadcastMessage(message string, entities []Entity) (string, error) {
 	return formattedMessage.String(), nil
 }
 
-func NotifyAdminsAboutNewUser(bot *tgbotapi.BotAPI, user *tgbotapi.User, isPremium bool, db *gorm.DB) {
-	if !database.UserIsNew(db, user.ID) {
+func NotifyAdminsAboutNewUser(bot telegram.Client, user *tgbotapi.User, isPremium bool, db *gorm.DB, botID int64) {
+	if !database.UserIsNew(db, botID, user.ID) {
 		return
 	}
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	channelIDStr := os.Getenv("CHANNEL_ID")
-	channelID, err := strconv.ParseInt(channelIDStr, 10, 64)
-	if err != nil {
-		log.Fatalf("Error parsing CHANNEL_ID: %v", err)
-	}
-
 	chatAdministratorsConfig := tgbotapi.ChatAdministratorsConfig{
 		ChatConfig: tgbotapi.ChatConfig{
-			ChatID: channelID,
+			ChatID: platform.ChannelID,
 		},
 	}
 
@@ -416,12 +400,17 @@ func NotifyAdminsAboutNewUser(bot *tgbotapi.BotAPI, user *tgbotapi.User, isPremi
 		return
 	}
 
-	messageText := fmt.Sprintf("Новый пользователь: %s\nID: %d\nРегион: %s\nPremium: %t",
-		user.UserName, user.ID, user.LanguageCode, isPremium)
+	// Администраторам уведомления приходят на языке по умолчанию
+	messageText := i18n.For(i18n.Default).T("admin.new_user", i18n.Args{
+		"name":    user.UserName,
+		"id":      user.ID,
+		"region":  user.LanguageCode,
+		"premium": isPremium,
+	})
 
 	for _, admin := range admins {
 		msg := tgbotapi.NewMessage(admin.User.ID, messageText)
-		bot.Send(msg)
+		sender.Send(bot, msg)
 
 	}
 }
@@ -3,183 +3,178 @@ package functionality
 import (
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 
-	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 )
 
-var DecimalPlaces = 4
+// Наценка платформы, общая для всех ботов
+func GetPricePercent() float64 {
+	return config.Current().PricePercent
+}
 
-func ConvertAmount(amount float64, rate float64, toRUB bool) float64 {
-	if toRUB {
-		return amount * rate
-	} else {
-		return amount / rate
+// Наценка владельца бота, добавляемая поверх базовой стоимости
+func GetBotMarkup(db *gorm.DB, botID int64) float64 {
+	botOwner, err := database.GetBotByID(db, botID)
+	if err != nil {
+		log.Printf("Error getting bot %d: %v", botID, err)
+		return 0
 	}
+	return botOwner.Markup
+}
+
+// Базовая стоимость — ставка поставщика с наценкой платформы, цена — базовая стоимость с наценкой владельца
+func CalculateOrderCost(rate float64, quantity int, ownerMarkup float64) (price, baseCost float64) {
+	baseCost = (float64(quantity) / 1000.0) * rate
+	baseCost += baseCost * (GetPricePercent() / 100.0)
+	price = baseCost + baseCost*(ownerMarkup/100.0)
+	return price, baseCost
 }
 
-func TranslateOrderStatus(status string) string {
+func TranslateOrderStatus(tr i18n.Localizer, status string) string {
 	switch status {
-	case "PENDING":
-		return "Ожидание"
-	case "COMPLETED":
-		return "Выполнен"
-	case "IN_PROGRESS":
-		return "В процессе"
-	case "PARTIAL":
-		return "Частично выполнен"
-	case "CANCELED":
-		return "Отменен"
+	case "PENDING", "COMPLETED", "IN_PROGRESS", "PARTIAL", "CANCELED":
+		return tr.T("order.status." + status)
 	default:
-		return "Неизвестный статус"
+		return tr.T("order.status.unknown")
 	}
 }
 
-func HandleBalanceCommand(bot *tgbotapi.BotAPI, userID int64, db *gorm.DB) {
+func HandleBalanceCommand(bot telegram.Client, userID int64, db *gorm.DB, botID int64) {
 	var userState models.UserState
-	if err := db.Where("user_id = ?", userID).First(&userState).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&userState).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
 		return
 	}
 
-	rate, err := api.GetCurrencyRate()
-	if err != nil {
-		log.Printf("Error getting currency rate: %v", err)
-		return
-	}
-
-	balance := userState.Balance
-	var balanceMsgText string
-
-	if userState.Currency == "RUB" {
-		balance = ConvertAmount(balance, rate, true)
-		balanceMsgText = fmt.Sprintf("💳 Ваш баланс: ₽%.*f", DecimalPlaces, balance)
-	} else {
-		balanceMsgText = fmt.Sprintf("💳 Ваш баланс: $%.*f", DecimalPlaces, balance)
-	}
-
-	msg := tgbotapi.NewMessage(userID, balanceMsgText)
+	tr := i18n.For(userLanguage(userState))
+	balance := currency.FormatUSD(userState.Balance, currencyOf(userState))
+	msg := tgbotapi.NewMessage(userID, tr.T("balance.text", i18n.Args{"balance": balance}))
+	msg.ReplyMarkup = TopUpKeyboard(tr)
+	sender.Send(bot, msg)
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+// Кнопки пополнения баланса и ввода промокода
+func TopUpKeyboard(tr i18n.Localizer) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⚡️Пополнить баланс", "replenishBalance"),
+			ActionButton(tr.T("balance.top_up"), Replenish{}),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🎁Промокод", "promo"),
+			ActionButton(tr.T("balance.promo"), EnterPromo{}),
 		),
 	)
-	msg.ReplyMarkup = keyboard
-
-	bot.Send(msg)
 }
 
-func HandleProfileCommand(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
+func HandleProfileCommand(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
 	var userState models.UserState
-	if err := db.Where("user_id = ?", chatID).First(&userState).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&userState).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
 		return
 	}
-	rate, err := api.GetCurrencyRate()
-	if err != nil {
-		log.Printf("Error getting currency rate: %v", err)
-		return
-	}
-	balance := userState.Balance
-	var messageText string
-	if userState.Currency == "RUB" {
-		balance = ConvertAmount(balance, rate, true)
-		messageText = fmt.Sprintf("🤵‍♂️ Пользователь:%v\n 🔎 ID:%v\n 💳 Ваш баланс:₽%.*f", userState.UserName, userState.UserID, DecimalPlaces, balance)
-	} else {
-		messageText = fmt.Sprintf("🤵‍♂️ Пользователь:%v\n 🔎 ID:%v\n 💳 Ваш баланс:$%.*f", userState.UserName, userState.UserID, DecimalPlaces, balance)
-	}
+	tr := i18n.For(userLanguage(userState))
+	messageText := tr.T("profile.text", i18n.Args{
+		"name":    userState.UserName,
+		"id":      userState.UserID,
+		"balance": currency.FormatUSD(userState.Balance, currencyOf(userState)),
+	})
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📝Мои заказы", "allorders"),
-			tgbotapi.NewInlineKeyboardButtonData("⚙️Настройки", "settings"),
+			ActionButton(tr.T("profile.orders"), ShowOrders{}),
+			ActionButton(tr.T("profile.settings"), ShowSettings{}),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⛑ Помощь", "techsup"),
+			ActionButton(tr.T("profile.help"), ShowSupport{}),
 		),
 	)
 	msg := tgbotapi.NewMessage(chatID, messageText)
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func HandleOrdersCommand(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
+func HandleOrdersCommand(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
 	var userOrders []models.UserOrders
 	chatIDString := strconv.FormatInt(chatID, 10)
-	result := db.Where("user_id = ?", chatIDString).Find(&userOrders)
+	result := db.Where("bot_id = ? AND user_id = ?", botID, chatIDString).Find(&userOrders)
 
 	if result.Error != nil {
 		log.Printf("Ошибка при получении заказов пользователя: %v", result.Error)
-		bot.Send(tgbotapi.NewMessage(chatID, "Произошла ошибка при получении информации о ваших заказах."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("orders.error")))
 		return
 	}
 
 	if len(userOrders) == 0 {
-		bot.Send(tgbotapi.NewMessage(chatID, "Вы еще не совершали покупок."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("orders.empty")))
 		return
 	}
 
-	messageText := "📝 Ваши заказы:\n\n"
+	messageText := tr.T("orders.title") + "\n\n"
 	for _, order := range userOrders {
-		status := TranslateOrderStatus(order.Status)
-		messageText += fmt.Sprintf("Номер услуги: %s\nСсылка: %s\nКоличество: %d\nСтатус: %s\n\n",
-			order.ServiceID, order.Link, order.Quantity, status)
+		messageText += tr.T("orders.item", i18n.Args{
+			"service":  order.ServiceID,
+			"link":     order.Link,
+			"quantity": order.Quantity,
+			"status":   TranslateOrderStatus(tr, order.Status),
+		}) + "\n\n"
 	}
 
 	msg := tgbotapi.NewMessage(chatID, messageText)
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func GiveSubscriptionBonus(bot *tgbotapi.BotAPI, db *gorm.DB, userState *models.UserState) {
-	rate, _ := api.GetCurrencyRate()
-	bonusAmount := 25.00 / rate
+func GiveSubscriptionBonus(bot telegram.Client, db *gorm.DB, userState *models.UserState) {
+	bonusRUB := config.Current().SubscriptionBonusRUB
+	bonusAmount, err := currency.ToUSD(bonusRUB, "RUB")
+	if err != nil {
+		log.Printf("Error converting subscription bonus: %v", err)
+		return
+	}
+	if err := database.AddUserBalance(db, userState.BotID, userState.UserID, bonusAmount); err != nil {
+		log.Printf("Error crediting subscription bonus to user %d: %v", userState.UserID, err)
+		return
+	}
 	userState.Balance += bonusAmount
-	bonusGiven++
-	message := ("🎁 Поздравляем, Вы получили бонус за подписку!\n\n🌟 Ваш баланс пополнен на 25р")
-	bot.Send(tgbotapi.NewMessage(userState.UserID, message))
+	message := i18n.For(userLanguage(*userState)).T("subscription.bonus", i18n.Args{"amount": bonusRUB})
+	sender.Send(bot, tgbotapi.NewMessage(userState.UserID, message))
 	userState.IsNewUser = false
 }
 
-func HandleFavoritesCommand(bot *tgbotapi.BotAPI, db *gorm.DB, chatID int64) {
-	favorites, err := database.GetUserFavorites(db, chatID)
+func HandleFavoritesCommand(bot telegram.Client, db *gorm.DB, botID, chatID int64) {
+	tr := Tr(db, botID, chatID)
+	favorites, err := database.GetUserFavorites(db, botID, chatID)
 	if err != nil || len(favorites) == 0 {
-		bot.Send(tgbotapi.NewMessage(chatID, "В избранном пока нет услуг."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("favorites.empty")))
 		return
 	}
 
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, service := range favorites {
-		button := tgbotapi.NewInlineKeyboardButtonData(service.Name, "serviceInfo:"+service.ServiceID)
+		button := ActionButton(service.Name, ServiceInfo{ServiceID: service.ServiceID})
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
 	}
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-	msg := tgbotapi.NewMessage(chatID, "Ваши избранные услуги:")
+	msg := tgbotapi.NewMessage(chatID, tr.T("favorites.title"))
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
 func GenerateReferralLink(chatID int64) string {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	botLink := os.Getenv("BOT_LINK")
-	return fmt.Sprintf(botLink+"?start=%d", chatID)
+	return fmt.Sprintf(platform.BotLink+"?start=%d", chatID)
 }
 
-func ShowReferralStats(bot *tgbotapi.BotAPI, db *gorm.DB, userID int64) {
+func ShowReferralStats(bot telegram.Client, db *gorm.DB, botID, userID int64) {
 	var referrals []models.Referral
-	db.Where("referrer_id = ?", userID).Find(&referrals)
+	db.Where("bot_id = ? AND referrer_id = ?", botID, userID).Find(&referrals)
 	count := len(referrals)
 
 	var totalEarned float64
@@ -187,25 +182,28 @@ func ShowReferralStats(bot *tgbotapi.BotAPI, db *gorm.DB, userID int64) {
 		totalEarned += referral.AmountEarned
 	}
 
-	msgText := fmt.Sprintf("🏂Приглашено человек: %d\n💸Заработано с ваших рефералов: $%.2f\n\n 🔘Приглашайте друзей и партнёров и получайте 10%% на баланс с каждой покупки. \n\n ✨Ваша партнёрская ссылка: %s", count, totalEarned, GenerateReferralLink(userID))
+	msgText := Tr(db, botID, userID).N("referral.stats", count, i18n.Args{
+		"earned": fmt.Sprintf("%.2f", totalEarned),
+		"link":   GenerateReferralLink(userID),
+	})
 
 	msg := tgbotapi.NewMessage(userID, msgText)
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func HandleChangeCurrency(bot *tgbotapi.BotAPI, userID int64, db *gorm.DB, toRUB bool) {
+func HandleChangeCurrency(bot telegram.Client, userID int64, db *gorm.DB, botID int64, code string) {
+	if _, ok := currency.Get(code); !ok {
+		log.Printf("Unsupported currency %q", code)
+		return
+	}
 	var user models.UserState
-	err := db.Where("user_id = ?", userID).First(&user).Error
+	err := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&user).Error
 	if err != nil {
 		log.Printf("Error getting user: %v", err)
 		return
 	}
 
-	if toRUB {
-		user.Currency = "RUB"
-	} else {
-		user.Currency = "USD"
-	}
+	user.Currency = code
 
 	err = db.Save(&user).Error
 	if err != nil {
@@ -213,41 +211,19 @@ func HandleChangeCurrency(bot *tgbotapi.BotAPI, userID int64, db *gorm.DB, toRUB
 		return
 	}
 
-	msgText := "Валюта изменена на "
-	if toRUB {
-		msgText += "рубли."
-	} else {
-		msgText += "доллары."
-	}
-	msg := tgbotapi.NewMessage(userID, msgText)
-	bot.Send(msg)
+	tr := i18n.For(userLanguage(user))
+	msg := tgbotapi.NewMessage(userID, tr.T("currency.changed."+user.Currency))
+	sender.Send(bot, msg)
 }
 
-func FormatServiceInfo(service models.Services, subcategory models.Subcategory, increasePercent float64, userCurrency string, currencyRate float64) string {
-	increasedRate := service.Rate + service.Rate*(increasePercent/100)
-
-	if userCurrency == "RUB" {
-		increasedRate = ConvertAmount(increasedRate, currencyRate, true)
-		currencySymbol := "₽"
-		return fmt.Sprintf(
-			"ℹ️ Информация об услуге\n\n"+
-				"🔢 ID услуги: %d\n"+
-				"📝 Услуга: %s\n\n"+
-				"📝 Категория: %s\n\n"+
-				"💸 Цена за 1000: %s%.*f\n\n"+
-				"📉 Минимальное количество: %d\n"+
-				"📈 Максимальное количество: %d",
-			service.ID, service.Name, subcategory.Name, currencySymbol, DecimalPlaces, increasedRate, service.Min, service.Max)
-	} else {
-		currencySymbol := "$"
-		return fmt.Sprintf(
-			"ℹ️ Информация об услуге\n\n"+
-				"🔢 ID услуги: %d\n"+
-				"📝 Услуга: %s\n\n"+
-				"📝 Категория: %s\n\n"+
-				"💸 Цена за 1000: %s%.*f\n\n"+
-				"📉 Минимальное количество: %d\n"+
-				"📈 Максимальное количество: %d",
-			service.ID, service.Name, subcategory.Name, currencySymbol, DecimalPlaces, increasedRate, service.Min, service.Max)
-	}
+func FormatServiceInfo(tr i18n.Localizer, service models.Services, subcategory models.Subcategory, ownerMarkup float64, userCurrency string) string {
+	increasedRate, _ := CalculateOrderCost(service.Rate, 1000, ownerMarkup)
+	return tr.T("service.info", i18n.Args{
+		"id":       service.ID,
+		"name":     service.Name,
+		"category": subcategory.Name,
+		"price":    currency.FormatUSD(increasedRate, currency.Normalize(userCurrency)),
+		"min":      service.Min,
+		"max":      service.Max,
+	})
 }
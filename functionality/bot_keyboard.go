@@ -1,16 +1,17 @@
 package functionality
 
 import (
-	"fmt"
 	"log"
-	"os"
 	"strconv"
 
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 
+	"github.com/Cekretik/BoostBot/currency"
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 )
 
@@ -33,116 +34,132 @@ func addEmojiToCategoryName(categoryName string) string {
 	}
 }
 
-func CreateQuickReplyMarkup() tgbotapi.ReplyKeyboardMarkup {
-	balanceButton := tgbotapi.NewKeyboardButton("💳 Баланс")
-	makeOrderButton := tgbotapi.NewKeyboardButton("✍️Сделать заказ")
-	makeReferralpButton := tgbotapi.NewKeyboardButton("🤝 Партнерам")
-	makeProfileButton := tgbotapi.NewKeyboardButton("🧩Профиль")
-	makeSiteButton := tgbotapi.NewKeyboardButton("⚡️Сайт (-55%)")
-	return tgbotapi.NewReplyKeyboard(
+func CreateQuickReplyMarkup(branding Branding) tgbotapi.ReplyKeyboardMarkup {
+	balanceButton := tgbotapi.NewKeyboardButton(branding.Menu.Balance)
+	makeOrderButton := tgbotapi.NewKeyboardButton(branding.Menu.Order)
+	makeReferralpButton := tgbotapi.NewKeyboardButton(branding.Menu.Referral)
+	makeProfileButton := tgbotapi.NewKeyboardButton(branding.Menu.Profile)
+	rows := [][]tgbotapi.KeyboardButton{
 		tgbotapi.NewKeyboardButtonRow(balanceButton, makeOrderButton),
 		tgbotapi.NewKeyboardButtonRow(makeReferralpButton, makeProfileButton),
-		tgbotapi.NewKeyboardButtonRow(makeSiteButton),
-	)
+	}
+	if branding.ShowSite {
+		makeSiteButton := tgbotapi.NewKeyboardButton(branding.Menu.Site)
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(makeSiteButton))
+	}
+	return tgbotapi.NewReplyKeyboard(rows...)
 }
 
-func SendKeyboardAfterOrder(bot *tgbotapi.BotAPI, chatID int64) {
-	messageText := "Заказ создан, ожидайте."
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	quickReplyMarkup := CreateQuickReplyMarkup()
+func SendKeyboardAfterOrder(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	msg := tgbotapi.NewMessage(chatID, tr.T("order.created_wait"))
+	quickReplyMarkup := CreateQuickReplyMarkup(GetBranding(db, botID, tr))
 	msg.ReplyMarkup = quickReplyMarkup
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
-func SendStandardKeyboard(bot *tgbotapi.BotAPI, chatID int64) {
-	messageText := "Отменено"
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	quickReplyMarkup := CreateQuickReplyMarkup()
+func SendStandardKeyboard(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	msg := tgbotapi.NewMessage(chatID, tr.T("common.cancelled"))
+	quickReplyMarkup := CreateQuickReplyMarkup(GetBranding(db, botID, tr))
 	msg.ReplyMarkup = quickReplyMarkup
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func SendStandardKeyboardAfterPayment(bot *tgbotapi.BotAPI, chatID int64) {
-	messageText := "После оплаты проверьте баланс."
-	msg := tgbotapi.NewMessage(chatID, messageText)
-	quickReplyMarkup := CreateQuickReplyMarkup()
+func SendStandardKeyboardAfterPayment(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	msg := tgbotapi.NewMessage(chatID, tr.T("payment.check_balance"))
+	quickReplyMarkup := CreateQuickReplyMarkup(GetBranding(db, botID, tr))
 	msg.ReplyMarkup = quickReplyMarkup
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
-func TechSupMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	channelLink := "https://t.me/DARRINAN00"
-	messageText := "Техническая поддержка: "
-	msg := tgbotapi.NewMessage(chatID, messageText)
+func TechSupMessage(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	channelLink := GetBranding(db, botID, tr).SupportLink
+	msg := tgbotapi.NewMessage(chatID, tr.T("support.text"))
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("Написать", channelLink),
+			tgbotapi.NewInlineKeyboardButtonURL(tr.T("support.button"), channelLink),
 		),
 	)
 	msg.ReplyMarkup = keyboard
 
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func SendSettingsKeyboard(bot *tgbotapi.BotAPI, chatID int64) {
-	messageText := "⚙️Сменить валюту на:"
-	msg := tgbotapi.NewMessage(chatID, messageText)
+func SendSettingsKeyboard(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	tr := Tr(db, botID, chatID)
+	msg := tgbotapi.NewMessage(chatID, tr.T("settings.currency")+"\n"+tr.T("settings.language"))
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("RUB", "changeCurrencyToRUB"),
-			tgbotapi.NewInlineKeyboardButtonData("USD", "changeCurrencyToUSD"),
-		),
-	)
+	var languageRow []tgbotapi.InlineKeyboardButton
+	for _, lang := range i18n.Languages() {
+		languageRow = append(languageRow, ActionButton(i18n.For(lang).T("language.name"), SetLanguage{Language: lang}))
+	}
+	// Валюты по три в ряд
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, code := range currency.Codes() {
+		if i%3 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], ActionButton(code, SetCurrency{Currency: code}))
+	}
+	muted, err := database.FavoriteAlertsMuted(db, botID, chatID)
+	if err != nil {
+		log.Printf("Error fetching favorite alerts setting of user %d: %v", chatID, err)
+	}
+	alertsButton := ActionButton(tr.T("settings.favorite_alerts.on"), SetFavoriteAlerts{Muted: true})
+	if muted {
+		alertsButton = ActionButton(tr.T("settings.favorite_alerts.off"), SetFavoriteAlerts{Muted: false})
+	}
+	rows = append(rows, languageRow, tgbotapi.NewInlineKeyboardRow(alertsButton))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
 
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func SendSubscriptionMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	channelLink := os.Getenv("CHANNEL_LINK")
-	messageText := "Чтобы пользоваться ботом, вам нужно подписаться на каналы. После подписки заново напишите /start"
-	msg := tgbotapi.NewMessage(chatID, messageText)
+func SendSubscriptionMessage(bot telegram.Client, chatID int64, tr i18n.Localizer, branding Branding) {
+	msg := tgbotapi.NewMessage(chatID, tr.T("subscription.required"))
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("Подписаться на канал", channelLink),
-		),
-	)
-	msg.ReplyMarkup = keyboard
+	if branding.ChannelLink != "" {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL(tr.T("subscription.button"), branding.ChannelLink),
+			),
+		)
+		msg.ReplyMarkup = keyboard
+	}
 
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func SendSiteMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	messageText := "⚡️На нашем сайте [StageSMM](https://stagesmm.com/) вы можете накрутить все, что есть в боте, в более удобном формате.\n\n☝️Главными плюсами сайта являются:\n\n🔸 Цены ПО ВСЕМ категориям на 55% дешевле цен бота \n🔸 ОГРОМНОЕ количество услуг\n🔸 Интуитивно понятный интерфейс\n🔸 Легкие пополнения с кучей способов оплат\n\n♦️И наконец промокод на пополнение `" + "STAGE10" + "` .Используя его вы сможете пополнять баланс на 10% больше оплаченного♦️"
-	msg := tgbotapi.NewMessage(chatID, messageText)
+func SendSiteMessage(bot telegram.Client, chatID int64, branding Branding) {
+	msg := tgbotapi.NewMessage(chatID, branding.SiteText)
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("⚡️StageSMM", "https://stagesmm.com/"),
+			tgbotapi.NewInlineKeyboardButtonURL(branding.Menu.Site, branding.SiteURL),
 		),
 	)
 	msg.ReplyMarkup = keyboard
-	msg.ParseMode = "Markdown"
+	msg.ParseMode = branding.SiteParseMode
 	msg.DisableWebPagePreview = true
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func SendPromotionMessage(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
+func SendPromotionMessage(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
 	var userState models.UserState
-	err := db.Where("user_id = ?", chatID).First(&userState).Error
+	err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&userState).Error
 	if err != nil {
 		log.Println("Error getting user state:", err)
 		return
 	}
-	greetingText := fmt.Sprintf("👋 Привет, %v! Я - StageSMM_Bot, ваш верный помощник для продвижения проектов и аккаунтов в социальных сетях. 🚀 Продвигай свои проекты с нашей помощью!", userState.UserName)
+	tr := i18n.For(userLanguage(userState))
+	branding := GetBranding(db, botID, tr)
+	greetingText := branding.GreetingFor(userState.UserName)
 	greetingMsg := tgbotapi.NewMessage(chatID, greetingText)
-	quickReplyMarkup := CreateQuickReplyMarkup()
+	quickReplyMarkup := CreateQuickReplyMarkup(branding)
 	greetingMsg.ReplyMarkup = quickReplyMarkup
-	if _, err := bot.Send(greetingMsg); err != nil {
+	if _, err := sender.Send(bot, greetingMsg); err != nil {
 		log.Println("Error sending greeting message:", err)
 		return
 	}
@@ -154,15 +171,15 @@ func SendPromotionMessage(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
 		return
 	}
 
-	categoryKeyboard, err := CreateCategoryKeyboard(db)
+	categoryKeyboard, err := CreateCategoryKeyboard(db, tr)
 	if err != nil {
 		log.Println("Error creating category keyboard:", err)
 		return
 	}
 
-	categoryMsg := tgbotapi.NewMessage(chatID, "✨ Выберите социальную сеть для продвижения:")
+	categoryMsg := tgbotapi.NewMessage(chatID, tr.T("catalog.choose_network"))
 	categoryMsg.ReplyMarkup = categoryKeyboard
-	if _, err := bot.Send(categoryMsg); err != nil {
+	if _, err := sender.Send(bot, categoryMsg); err != nil {
 		log.Println("Error sending category message:", err)
 		return
 	}
@@ -176,14 +193,14 @@ func SendPromotionMessage(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
 	for _, subcategory := range subcategories {
 		subcategoryMsg := tgbotapi.NewMessage(chatID, subcategory.Name)
 
-		subcategoryKeyboard, err := CreateSubcategoryKeyboard(db, subcategory.ID, currentPage, strconv.Itoa(totalPages))
+		subcategoryKeyboard, err := CreateSubcategoryKeyboard(db, tr, subcategory.ID, currentPage, strconv.Itoa(totalPages))
 		if err != nil {
 			log.Println("Error creating subcategory keyboard:", err)
 			continue
 		}
 
 		subcategoryMsg.ReplyMarkup = subcategoryKeyboard
-		if _, err := bot.Send(subcategoryMsg); err != nil {
+		if _, err := sender.Send(bot, subcategoryMsg); err != nil {
 			log.Println("Error sending subcategory message:", err)
 		}
 	}
@@ -204,20 +221,20 @@ func SendPromotionMessage(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
 	for _, service := range services {
 		serviceMsg := tgbotapi.NewMessage(chatID, service.Name)
 
-		serviceKeyboard, err := CreateServiceKeyboard(db, service.ServiceID, currentPage, strconv.Itoa(totalServicePages))
+		serviceKeyboard, err := CreateServiceKeyboard(db, tr, service.ServiceID, currentPage, strconv.Itoa(totalServicePages))
 		if err != nil {
 			log.Println("Error creating service keyboard:", err)
 			continue
 		}
 
 		serviceMsg.ReplyMarkup = serviceKeyboard
-		if _, err := bot.Send(serviceMsg); err != nil {
+		if _, err := sender.Send(bot, serviceMsg); err != nil {
 			log.Println("Error sending service message:", err)
 		}
 	}
 }
 
-func CreateCategoryKeyboard(db *gorm.DB) (tgbotapi.InlineKeyboardMarkup, error) {
+func CreateCategoryKeyboard(db *gorm.DB, tr i18n.Localizer) (tgbotapi.InlineKeyboardMarkup, error) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	categoryNames := []string{"Telegram", "YouTube", "Instagram", "TikTok", "Twitter"}
@@ -235,7 +252,7 @@ func CreateCategoryKeyboard(db *gorm.DB) (tgbotapi.InlineKeyboardMarkup, error)
 	for i, name := range categoryNames {
 		if category, ok := categoryMap[name]; ok {
 			categoryNameWithEmoji := addEmojiToCategoryName(category.Name)
-			categoryButton := tgbotapi.NewInlineKeyboardButtonData(categoryNameWithEmoji, fmt.Sprintf("category:%s", category.ID))
+			categoryButton := ActionButton(categoryNameWithEmoji, OpenCategory{CategoryID: category.ID})
 
 			if i == 0 || i%2 == 1 {
 				rows = append(rows, []tgbotapi.InlineKeyboardButton{categoryButton})
@@ -246,14 +263,15 @@ func CreateCategoryKeyboard(db *gorm.DB) (tgbotapi.InlineKeyboardMarkup, error)
 		}
 	}
 
-	// Добавляем кнопку "Избранное" отдельно внизу
-	favoriteButton := tgbotapi.NewInlineKeyboardButtonData("❤️\u200d🔥Избранное", "profile:favorites")
-	rows = append(rows, []tgbotapi.InlineKeyboardButton{favoriteButton})
+	// Добавляем кнопки "Поиск" и "Избранное" отдельно внизу
+	searchButton := ActionButton(tr.T("search.button"), Search{})
+	favoriteButton := ActionButton(tr.T("favorites.button"), ShowFavorites{})
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{searchButton, favoriteButton})
 
 	return tgbotapi.NewInlineKeyboardMarkup(rows...), nil
 }
 
-func CreateSubcategoryKeyboard(db *gorm.DB, categoryID, currentPage, totalPages string) (tgbotapi.InlineKeyboardMarkup, error) {
+func CreateSubcategoryKeyboard(db *gorm.DB, tr i18n.Localizer, categoryID, currentPage, totalPages string) (tgbotapi.InlineKeyboardMarkup, error) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	subcategories, err := database.GetSubcategoriesByCategoryID(db, categoryID)
@@ -265,7 +283,7 @@ func CreateSubcategoryKeyboard(db *gorm.DB, categoryID, currentPage, totalPages
 
 	for i := startIdx; i < endIdx; i++ {
 		subcategory := subcategories[i]
-		button := tgbotapi.NewInlineKeyboardButtonData(subcategory.Name, fmt.Sprintf("subcategory:%s", subcategory.ID))
+		button := ActionButton(subcategory.Name, OpenSubcategory{SubcategoryID: subcategory.ID})
 		row := []tgbotapi.InlineKeyboardButton{button}
 		rows = append(rows, row)
 	}
@@ -280,13 +298,13 @@ func CreateSubcategoryKeyboard(db *gorm.DB, categoryID, currentPage, totalPages
 		return tgbotapi.InlineKeyboardMarkup{}, err
 	}
 
-	paginationRow := createPaginationRow(categoryID, currentPageInt, totalPagesInt)
+	paginationRow := createPaginationRow(tr, categoryID, currentPageInt, totalPagesInt)
 	rows = append(rows, paginationRow)
 
 	return tgbotapi.NewInlineKeyboardMarkup(rows...), nil
 }
 
-func CreateServiceKeyboard(db *gorm.DB, subcategoryID, currentPage, totalServicePages string) (tgbotapi.InlineKeyboardMarkup, error) {
+func CreateServiceKeyboard(db *gorm.DB, tr i18n.Localizer, subcategoryID, currentPage, totalServicePages string) (tgbotapi.InlineKeyboardMarkup, error) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	services, err := database.GetServicesBySubcategoryID(db, subcategoryID)
@@ -299,7 +317,7 @@ func CreateServiceKeyboard(db *gorm.DB, subcategoryID, currentPage, totalService
 	for i := startIdx; i < endIdx; i++ {
 		service := services[i]
 
-		button := tgbotapi.NewInlineKeyboardButtonData(service.Name, fmt.Sprintf("serviceInfo:%s", service.ServiceID))
+		button := ActionButton(service.Name, ServiceInfo{ServiceID: service.ServiceID})
 		row := []tgbotapi.InlineKeyboardButton{button}
 		rows = append(rows, row)
 	}
@@ -313,9 +331,9 @@ func CreateServiceKeyboard(db *gorm.DB, subcategoryID, currentPage, totalService
 	if err != nil {
 		return tgbotapi.InlineKeyboardMarkup{}, err
 	}
-	backToSubcategoriesButton := tgbotapi.NewInlineKeyboardButtonData("🔙 Вернуться к категориям", fmt.Sprintf("backToSubcategories:%s", subcategoryID))
+	backToSubcategoriesButton := ActionButton(tr.T("catalog.back_to_categories"), BackToSubcategories{SubcategoryID: subcategoryID})
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{backToSubcategoriesButton})
-	paginationRow := createServicePaginationRow(subcategoryID, currentPageInt, totalServicePagesInt)
+	paginationRow := createServicePaginationRow(tr, subcategoryID, currentPageInt, totalServicePagesInt)
 	rows = append(rows, paginationRow)
 
 	return tgbotapi.NewInlineKeyboardMarkup(rows...), nil
@@ -0,0 +1,119 @@
+package functionality
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultSupportLink = "https://t.me/DARRINAN00"
+	DefaultSiteURL     = "https://stagesmm.com/"
+)
+
+type MenuLabels struct {
+	Balance  string
+	Order    string
+	Referral string
+	Profile  string
+	Site     string
+}
+
+// Текст - одна из кнопок главного меню
+func (m MenuLabels) Has(text string) bool {
+	switch text {
+	case m.Balance, m.Order, m.Referral, m.Profile, m.Site:
+		return true
+	}
+	return false
+}
+
+func DefaultMenuLabels(tr i18n.Localizer) MenuLabels {
+	return MenuLabels{
+		Balance:  tr.T("menu.balance"),
+		Order:    tr.T("menu.order"),
+		Referral: tr.T("menu.referral"),
+		Profile:  tr.T("menu.profile"),
+		Site:     tr.T("menu.site"),
+	}
+}
+
+// Оформление клона с подставленными значениями по умолчанию
+type Branding struct {
+	Greeting    string
+	SupportLink string
+	SiteText    string
+	// Текст сайта по умолчанию размечен Markdown, текст владельца отправляется как есть
+	SiteParseMode string
+	SiteURL       string
+	ShowSite      bool
+	// 0 - подписка на канал не требуется
+	ChannelID   int64
+	ChannelLink string
+	Menu        MenuLabels
+}
+
+func defaultChannel() (int64, string) {
+	return platform.ChannelID, platform.ChannelLink
+}
+
+func orDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// Тексты по умолчанию берутся на языке tr, тексты владельца бота не переводятся
+func GetBranding(db *gorm.DB, botID int64, tr i18n.Localizer) Branding {
+	channelID, channelLink := defaultChannel()
+	defaultMenu := DefaultMenuLabels(tr)
+	branding := Branding{
+		Greeting:      tr.T("branding.greeting"),
+		SupportLink:   DefaultSupportLink,
+		SiteText:      tr.T("branding.site_text"),
+		SiteParseMode: "Markdown",
+		SiteURL:       DefaultSiteURL,
+		ShowSite:      true,
+		ChannelID:     channelID,
+		ChannelLink:   channelLink,
+		Menu:          defaultMenu,
+	}
+
+	settings, err := database.GetBotSettings(db, botID)
+	if err != nil {
+		log.Printf("Error getting settings of bot %d: %v", botID, err)
+		return branding
+	}
+
+	branding.Greeting = orDefault(settings.Greeting, branding.Greeting)
+	branding.SupportLink = orDefault(settings.SupportLink, branding.SupportLink)
+	if settings.SiteText != "" {
+		branding.SiteText = settings.SiteText
+		branding.SiteParseMode = ""
+	}
+	branding.SiteURL = orDefault(settings.SiteURL, branding.SiteURL)
+	branding.ShowSite = !settings.HideSite
+	if settings.ChannelDisabled {
+		branding.ChannelID = 0
+		branding.ChannelLink = ""
+	} else if settings.ChannelID != 0 {
+		branding.ChannelID = settings.ChannelID
+		branding.ChannelLink = settings.ChannelLink
+	}
+	branding.Menu = MenuLabels{
+		Balance:  orDefault(settings.MenuBalance, defaultMenu.Balance),
+		Order:    orDefault(settings.MenuOrder, defaultMenu.Order),
+		Referral: orDefault(settings.MenuReferral, defaultMenu.Referral),
+		Profile:  orDefault(settings.MenuProfile, defaultMenu.Profile),
+		Site:     orDefault(settings.MenuSite, defaultMenu.Site),
+	}
+	return branding
+}
+
+func (b Branding) GreetingFor(userName string) string {
+	return strings.ReplaceAll(b.Greeting, "{name}", userName)
+}
@@ -0,0 +1,154 @@
+package functionality
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+// Очередь уведомлений об избранном. Синхронизация каталога добавляет изменения
+// и не ждет отправки, которую задерживают лимиты Telegram
+type FavoriteNotifier struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	pending []models.CatalogChange
+	wake    chan struct{}
+}
+
+func NewFavoriteNotifier(db *gorm.DB) *FavoriteNotifier {
+	return &FavoriteNotifier{db: db, wake: make(chan struct{}, 1)}
+}
+
+func (n *FavoriteNotifier) Enqueue(changes []models.CatalogChange) {
+	if len(changes) == 0 {
+		return
+	}
+	n.mu.Lock()
+	n.pending = append(n.pending, changes...)
+	n.mu.Unlock()
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Рассылает изменения из очереди до отмены ctx
+func (n *FavoriteNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.wake:
+		}
+		n.mu.Lock()
+		changes := n.pending
+		n.pending = nil
+		n.mu.Unlock()
+		NotifyFavoriteChanges(ctx, n.db, changes)
+	}
+}
+
+// Сообщает пользователям об изменениях их избранных услуг: цена изменилась больше
+// FAVORITE_ALERT_THRESHOLD процентов, услуга снята с продажи или вернулась.
+// Рассылка прекращается при отмене ctx
+func NotifyFavoriteChanges(ctx context.Context, db *gorm.DB, changes []models.CatalogChange) {
+	markups := make(map[int64]float64)
+	for _, change := range changes {
+		if !favoriteAlertWorthy(change) {
+			continue
+		}
+		users, err := database.GetFavoriteFollowers(db, change.ServiceID)
+		if err != nil {
+			log.Printf("Error getting followers of service %d: %v", change.ServiceID, err)
+			continue
+		}
+		for _, user := range users {
+			if ctx.Err() != nil {
+				return
+			}
+			// Пишем только через запущенного бота
+			bot, ok := telegram.Bot(user.BotID)
+			if !ok {
+				continue
+			}
+			markup, ok := markups[user.BotID]
+			if !ok {
+				markup = GetBotMarkup(db, user.BotID)
+				markups[user.BotID] = markup
+			}
+			sender.Send(bot, favoriteAlertMessage(user, change, markup))
+		}
+	}
+}
+
+func favoriteAlertWorthy(change models.CatalogChange) bool {
+	switch change.Kind {
+	case "added", "removed":
+		return true
+	case "updated":
+		if !change.PriceChanged || change.OldRate <= 0 {
+			return false
+		}
+		percent := math.Abs(change.NewRate-change.OldRate) / change.OldRate * 100
+		return percent >= config.Current().FavoriteAlertThreshold
+	}
+	return false
+}
+
+func favoriteAlertMessage(user models.UserState, change models.CatalogChange, markup float64) tgbotapi.MessageConfig {
+	tr := i18n.For(userLanguage(user))
+	code := currencyOf(user)
+	oldPrice, _ := CalculateOrderCost(change.OldRate, 1000, markup)
+	newPrice, _ := CalculateOrderCost(change.NewRate, 1000, markup)
+	args := i18n.Args{
+		"name": change.Name,
+		"old":  currency.FormatUSD(oldPrice, code),
+		"new":  currency.FormatUSD(newPrice, code),
+	}
+
+	var key string
+	switch {
+	case change.Kind == "removed":
+		key = "favorites.alert.removed"
+	case change.Kind == "added":
+		key = "favorites.alert.returned"
+	case change.NewRate < change.OldRate:
+		key = "favorites.alert.price_down"
+	default:
+		key = "favorites.alert.price_up"
+	}
+	msg := tgbotapi.NewMessage(user.UserID, tr.T(key, args))
+	// Снятую с продажи услугу заказать нельзя
+	if change.Kind != "removed" {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				ActionButton(tr.T("service.order"), OrderService{ServiceID: change.ServiceID}),
+			),
+		)
+	}
+	return msg
+}
+
+// Включает или отключает уведомления об избранных услугах
+func HandleFavoriteAlerts(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, muted bool) {
+	if err := database.SetFavoriteAlertsMuted(db, botID, chatID, muted); err != nil {
+		log.Printf("Error saving favorite alerts setting of user %d: %v", chatID, err)
+		return
+	}
+	key := "favorites.alerts.unmuted"
+	if muted {
+		key = "favorites.alerts.muted"
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, Tr(db, botID, chatID).T(key)))
+}
@@ -0,0 +1,59 @@
+package functionality
+
+import (
+	"log"
+
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+// Язык пользователя бота. Пользователи, сохраненные до выбора языка, получают язык по умолчанию
+func UserLanguage(db *gorm.DB, botID, userID int64) string {
+	language, err := database.GetUserLanguage(db, botID, userID)
+	if err != nil {
+		log.Printf("Error getting language of user %d: %v", userID, err)
+	}
+	if language == "" {
+		return i18n.Default
+	}
+	return language
+}
+
+func userLanguage(user models.UserState) string {
+	if user.Language == "" {
+		return i18n.Default
+	}
+	return user.Language
+}
+
+// Тексты на языке пользователя
+func Tr(db *gorm.DB, botID, userID int64) i18n.Localizer {
+	return i18n.For(UserLanguage(db, botID, userID))
+}
+
+// Валюта пользователя. Пустая или неизвестная валюта заменяется валютой по умолчанию
+func currencyOf(user models.UserState) string {
+	return currency.Normalize(user.Currency)
+}
+
+func HandleChangeLanguage(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, language string) {
+	if language != i18n.Match(language) {
+		log.Printf("Unsupported language %q", language)
+		return
+	}
+	if err := database.SetUserLanguage(db, botID, chatID, language); err != nil {
+		log.Printf("Error saving language of user %d: %v", chatID, err)
+		return
+	}
+
+	tr := i18n.For(language)
+	msg := tgbotapi.NewMessage(chatID, tr.T("language.changed"))
+	msg.ReplyMarkup = CreateQuickReplyMarkup(GetBranding(db, botID, tr))
+	sender.Send(bot, msg)
+}
@@ -1,12 +1,13 @@
 package functionality
 
 import (
-	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
@@ -62,72 +63,63 @@ func calculatePageRange(totalItems, itemsPerPage int, currentPage string) (start
 	}
 	return startIndex, endIndex
 }
-func createPaginationRow(categoryID string, currentPage int, totalPages int) []tgbotapi.InlineKeyboardButton {
+func createPaginationRow(tr i18n.Localizer, categoryID string, currentPage int, totalPages int) []tgbotapi.InlineKeyboardButton {
 	var paginationRow []tgbotapi.InlineKeyboardButton
 	if currentPage > 1 {
-		prevButton := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("prevCat:%s:%d", categoryID, currentPage))
+		prevButton := ActionButton(tr.T("catalog.prev"), CategoryPage{CategoryID: categoryID, Page: currentPage - 1})
 		paginationRow = append(paginationRow, prevButton)
 	}
-	pageInfoButton := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Страница %d из %d", currentPage, totalPages), "page_info")
+	pageInfoButton := ActionButton(tr.T("catalog.page", i18n.Args{"page": currentPage, "total": totalPages}), Noop{})
 	paginationRow = append(paginationRow, pageInfoButton)
 	if currentPage < totalPages {
-		nextButton := tgbotapi.NewInlineKeyboardButtonData("➡️ Вперед", fmt.Sprintf("nextCat:%s:%d", categoryID, currentPage))
+		nextButton := ActionButton(tr.T("catalog.next"), CategoryPage{CategoryID: categoryID, Page: currentPage + 1})
 		paginationRow = append(paginationRow, nextButton)
 	}
 
 	return paginationRow
 }
 
-func createServicePaginationRow(subcategoryID string, currentPage int, totalServicePages int) []tgbotapi.InlineKeyboardButton {
+func createServicePaginationRow(tr i18n.Localizer, subcategoryID string, currentPage int, totalServicePages int) []tgbotapi.InlineKeyboardButton {
 	var paginationRow []tgbotapi.InlineKeyboardButton
 	if currentPage > 1 {
-		prevButton := tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("prevServ:%s:%d", subcategoryID, currentPage))
+		prevButton := ActionButton(tr.T("catalog.prev"), ServicePage{SubcategoryID: subcategoryID, Page: currentPage - 1})
 		paginationRow = append(paginationRow, prevButton)
 	}
-	pageInfoButton := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Страница %d из %d", currentPage, totalServicePages), "page_info")
+	pageInfoButton := ActionButton(tr.T("catalog.page", i18n.Args{"page": currentPage, "total": totalServicePages}), Noop{})
 	paginationRow = append(paginationRow, pageInfoButton)
 	if currentPage < totalServicePages {
-		nextButton := tgbotapi.NewInlineKeyboardButtonData("➡️ Вперед", fmt.Sprintf("nextServ:%s:%d", subcategoryID, currentPage))
+		nextButton := ActionButton(tr.T("catalog.next"), ServicePage{SubcategoryID: subcategoryID, Page: currentPage + 1})
 		paginationRow = append(paginationRow, nextButton)
 	}
 
 	return paginationRow
 }
 
-func HandleAddToFavoritesCallback(bot *tgbotapi.BotAPI, db *gorm.DB, callbackQuery *tgbotapi.CallbackQuery) {
-	parts := strings.Split(callbackQuery.Data, ":")
-	action := parts[0]
-	serviceIDStr := parts[1]
-
-	// Преобразование строки ID в int
-	serviceID, err := strconv.ParseInt(serviceIDStr, 10, 64)
-	if err != nil {
-		bot.Request(tgbotapi.NewCallback(callbackQuery.ID, "Ошибка: неверный идентификатор услуги"))
-		return
-	}
-
+func HandleAddToFavoritesCallback(bot telegram.Client, db *gorm.DB, botID int64, callbackQuery *tgbotapi.CallbackQuery, action Favorite) {
 	userID := callbackQuery.Message.Chat.ID
+	tr := Tr(db, botID, userID)
 
 	// Получение объекта услуги из базы данных
 	var service models.Services
-	if err := db.First(&service, serviceID).Error; err != nil {
-		bot.Request(tgbotapi.NewCallback(callbackQuery.ID, "Услуга не найдена"))
+	if err := db.First(&service, action.ServiceID).Error; err != nil {
+		sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, tr.T("service.not_found")))
 		return
 	}
 
+	var err error
 	var responseText string
-	if action == "addFavorite" {
-		err = database.AddServiceToFavorites(db, userID, service.ID)
-		responseText = "Услуга добавлена в избранное"
-	} else if action == "removeFavorite" {
-		err = database.RemoveServiceFromFavorites(db, userID, service.ID)
-		responseText = "Услуга удалена из избранного"
+	if action.Add {
+		err = database.AddServiceToFavorites(db, botID, userID, service.ID)
+		responseText = tr.T("favorites.added")
+	} else {
+		err = database.RemoveServiceFromFavorites(db, botID, userID, service.ID)
+		responseText = tr.T("favorites.removed")
 	}
 
 	if err != nil {
-		bot.Request(tgbotapi.NewCallback(callbackQuery.ID, "Ошибка при обновлении избранных услуг"))
+		sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, tr.T("favorites.error")))
 		return
 	}
 
-	bot.Request(tgbotapi.NewCallback(callbackQuery.ID, responseText))
+	sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, responseText))
 }
@@ -0,0 +1,112 @@
+package functionality
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/models"
+	"gorm.io/gorm"
+)
+
+// Ни один поставщик не принимает заказ с таким количеством
+var ErrNoProvider = errors.New("no provider accepts the order")
+
+// Поставщик, которому можно отправить заказ услуги каталога
+type orderRoute struct {
+	provider  api.Provider
+	serviceID string
+	rate      float64
+	min, max  int
+	priority  int
+}
+
+func (r orderRoute) accepts(quantity int) bool {
+	return quantity >= r.min && (r.max <= 0 || quantity <= r.max)
+}
+
+// Основной поставщик и доступные привязки услуги в порядке SMM_ROUTING
+func orderRoutes(db *gorm.DB, service models.Services) []orderRoute {
+	routes := []orderRoute{{
+		provider:  api.Default,
+		serviceID: strconv.Itoa(service.ID),
+		rate:      service.Rate,
+		min:       service.Min,
+		max:       service.Max,
+		priority:  config.Current().DefaultProviderPriority,
+	}}
+	links, err := database.GetServiceLinks(db, service.ID)
+	if err != nil {
+		log.Printf("Error getting links of service %d: %v", service.ID, err)
+	}
+	for _, link := range links {
+		provider, ok := api.Get(link.Provider)
+		if !ok || link.Unavailable || provider == api.Default {
+			continue
+		}
+		routes = append(routes, orderRoute{
+			provider:  provider,
+			serviceID: link.ProviderServiceID,
+			rate:      link.Rate,
+			min:       link.Min,
+			max:       link.Max,
+			priority:  link.Priority,
+		})
+	}
+
+	if config.Current().RoutingPolicy == "preferred" {
+		sort.SliceStable(routes, func(i, j int) bool { return routes[i].priority < routes[j].priority })
+	} else {
+		sort.SliceStable(routes, func(i, j int) bool { return routes[i].rate < routes[j].rate })
+	}
+	return routes
+}
+
+// Количество, которое принимает хотя бы один поставщик услуги. Лимиты каждого
+// поставщика проверяет PlaceOrder
+func QuantityAccepted(db *gorm.DB, service models.Services, quantity int) bool {
+	for _, route := range orderRoutes(db, service) {
+		if route.accepts(quantity) {
+			return true
+		}
+	}
+	return false
+}
+
+// Самый широкий диапазон количества по всем поставщикам услуги
+func QuantityLimits(db *gorm.DB, service models.Services) (min, max int) {
+	min, max = service.Min, service.Max
+	for _, route := range orderRoutes(db, service) {
+		if route.min < min {
+			min = route.min
+		}
+		if route.max > max {
+			max = route.max
+		}
+	}
+	return min, max
+}
+
+// Отправляет заказ первому подходящему поставщику. Поставщики с другими лимитами
+// пропускаются, при ошибке заказ уходит следующему
+func PlaceOrder(db *gorm.DB, service models.Services, order models.Order) (provider string, orderID int, err error) {
+	err = ErrNoProvider
+	for _, route := range orderRoutes(db, service) {
+		if !route.accepts(order.Quantity) {
+			continue
+		}
+		order.ServiceID = route.serviceID
+		orderID, addErr := route.provider.AddOrder(order)
+		if addErr != nil {
+			log.Printf("Error placing order for service %d at %s: %v", service.ID, route.provider.Name(), addErr)
+			err = addErr
+			continue
+		}
+		return route.provider.Name(), orderID, nil
+	}
+	return "", 0, err
+}
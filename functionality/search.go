@@ -0,0 +1,436 @@
+package functionality
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+const (
+	// Каждое сообщение в этом состоянии - новый поисковый запрос, пока пользователь не нажмет "Отмена"
+	StateSearchAwaitingQuery fsm.State = "search:awaitingQuery"
+	// Результаты /search с текстом: следующий текст уточняет поиск, кнопки меню работают как обычно
+	StateSearchResults fsm.State = "search:results"
+)
+
+// Сколько найденных услуг запоминается для страниц. Остальные не показываются, запрос стоит уточнить
+const searchResultLimit = 200
+
+// Данные поиска: запрос, сообщение с его результатами и номера найденных услуг по порядку.
+// Кнопки страниц несут только номер страницы, услуги страницы берутся отсюда без повторного
+// поиска. Результаты следующего запроса заменяют сообщение
+type SearchConversation struct {
+	Query     string `json:"query,omitempty"`
+	MessageID int    `json:"message_id,omitempty"`
+	Results   []int  `json:"results,omitempty"`
+}
+
+// Разобранный запрос: слова для поиска по названию и фильтры
+type SearchQuery struct {
+	Terms []string
+	// Соцсеть в нормализованном виде, например "instagram"
+	Platform string
+	// Цена за 1000 в валюте пользователя, 0 - без ограничения
+	MaxPrice float64
+	Refill   bool
+	Cancel   bool
+}
+
+func (q SearchQuery) empty() bool {
+	return len(q.Terms) == 0 && q.Platform == "" && q.MaxPrice == 0 && !q.Refill && !q.Cancel
+}
+
+// Синонимы слов в названиях услуг. Первое слово группы - общая форма
+var searchSynonyms = [][]string{
+	{"followers", "follower", "подписчики", "подписчик", "подписки", "подписка", "фолловеры", "фоловеры", "subscribers", "subscriber", "subs", "sub", "members", "member", "участники"},
+	{"likes", "like", "лайки", "лайк", "сердечки", "hearts"},
+	{"views", "view", "просмотры", "просмотр", "plays", "прослушивания"},
+	{"comments", "comment", "комментарии", "комментарий", "комменты", "коммент"},
+	{"reactions", "reaction", "реакции", "реакция"},
+	{"reposts", "repost", "репосты", "репост", "shares", "share", "retweets", "ретвиты"},
+	{"votes", "vote", "голоса", "голосования", "poll", "опросы"},
+}
+
+// Названия соцсетей: в запросе они становятся фильтром по соцсети
+var searchPlatforms = [][]string{
+	{"telegram", "tg", "телеграм", "телеграмм", "тг", "телега"},
+	{"instagram", "ig", "insta", "inst", "инстаграм", "инстаграмм", "инста", "инст"},
+	{"youtube", "yt", "ютуб", "ютьюб"},
+	{"tiktok", "tt", "тикток"},
+	{"twitter", "твиттер"},
+	{"vk", "вк", "vkontakte", "вконтакте"},
+	{"facebook", "fb", "фейсбук"},
+	{"twitch", "твич"},
+}
+
+// Слова-фильтры докрутки и отмены
+var (
+	refillWords = []string{"refill", "докрутка", "докруткой", "рефилл"}
+	cancelWords = []string{"cancel", "отмена", "отменой"}
+)
+
+var (
+	synonymOf  = wordIndex(searchSynonyms)
+	platformOf = wordIndex(searchPlatforms)
+)
+
+func wordIndex(groups [][]string) map[string]string {
+	index := make(map[string]string)
+	for _, group := range groups {
+		for _, word := range group {
+			index[transliterate(word)] = transliterate(group[0])
+		}
+	}
+	return index
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "h", 'ц': "c", 'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "i", 'є': "e", 'ґ': "g",
+}
+
+// Переводит кириллицу в латиницу, чтобы "тикток" и "tiktok" совпадали
+func transliterate(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Ищет слово в словаре синонимов, отбрасывая до трех букв окончания: "подписчиков" -> "подписчик"
+func lookupSynonym(word string) (string, bool) {
+	for end := len(word); end >= len(word)-3 && end >= 4; end-- {
+		if canonical, ok := synonymOf[word[:end]]; ok {
+			return canonical, true
+		}
+	}
+	canonical, ok := synonymOf[word]
+	return canonical, ok
+}
+
+// Слова текста в общей форме: латиницей, синонимы заменены первым словом группы
+func searchWords(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	words := make([]string, 0, len(fields))
+	for _, field := range fields {
+		word := transliterate(field)
+		if canonical, ok := lookupSynonym(word); ok {
+			word = canonical
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+// Соцсеть по названию категории или слову запроса
+func normalizePlatform(name string) string {
+	words := searchWords(name)
+	if len(words) == 0 {
+		return ""
+	}
+	if platform, ok := platformOf[words[0]]; ok {
+		return platform
+	}
+	return words[0]
+}
+
+func isOneOf(word string, words []string) bool {
+	for _, w := range words {
+		if word == w {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePrice(s string) (float64, bool) {
+	price, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return price, err == nil && price > 0
+}
+
+// Разбирает запрос вида "подписчики инстаграм <100 refill": #соцсеть или ее название,
+// <цена или "до цена" за 1000, refill и cancel
+func ParseSearchQuery(text string) SearchQuery {
+	var query SearchQuery
+	var rest []string
+	fields := strings.Fields(strings.ToLower(text))
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case isOneOf(field, refillWords):
+			query.Refill = true
+		case isOneOf(field, cancelWords):
+			query.Cancel = true
+		case strings.HasPrefix(field, "#") && len(field) > 1:
+			query.Platform = normalizePlatform(field[1:])
+		case strings.HasPrefix(field, "<") || strings.HasPrefix(field, "≤"):
+			if price, ok := parsePrice(strings.TrimLeft(field, "<=≤")); ok {
+				query.MaxPrice = price
+			}
+		case (field == "до" || field == "under") && i+1 < len(fields):
+			if price, ok := parsePrice(fields[i+1]); ok {
+				query.MaxPrice = price
+				i++
+			} else {
+				rest = append(rest, field)
+			}
+		default:
+			rest = append(rest, field)
+		}
+	}
+	for _, word := range searchWords(strings.Join(rest, " ")) {
+		if platform, ok := platformOf[word]; ok {
+			query.Platform = platform
+			continue
+		}
+		query.Terms = append(query.Terms, word)
+	}
+	return query
+}
+
+type searchResult struct {
+	service models.Services
+	// Цена за 1000 в долларах с наценками
+	price float64
+	score int
+}
+
+// Релевантность названия: совпадение слова 3, начало слова 2, часть названия 1.
+// 0, если какое-то слово запроса не найдено
+func searchScore(terms []string, name string) int {
+	words := searchWords(name)
+	joined := strings.Join(words, " ")
+	score := 0
+	for _, term := range terms {
+		best := 0
+		for _, word := range words {
+			if word == term {
+				best = 3
+				break
+			}
+			if strings.HasPrefix(word, term) {
+				best = 2
+			}
+		}
+		if best == 0 && strings.Contains(joined, term) {
+			best = 1
+		}
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+	// Запрос целиком идет подряд
+	if len(terms) > 1 && strings.Contains(joined, strings.Join(terms, " ")) {
+		score += 2
+	}
+	return score
+}
+
+// Услуги по запросу: сначала самые релевантные, при равной релевантности - дешевые
+func searchServices(db *gorm.DB, botID int64, user models.UserState, query SearchQuery) ([]searchResult, error) {
+	services, err := database.GetSearchableServices(db, query.Refill, query.Cancel)
+	if err != nil {
+		return nil, err
+	}
+	maxPrice := 0.0
+	if query.MaxPrice > 0 {
+		if maxPrice, err = currency.ToUSD(query.MaxPrice, currencyOf(user)); err != nil {
+			return nil, err
+		}
+	}
+	markup := GetBotMarkup(db, botID)
+
+	seen := make(map[int]bool, len(services))
+	var results []searchResult
+	for _, service := range services {
+		if seen[service.ID] {
+			continue
+		}
+		seen[service.ID] = true
+		if query.Platform != "" && normalizePlatform(service.Platform) != query.Platform {
+			continue
+		}
+		price, _ := CalculateOrderCost(service.Rate, 1000, markup)
+		if maxPrice > 0 && price > maxPrice {
+			continue
+		}
+		score := 1
+		if len(query.Terms) > 0 {
+			if score = searchScore(query.Terms, service.Name); score == 0 {
+				continue
+			}
+		}
+		results = append(results, searchResult{service: service.Services, price: price, score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].price < results[j].price
+	})
+	return results, nil
+}
+
+// /search без текста и кнопка поиска: следующие сообщения пользователя - поисковые запросы
+func StartSearch(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	if err := fsm.Default.Set(botID, chatID, StateSearchAwaitingQuery, SearchConversation{}); err != nil {
+		log.Printf("Error saving search conversation: %v", err)
+	}
+	tr := Tr(db, botID, chatID)
+	msg := tgbotapi.NewMessage(chatID, tr.T("search.prompt"))
+	msg.ReplyMarkup = CancelKeyboard(tr)
+	sender.Send(bot, msg)
+}
+
+func HandleSearchCommand(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, text string) {
+	if strings.TrimSpace(text) == "" {
+		StartSearch(bot, chatID, db, botID)
+		return
+	}
+	if conv := sendSearchResults(bot, chatID, db, botID, text); conv.MessageID != 0 {
+		saveSearch(botID, chatID, StateSearchResults, conv)
+	}
+}
+
+func saveSearch(botID, chatID int64, state fsm.State, conv SearchConversation) {
+	if err := fsm.Default.Set(botID, chatID, state, conv); err != nil {
+		log.Printf("Error saving search conversation: %v", err)
+	}
+}
+
+// Очередной запрос в режиме поиска или после /search заменяет результаты предыдущего
+func HandleSearchInput(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, text string) {
+	state, conv, ok := fsm.Get[SearchConversation](fsm.Default, botID, chatID)
+	if !ok || state != StateSearchResults {
+		state = StateSearchAwaitingQuery
+	}
+	if conv.MessageID != 0 {
+		sender.Send(bot, tgbotapi.NewDeleteMessage(chatID, conv.MessageID))
+	}
+	saveSearch(botID, chatID, state, sendSearchResults(bot, chatID, db, botID, text))
+}
+
+// Переключение страницы результатов. false, если это не результаты последнего поиска
+func HandleSearchPage(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, page int) bool {
+	state, conv, ok := fsm.Get[SearchConversation](fsm.Default, botID, chatID)
+	if !ok || (state != StateSearchAwaitingQuery && state != StateSearchResults) || conv.MessageID != messageID {
+		return false
+	}
+	user, ok := searchUser(db, botID, chatID)
+	if !ok || len(conv.Results) == 0 {
+		return true
+	}
+	totalPages := (len(conv.Results) + ItemsPerPage - 1) / ItemsPerPage
+	page = clampPage(page, totalPages)
+	startIdx, endIdx := calculatePageRange(len(conv.Results), ItemsPerPage, strconv.Itoa(page))
+	services, err := database.GetServicesByIDs(db, conv.Results[startIdx:endIdx])
+	if err != nil {
+		log.Printf("Error getting search results: %v", err)
+		return true
+	}
+	markup := GetBotMarkup(db, botID)
+	items := make([]searchResult, 0, len(services))
+	for _, service := range services {
+		price, _ := CalculateOrderCost(service.Rate, 1000, markup)
+		items = append(items, searchResult{service: service, price: price})
+	}
+	keyboard := searchKeyboard(user, items, page, totalPages)
+	sender.Send(bot, tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
+	return true
+}
+
+// Отправляет первую страницу результатов и возвращает данные поиска для страниц.
+// MessageID = 0, если сообщение не отправлено
+func sendSearchResults(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, text string) SearchConversation {
+	conv := SearchConversation{Query: text}
+	user, ok := searchUser(db, botID, chatID)
+	if !ok {
+		return conv
+	}
+	tr := i18n.For(userLanguage(user))
+	query := ParseSearchQuery(text)
+	if query.empty() {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("search.prompt")))
+		return conv
+	}
+	results, err := searchServices(db, botID, user, query)
+	if err != nil {
+		log.Printf("Error searching services: %v", err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("search.error")))
+		return conv
+	}
+	for _, result := range results {
+		if len(conv.Results) == searchResultLimit {
+			break
+		}
+		conv.Results = append(conv.Results, result.service.ID)
+	}
+
+	var msg tgbotapi.MessageConfig
+	if len(results) == 0 {
+		msg = tgbotapi.NewMessage(chatID, tr.T("search.not_found", i18n.Args{"query": text}))
+	} else {
+		msg = tgbotapi.NewMessage(chatID, tr.N("search.found", len(results), i18n.Args{"query": text}))
+		totalPages := (len(conv.Results) + ItemsPerPage - 1) / ItemsPerPage
+		_, endIdx := calculatePageRange(len(results), ItemsPerPage, "1")
+		msg.ReplyMarkup = searchKeyboard(user, results[:endIdx], 1, totalPages)
+	}
+	sent, err := sender.Send(bot, msg)
+	if err != nil {
+		return conv
+	}
+	conv.MessageID = sent.MessageID
+	return conv
+}
+
+func searchUser(db *gorm.DB, botID, chatID int64) (models.UserState, bool) {
+	var user models.UserState
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
+		log.Printf("Error fetching user state: %v", err)
+		return user, false
+	}
+	return user, true
+}
+
+// Страница результатов: карточки услуг страницы с ценой за 1000 и переключатель страниц
+func searchKeyboard(user models.UserState, items []searchResult, page, totalPages int) tgbotapi.InlineKeyboardMarkup {
+	tr := i18n.For(userLanguage(user))
+	code := currencyOf(user)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, result := range items {
+		label := tr.T("search.item", i18n.Args{"name": result.service.Name, "price": currency.FormatUSD(result.price, code)})
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(ActionButton(label, ServiceInfo{ServiceID: result.service.ServiceID})))
+	}
+
+	var paginationRow []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		paginationRow = append(paginationRow, ActionButton(tr.T("catalog.prev"), SearchPage{Page: page - 1}))
+	}
+	paginationRow = append(paginationRow, ActionButton(tr.T("catalog.page", i18n.Args{"page": page, "total": totalPages}), Noop{}))
+	if page < totalPages {
+		paginationRow = append(paginationRow, ActionButton(tr.T("catalog.next"), SearchPage{Page: page + 1}))
+	}
+	rows = append(rows, paginationRow)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -1,230 +1,150 @@
 package functionality
 
 import (
-	"fmt"
 	"log"
-	"os"
 	"strconv"
-	"strings"
 
-	"github.com/Cekretik/BoostBot/api"
 	"github.com/Cekretik/BoostBot/database"
-	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
 
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
 
-func HandleCallbackQuery(bot *tgbotapi.BotAPI, db *gorm.DB, callbackQuery *tgbotapi.CallbackQuery, totalPages int) {
-	parts := strings.Split(callbackQuery.Data, ":")
-	action := parts[0]
-
-	if action == "category" {
-		categoryID := parts[1]
-		totalPages, err := GetTotalPagesForCategory(db, ItemsPerPage, categoryID)
-		if err != nil {
-			log.Println("Error calculating total pages:", err)
-			return
-		}
-
-		keyboard, err := CreateSubcategoryKeyboard(db, categoryID, "1", strconv.Itoa(totalPages))
-		if err != nil {
-			log.Println("Error creating subcategory keyboard:", err)
-			return
-		}
-
-		deleteMsg := tgbotapi.NewDeleteMessage(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID)
-		bot.Send(deleteMsg)
-
-		msg := tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, "Выберите категорию:")
-		msg.ReplyMarkup = keyboard
-		bot.Send(msg)
-
-	} else if action == "prevCat" || action == "nextCat" {
-		currentPage, _ := strconv.Atoi(parts[2])
-		if action == "prevCat" && currentPage > 1 {
-			currentPage--
-		} else if action == "nextCat" && currentPage < totalPages {
-			currentPage++
-		}
-		totalPages, err := GetTotalPagesForCategory(db, ItemsPerPage, parts[1])
-		if err != nil {
-			log.Println("Error recalculating total pages:", err)
-			return
-		}
-		keyboard, err := CreateSubcategoryKeyboard(db, parts[1], strconv.Itoa(currentPage), strconv.Itoa(totalPages))
-		if err != nil {
-			log.Println("Error updating subcategory keyboard:", err)
-			return
-		}
-		editMsg := tgbotapi.NewEditMessageReplyMarkup(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID, keyboard)
-		bot.Send(editMsg)
+func clampPage(page, totalPages int) int {
+	if page > totalPages {
+		page = totalPages
 	}
+	if page < 1 {
+		page = 1
+	}
+	return page
+}
+
+// Подкатегории социальной сети новым сообщением вместо сообщения с кнопкой
+func HandleOpenCategory(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, categoryID string) {
+	tr := Tr(db, botID, chatID)
+	totalPages, err := GetTotalPagesForCategory(db, ItemsPerPage, categoryID)
+	if err != nil {
+		log.Println("Error calculating total pages:", err)
+		return
+	}
+
+	keyboard, err := CreateSubcategoryKeyboard(db, tr, categoryID, "1", strconv.Itoa(totalPages))
+	if err != nil {
+		log.Println("Error creating subcategory keyboard:", err)
+		return
+	}
+
+	sender.Send(bot, tgbotapi.NewDeleteMessage(chatID, messageID))
+
+	msg := tgbotapi.NewMessage(chatID, tr.T("catalog.choose_category"))
+	msg.ReplyMarkup = keyboard
+	sender.Send(bot, msg)
+}
+
+func HandleCategoryPage(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, categoryID string, page int) {
+	tr := Tr(db, botID, chatID)
+	totalPages, err := GetTotalPagesForCategory(db, ItemsPerPage, categoryID)
+	if err != nil {
+		log.Println("Error recalculating total pages:", err)
+		return
+	}
+	page = clampPage(page, totalPages)
+	keyboard, err := CreateSubcategoryKeyboard(db, tr, categoryID, strconv.Itoa(page), strconv.Itoa(totalPages))
+	if err != nil {
+		log.Println("Error updating subcategory keyboard:", err)
+		return
+	}
+	sender.Send(bot, tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
+}
+
+// Первая страница услуг подкатегории новым сообщением
+func HandleOpenSubcategory(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, subcategoryID string) {
+	tr := Tr(db, botID, chatID)
+	totalServicePages, err := GetTotalPagesForService(db, ItemsPerPage, subcategoryID)
+	if err != nil {
+		log.Printf("Error calculating total pages for subcategory '%s': %v", subcategoryID, err)
+		return
+	}
+
+	keyboard, err := CreateServiceKeyboard(db, tr, subcategoryID, "1", strconv.Itoa(totalServicePages))
+	if err != nil {
+		log.Printf("Error creating service keyboard for subcategory '%s': %v", subcategoryID, err)
+		return
+	}
+
+	sender.Send(bot, tgbotapi.NewDeleteMessage(chatID, messageID))
+
+	msg := tgbotapi.NewMessage(chatID, tr.T("catalog.choose_service"))
+	msg.ReplyMarkup = keyboard
+	sender.Send(bot, msg)
+}
+
+func HandleServicePage(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, subcategoryID string, page int) {
+	tr := Tr(db, botID, chatID)
+	totalServicePages, err := GetTotalPagesForService(db, ItemsPerPage, subcategoryID)
+	if err != nil {
+		log.Printf("Error recalculating total pages for subcategory '%s': %v", subcategoryID, err)
+		return
+	}
+	page = clampPage(page, totalServicePages)
+
+	keyboard, err := CreateServiceKeyboard(db, tr, subcategoryID, strconv.Itoa(page), strconv.Itoa(totalServicePages))
+	if err != nil {
+		log.Printf("Error updating service keyboard for subcategory '%s', page %d: %v", subcategoryID, page, err)
+		return
+	}
+
+	sender.Send(bot, tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, keyboard))
+}
+
+func HandleServiceInfo(bot telegram.Client, db *gorm.DB, botID int64, chatID int64, messageID int, serviceID string) {
+	sender.Send(bot, tgbotapi.NewDeleteMessage(chatID, messageID))
+
+	service, err := database.GetServiceByID(db, serviceID)
+	if err != nil {
+		log.Printf("Error getting service '%s': %v", serviceID, err)
+		return
+	}
+
+	subcategory, err := database.GetSubcategoryByID(db, service.CategoryID)
+	if err != nil {
+		log.Printf("Error getting subcategory '%s': %v", service.CategoryID, err)
+		return
+	}
+
+	ownerMarkup := GetBotMarkup(db, botID)
+	userCurrency, err := database.GetUserCurrency(db, botID, chatID)
+	if err != nil {
+		log.Printf("Error getting user currency: %v", err)
+		return
+	}
+	tr := Tr(db, botID, chatID)
+	msgText := FormatServiceInfo(tr, service, subcategory, ownerMarkup, userCurrency)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			ActionButton(tr.T("service.back"), OpenSubcategory{SubcategoryID: service.CategoryID}),
+			ActionButton(tr.T("service.order"), OrderService{ServiceID: service.ID}),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			ActionButton(tr.T("service.favorite_remove"), Favorite{ServiceID: service.ID}),
+			ActionButton(tr.T("service.favorite_add"), Favorite{ServiceID: service.ID, Add: true}),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, msgText)
+	msg.ReplyMarkup = keyboard
+	sender.Send(bot, msg)
 }
 
-func HandleServiceCallBackQuery(bot *tgbotapi.BotAPI, db *gorm.DB, callbackQuery *tgbotapi.CallbackQuery, totalServicePages int) {
-	if strings.HasPrefix(callbackQuery.Data, "subcategory:") {
-		subcategoryID := strings.TrimPrefix(callbackQuery.Data, "subcategory:")
-
-		totalServicePages, err := GetTotalPagesForService(db, ItemsPerPage, subcategoryID)
-		if err != nil {
-			log.Printf("Error calculating total pages for subcategory '%s': %v", subcategoryID, err)
-			return
-		}
-
-		keyboard, err := CreateServiceKeyboard(db, subcategoryID, "1", strconv.Itoa(totalServicePages))
-		if err != nil {
-			log.Printf("Error creating service keyboard for subcategory '%s': %v", subcategoryID, err)
-			return
-		}
-
-		deleteMsg := tgbotapi.NewDeleteMessage(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID)
-		bot.Send(deleteMsg)
-
-		msg := tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, "Выберите услугу:")
-		msg.ReplyMarkup = keyboard
-		bot.Send(msg)
-
-	} else if strings.HasPrefix(callbackQuery.Data, "prevServ:") || strings.HasPrefix(callbackQuery.Data, "nextServ:") {
-		parts := strings.Split(callbackQuery.Data, ":")
-		action, subcategoryID, currentPageStr := parts[0], parts[1], parts[2]
-		currentPage, err := strconv.Atoi(currentPageStr)
-		if err != nil {
-			log.Printf("Error converting currentPage to integer: %v", err)
-			return
-		}
-
-		if action == "prevServ" && currentPage > 1 {
-			currentPage--
-		} else if action == "nextServ" && currentPage < totalServicePages {
-			currentPage++
-		}
-
-		totalServicePages, err := GetTotalPagesForService(db, ItemsPerPage, subcategoryID)
-		if err != nil {
-			log.Printf("Error recalculating total pages for subcategory '%s': %v", subcategoryID, err)
-			return
-		}
-
-		keyboard, err := CreateServiceKeyboard(db, subcategoryID, strconv.Itoa(currentPage), strconv.Itoa(totalServicePages))
-		if err != nil {
-			log.Printf("Error updating service keyboard for subcategory '%s', page %d: %v", subcategoryID, currentPage, err)
-			return
-		}
-
-		editMsg := tgbotapi.NewEditMessageReplyMarkup(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID, keyboard)
-		bot.Send(editMsg)
-
-	} else if strings.HasPrefix(callbackQuery.Data, "serviceInfo:") {
-		serviceIDStr := strings.TrimPrefix(callbackQuery.Data, "serviceInfo:")
-		var service models.Services
-		userID := callbackQuery.Message.Chat.ID
-		deleteMsg := tgbotapi.NewDeleteMessage(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID)
-		bot.Send(deleteMsg)
-
-		service, err := database.GetServiceByID(db, serviceIDStr)
-		if err != nil {
-			log.Printf("Error getting service '%s': %v", service.Name, err)
-			return
-		}
-
-		subcategory, err := database.GetSubcategoryByID(db, service.CategoryID)
-		if err != nil {
-			log.Printf("Error getting subcategory '%s': %v", subcategory.Name, err)
-			return
-		}
-
-		favoriteButtonText := "✅Добавить в избранное"
-		favoriteCallbackData := fmt.Sprintf("addFavorite:%d", service.ID)
-
-		removeFavoriteButtonText := "❌Удалить из избранного"
-		removeFavoriteCallbackData := fmt.Sprintf("removeFavorite:%d", service.ID)
-
-		increasePercent, err := strconv.ParseFloat(os.Getenv("PRICE_PERCENT"), 64)
-		if err != nil {
-			increasePercent = 0 // или установите значение по умолчанию
-		}
-		userCurrency, err := database.GetUserCurrency(db, userID)
-		if err != nil {
-			log.Printf("Error getting user currency: %v", err)
-			return
-		}
-		currencyRate := api.GetCurrentCurrencyRate()
-		msgText := FormatServiceInfo(service, subcategory, increasePercent, userCurrency, currencyRate)
-		backData := "backToServices:" + service.CategoryID
-		keyboard := tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("🔙Вернуться к услугам", backData),
-				tgbotapi.NewInlineKeyboardButtonData("➕Заказать", "order:"+strconv.Itoa(service.ID)),
-			),
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData(removeFavoriteButtonText, removeFavoriteCallbackData),
-				tgbotapi.NewInlineKeyboardButtonData(favoriteButtonText, favoriteCallbackData),
-			),
-		)
-
-		msg := tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, msgText)
-		msg.ReplyMarkup = keyboard
-		bot.Send(msg)
-
-	} else if strings.HasPrefix(callbackQuery.Data, "order:") {
-		serviceID := strings.TrimPrefix(callbackQuery.Data, "order:")
-		service, err := database.GetServiceByID(db, serviceID)
-		if err != nil {
-			log.Printf("Error getting service '%s': %v", serviceID, err)
-			bot.Send(tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, "Ошибка при получении данных сервиса."))
-			return
-		}
-		HandleOrderCommand(bot, callbackQuery.Message.Chat.ID, service)
-	} else if strings.HasPrefix(callbackQuery.Data, "backToServices:") {
-		subcategoryID := strings.TrimPrefix(callbackQuery.Data, "backToServices:")
-		deleteMsg := tgbotapi.NewDeleteMessage(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID)
-		bot.Send(deleteMsg)
-
-		totalServicePages, err := GetTotalPagesForService(db, ItemsPerPage, subcategoryID)
-		if err != nil {
-			log.Printf("Error getting total pages for services: %v", err)
-			return
-		}
-
-		keyboard, err := CreateServiceKeyboard(db, subcategoryID, "1", strconv.Itoa(totalServicePages))
-		if err != nil {
-			log.Printf("Error creating service keyboard for subcategory '%s': %v", subcategoryID, err)
-			return
-		}
-
-		msg := tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, "Выберите услугу:")
-		msg.ReplyMarkup = keyboard
-		bot.Send(msg)
-	} else if strings.HasPrefix(callbackQuery.Data, "backToSubcategories:") {
-		// Получение ID подкатегории из данных callback
-		subcategoryID := strings.TrimPrefix(callbackQuery.Data, "backToSubcategories:")
-
-		// Получение объекта подкатегории по ID
-		subcategory, err := database.GetSubcategoryByID(db, subcategoryID)
-		if err != nil {
-			log.Printf("Error getting subcategory '%s': %v", subcategoryID, err)
-			return
-		}
-
-		deleteMsg := tgbotapi.NewDeleteMessage(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID)
-		bot.Send(deleteMsg)
-
-		// Получение списка подкатегорий для категории, к которой принадлежит текущая подкатегория
-		totalPages, err := GetTotalPagesForCategory(db, ItemsPerPage, subcategory.CategoryID)
-		if err != nil {
-			log.Printf("Error calculating total pages for category '%s': %v", subcategory.CategoryID, err)
-			return
-		}
-
-		keyboard, err := CreateSubcategoryKeyboard(db, subcategory.CategoryID, "1", strconv.Itoa(totalPages))
-		if err != nil {
-			log.Printf("Error creating subcategory keyboard for category '%s': %v", subcategory.CategoryID, err)
-			return
-		}
-
-		msg := tgbotapi.NewMessage(callbackQuery.Message.Chat.ID, "Выберите категорию:")
-		msg.ReplyMarkup = keyboard
-		bot.Send(msg)
+// Возврат к подкатегориям социальной сети, к которой относится подкатегория
+func HandleBackToSubcategories(bot telegram.Client, db *gorm.DB, botID, chatID int64, messageID int, subcategoryID string) {
+	subcategory, err := database.GetSubcategoryByID(db, subcategoryID)
+	if err != nil {
+		log.Printf("Error getting subcategory '%s': %v", subcategoryID, err)
+		return
 	}
+	HandleOpenCategory(bot, db, botID, chatID, messageID, subcategory.CategoryID)
 }
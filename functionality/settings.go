@@ -0,0 +1,37 @@
+package functionality
+
+import (
+	"context"
+
+	"github.com/Cekretik/BoostBot/config"
+)
+
+// Настройки основного бота платформы: канал администраторов и ссылка на бота
+var platform config.Telegram
+
+func Configure(cfg config.Telegram) {
+	platform = cfg
+}
+
+// Запускает долгую фоновую задачу, например рассылку. main передает сюда
+// lifecycle.Manager.Go, чтобы остановка приложения дожидалась задач
+var startTask = func(name string, fn func(ctx context.Context)) {
+	go fn(context.Background())
+}
+
+func SetTaskRunner(run func(name string, fn func(ctx context.Context))) {
+	startTask = run
+}
+
+// Контекст, который отменяется вместе с a или b
+func withEither(a, b context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(a)
+	go func() {
+		select {
+		case <-b.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
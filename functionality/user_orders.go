@@ -1,212 +1,183 @@
 package functionality
 
 import (
-	"fmt"
+	"errors"
 	"log"
-	"os"
 	"strconv"
 	"strings"
 
-	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
 
-type UserStatus struct {
-	ChatID           int64
-	CurrentState     string
-	PendingServiceID string
-	Link             string
-	Quantity         int
-	ReplenishAmount  float64
-	OrderID          string
-}
+const (
+	StateOrderAwaitingLink     fsm.State = "order:awaitingLink"
+	StateOrderAwaitingQuantity fsm.State = "order:awaitingQuantity"
+)
 
-var UserStatuses map[int64]*UserStatus = make(map[int64]*UserStatus)
+// Данные оформления заказа
+type OrderConversation struct {
+	ServiceID int    `json:"service_id"`
+	Link      string `json:"link,omitempty"`
+	Quantity  int    `json:"quantity,omitempty"`
+}
 
-func GetUserStatus(chatID int64) *UserStatus {
-	if status, exists := UserStatuses[chatID]; exists {
-		return status
+func HandleOrderCommand(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, service models.Services) {
+	if err := fsm.Default.Set(botID, chatID, StateOrderAwaitingLink, OrderConversation{ServiceID: service.ID}); err != nil {
+		log.Printf("Error saving order conversation: %v", err)
 	}
-	UserStatuses[chatID] = &UserStatus{ChatID: chatID}
-	return UserStatuses[chatID]
-}
 
-func HandleOrderCommand(bot *tgbotapi.BotAPI, chatID int64, service models.Services) {
-	userStatus := GetUserStatus(chatID)
-	userStatus.CurrentState = "awaitingLink"
-	userStatus.PendingServiceID = strconv.Itoa(service.ID)
-
-	msgText := fmt.Sprintf("💬 Вы заказываете услугу: %s.\n\n ID усулги %d. \n\nДля оформления заказа укажите ссылку.", service.Name, service.ID)
-	cancelKeyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Отмена"),
-		),
-	)
+	tr := Tr(db, botID, chatID)
+	msgText := tr.T("order.start", i18n.Args{"name": service.Name, "id": service.ID})
 	msg := tgbotapi.NewMessage(chatID, msgText)
-	msg.ReplyMarkup = cancelKeyboard
-	bot.Send(msg)
+	msg.ReplyMarkup = CancelKeyboard(tr)
+	sender.Send(bot, msg)
 }
 
 func IsValidURL(url string) bool {
 	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
 }
 
-func HandleUserInput(db *gorm.DB, bot *tgbotapi.BotAPI, update tgbotapi.Update, service models.Services) {
+func HandleUserInput(db *gorm.DB, botID int64, bot telegram.Client, update tgbotapi.Update, service models.Services) {
 	chatID := update.Message.Chat.ID
-	userStatus := GetUserStatus(chatID)
+	state, conv, ok := fsm.Get[OrderConversation](fsm.Default, botID, chatID)
+	if !ok {
+		return
+	}
 
 	var user models.UserState
-	if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
 		return
 	}
-	userCurrency := user.Currency
-	currencyRate := api.GetCurrentCurrencyRate()
+	userCurrency := currencyOf(user)
+	tr := i18n.For(userLanguage(user))
 
-	switch userStatus.CurrentState {
-	case "awaitingLink":
+	switch state {
+	case StateOrderAwaitingLink:
 		link := update.Message.Text
 		if !IsValidURL(link) {
-			bot.Send(tgbotapi.NewMessage(chatID, "Введите ссылку корректно."))
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.bad_link")))
 			return
 		}
-		userStatus.Link = link
-		userStatus.CurrentState = "awaitingQuantity"
-		msgText := fmt.Sprintf("Введите количество. Минимальное: %d, максимальное: %d.", service.Min, service.Max)
+		conv.Link = link
+		fsm.Default.Set(botID, chatID, StateOrderAwaitingQuantity, conv)
+		min, max := QuantityLimits(db, service)
+		msgText := tr.T("order.enter_quantity", i18n.Args{"min": min, "max": max})
 		msg := tgbotapi.NewMessage(chatID, msgText)
-		bot.Send(msg)
+		sender.Send(bot, msg)
 
-	case "awaitingQuantity":
+	case StateOrderAwaitingQuantity:
 		quantity, err := strconv.Atoi(update.Message.Text)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, "Пожалуйста, введите действительное число."))
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.bad_number")))
 			return
-		} else if quantity < service.Min || quantity > service.Max {
-			msgText := fmt.Sprintf("Количество должно быть в диапазоне от %d до %d.", service.Min, service.Max)
-			bot.Send(tgbotapi.NewMessage(chatID, msgText))
+		} else if !QuantityAccepted(db, service, quantity) {
+			min, max := QuantityLimits(db, service)
+			msgText := tr.T("order.quantity_range", i18n.Args{"min": min, "max": max})
+			sender.Send(bot, tgbotapi.NewMessage(chatID, msgText))
 			return
 		}
-		userStatus.Quantity = quantity
-		increasePercent, err := strconv.ParseFloat(os.Getenv("PRICE_PERCENT"), 64)
-		if err != nil {
-			increasePercent = 0
-		}
-		cost := (float64(quantity) / 1000.0) * service.Rate
-		cost += cost * (increasePercent / 100.0)
+		conv.Quantity = quantity
+		fsm.Default.Set(botID, chatID, StateOrderAwaitingQuantity, conv)
+		cost, _ := CalculateOrderCost(service.Rate, quantity, GetBotMarkup(db, botID))
 		// Получение баланса пользователя
 		var user models.UserState
-		if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
-			bot.Send(tgbotapi.NewMessage(chatID, "Произошла ошибка при получении информации о вашем балансе."))
+		if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.balance_error")))
 			return
 		}
 
+		prices := i18n.Args{
+			"price":   currency.FormatUSD(cost, userCurrency),
+			"balance": currency.FormatUSD(user.Balance, userCurrency),
+		}
+
+		var msg tgbotapi.MessageConfig
 		if user.Balance >= cost {
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			msg = tgbotapi.NewMessage(chatID, tr.T("order.price", prices))
+			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("💰Купить", "buy"),
-				),
-			)
-			cancelKeyboard := tgbotapi.NewReplyKeyboard(
-				tgbotapi.NewKeyboardButtonRow(
-					tgbotapi.NewKeyboardButton("Отмена"),
+					ActionButton(tr.T("order.buy"), Buy{}),
 				),
 			)
-			var infoMsg string
-			if userCurrency == "RUB" {
-				cost = ConvertAmount(cost, currencyRate, true)
-				infoMsg = fmt.Sprintf("Цена услуги: ₽%.*f. Ваш баланс: ₽%.*f.", DecimalPlaces, cost, DecimalPlaces, ConvertAmount(user.Balance, currencyRate, true))
-			} else {
-				infoMsg = fmt.Sprintf("Цена услуги: $%.*f. Ваш баланс: $%.*f.", DecimalPlaces, cost, DecimalPlaces, user.Balance)
-			}
-			msg := tgbotapi.NewMessage(chatID, infoMsg)
-			msg.ReplyMarkup = cancelKeyboard
-			msg.ReplyMarkup = keyboard
-			bot.Send(msg)
 		} else {
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(
-				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("⚡️Пополнить баланс", "replenishBalance"),
-				),
-				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("🎁Промокод", "promo"),
-				),
-			)
-			cancelKeyboard := tgbotapi.NewReplyKeyboard(
-				tgbotapi.NewKeyboardButtonRow(
-					tgbotapi.NewKeyboardButton("Отмена"),
-				),
-			)
-			var infoMsg string
-			if userCurrency == "RUB" {
-				cost = ConvertAmount(cost, currencyRate, true)
-				infoMsg = fmt.Sprintf("На вашем балансе недостаточно средств. Цена услуги: ₽%.*f. Ваш баланс: ₽%.*f.", DecimalPlaces, cost, DecimalPlaces, ConvertAmount(user.Balance, currencyRate, true))
-			} else {
-				infoMsg = fmt.Sprintf("Цена услуги: $%.*f. Ваш баланс: $%.*f.", DecimalPlaces, cost, DecimalPlaces, user.Balance)
-			}
-			msg := tgbotapi.NewMessage(chatID, infoMsg)
-			msg.ReplyMarkup = cancelKeyboard
-			msg.ReplyMarkup = keyboard
-			bot.Send(msg)
+			msg = tgbotapi.NewMessage(chatID, tr.T("order.price_short", prices))
+			msg.ReplyMarkup = TopUpKeyboard(tr)
 		}
+		sender.Send(bot, msg)
 	}
 }
 
-func HandlePurchase(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, service models.Services) {
-	userStatus, exists := UserStatuses[chatID]
-	if !exists {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при оформлении заказа. Пожалуйста, попробуйте снова."))
+func HandlePurchase(db *gorm.DB, botID int64, bot telegram.Client, chatID int64, service models.Services) {
+	tr := Tr(db, botID, chatID)
+	_, conv, ok := fsm.Get[OrderConversation](fsm.Default, botID, chatID)
+	if !ok || conv.Quantity == 0 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.failed")))
 		return
 	}
-	userStatus.PendingServiceID = strconv.Itoa(service.ID)
 
 	var user models.UserState
-	if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Произошла ошибка при доступе к вашему балансу."))
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.balance_access")))
 		return
 	}
 
-	cost := (float64(userStatus.Quantity) / 1000.0) * service.Rate
-	if user.Balance < cost {
-		bot.Send(tgbotapi.NewMessage(chatID, "На вашем балансе недостаточно средств для оформления заказа."))
+	cost, baseCost := CalculateOrderCost(service.Rate, conv.Quantity, GetBotMarkup(db, botID))
+	if err := database.DebitUserBalance(db, botID, chatID, cost); err != nil {
+		if !errors.Is(err, database.ErrInsufficientBalance) {
+			log.Printf("Error debiting balance of user %d: %v", chatID, err)
+		}
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.insufficient")))
 		return
 	}
-	user.Balance -= cost
-	db.Save(&user)
 
 	order := models.Order{
-		ServiceID: userStatus.PendingServiceID,
-		Link:      userStatus.Link,
-		Quantity:  userStatus.Quantity,
+		Link:     conv.Link,
+		Quantity: conv.Quantity,
 	}
 
 	// Отправка заказа
-	createdOrder, err := api.CreateOrder(order, api.Token)
+	provider, orderID, err := PlaceOrder(db, service, order)
 	if err != nil {
-		user.Balance += cost
-		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка при создании заказа: %s", err.Error())))
+		if refundErr := database.AddUserBalance(db, botID, chatID, cost); refundErr != nil {
+			log.Printf("Error refunding %.2f to user %d of bot %d after failed order: %v", cost, chatID, botID, refundErr)
+		}
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.create_error", i18n.Args{"error": err})))
 		return
 	}
 
-	db.Model(&models.UserOrders{}).Create(map[string]interface{}{
-		"ChatID":     strconv.FormatInt(chatID, 10),
-		"ServiceID":  createdOrder.ServiceID,
-		"Cost":       createdOrder.Cost,
-		"OrderID":    createdOrder.OrderID,
-		"CreatedAt":  createdOrder.CreatedAt,
-		"UpdatedAt":  createdOrder.UpdatedAt,
-		"DeletedAt":  createdOrder.DeletedAt,
-		"Link":       createdOrder.Link,
-		"Quantity":   createdOrder.Quantity,
-		"Status":     createdOrder.Status,
-		"Charge":     createdOrder.Charge,
-		"StartCount": createdOrder.StartCount,
-		"Remains":    createdOrder.Remains,
+	// Заказ уже принят поставщиком: запись заказа и доход владельца сохраняются вместе
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.UserOrders{
+			BotID:       botID,
+			ChatID:      strconv.FormatInt(chatID, 10),
+			ServiceID:   strconv.Itoa(service.ID),
+			Cost:        cost,
+			OrderID:     orderID,
+			Provider:    provider,
+			Link:        order.Link,
+			Quantity:    order.Quantity,
+			Status:      "PENDING",
+			OwnerMargin: cost - baseCost,
+		}).Error; err != nil {
+			return err
+		}
+		return database.AddOwnerEarning(tx, botID, orderID, cost-baseCost, "order")
 	})
+	if err != nil {
+		log.Printf("Error saving order %d at %s for user %d of bot %d (charged %.2f): %v", orderID, provider, chatID, botID, cost, err)
+	}
 
 	// Отправка подтверждения пользователю
-	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Заказ успешно создан. ID услуги: %s", createdOrder.ServiceID)))
-	delete(UserStatuses, chatID)
-	SendKeyboardAfterOrder(bot, chatID)
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("order.created", i18n.Args{"service": service.ID})))
+	fsm.Default.Clear(botID, chatID)
+	SendKeyboardAfterOrder(bot, chatID, db, botID)
 }
@@ -3,19 +3,60 @@ package functionality
 import (
 	"errors"
 	"log"
+	"sync"
 
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
 
-var (
-	bonusActive bool  = false
-	bonusLimit  int64 = 1
-	bonusGiven  int64 = 0
-)
+// Бонус за подписку переключается командой /bonus и выдается из обработчиков разных чатов.
+// Число бонусов задает SUBSCRIPTION_BONUS_LIMIT
+type subscriptionBonus struct {
+	mu     sync.Mutex
+	active bool
+	given  int64
+}
+
+var bonus = &subscriptionBonus{}
+
+func (b *subscriptionBonus) toggle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = !b.active
+	if b.active {
+		b.given = 0
+	}
+	return b.active
+}
+
+// Резервирует бонус для одного пользователя
+func (b *subscriptionBonus) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.active || b.given >= config.Current().SubscriptionBonusLimit {
+		return false
+	}
+	b.given++
+	return true
+}
+
+func (b *subscriptionBonus) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.active && b.given < config.Current().SubscriptionBonusLimit
+}
+
+func CheckSubscriptionStatus(bot telegram.Client, db *gorm.DB, botID, channelID, userID int64, balance float64, userName, languageCode string) (bool, error) {
+	// Владелец бота отключил обязательную подписку
+	if channelID == 0 {
+		return true, UpdateUserStatus(bot, db, botID, channelID, userID, true, balance, userName, languageCode)
+	}
 
-func CheckSubscriptionStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID, userID int64, balance float64, userName string) (bool, error) {
 	chatMemberConfig := tgbotapi.GetChatMemberConfig{
 		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
 			ChatID: channelID,
@@ -30,7 +71,7 @@ func CheckSubscriptionStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID, userI
 
 	isSubscribed := chatMember.Status != "left"
 
-	if err := UpdateUserStatus(bot, db, channelID, userID, isSubscribed, balance, userName); err != nil {
+	if err := UpdateUserStatus(bot, db, botID, channelID, userID, isSubscribed, balance, userName, languageCode); err != nil {
 		log.Printf("Error updating subscription status in the database: %v", err)
 		return false, err
 	}
@@ -38,12 +79,14 @@ func CheckSubscriptionStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID, userI
 	return isSubscribed, nil
 }
 
-func UpdateUserStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID int64, userID int64, subscribed bool, balance float64, userName string) error {
+func UpdateUserStatus(bot telegram.Client, db *gorm.DB, botID, channelID int64, userID int64, subscribed bool, balance float64, userName, languageCode string) error {
 	var userState models.UserState
-	result := db.Where("user_id = ? AND channel_id = ?", userID, channelID).First(&userState)
+	// Канал может смениться в настройках бота, поэтому пользователь ищется без channel_id
+	result := db.Where("bot_id = ? AND user_id = ?", botID, userID).First(&userState)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			userState = models.UserState{
+				BotID:                botID,
 				UserID:               userID,
 				UserName:             userName,
 				ChannelID:            channelID,
@@ -51,7 +94,8 @@ func UpdateUserStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID int64, userID
 				IsNewUser:            true,
 				PreviouslySubscribed: subscribed,
 				Balance:              balance,
-				Currency:             "RUB",
+				Currency:             currency.Default,
+				Language:             i18n.Match(languageCode),
 			}
 			if err := db.Create(&userState).Error; err != nil {
 				log.Printf("Error creating new user state: %v", err)
@@ -62,7 +106,7 @@ func UpdateUserStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID int64, userID
 		log.Printf("Error finding user state: %v", result.Error)
 		return result.Error
 	}
-	if userState.IsNewUser && subscribed && bonusActive && bonusGiven < bonusLimit {
+	if userState.IsNewUser && subscribed && bonus.take() {
 		GiveSubscriptionBonus(bot, db, &userState)
 	}
 
@@ -72,10 +116,14 @@ func UpdateUserStatus(bot *tgbotapi.BotAPI, db *gorm.DB, channelID int64, userID
 		userState.PreviouslySubscribed = true
 	}
 	userState.UserName = userName
-	if !bonusActive || bonusGiven == bonusLimit {
+	userState.ChannelID = channelID
+	// Пользователь снова пишет боту, значит бот разблокирован
+	userState.Unreachable = false
+	if !bonus.available() {
 		userState.IsNewUser = false
 	}
-	if err := db.Save(&userState).Error; err != nil {
+	// Баланс не сохраняется целиком, его меняют только атомарные обновления
+	if err := db.Model(&userState).Select("subscribed", "previously_subscribed", "user_name", "channel_id", "is_new_user", "unreachable").Updates(&userState).Error; err != nil {
 		log.Printf("Error updating user subscription status: %v", err)
 		return err
 	}
@@ -0,0 +1,327 @@
+package i18n
+
+var en = &Catalog{
+	Lang: "en",
+	Rule: englishRule,
+	Messages: map[string]string{
+		"language.name":    "🇬🇧 English",
+		"language.changed": "Language changed to English.",
+
+		"common.cancel":    "Cancel",
+		"common.cancelled": "Cancelled",
+		"common.error":     "Something went wrong.",
+		"common.back":      "⬅️Back",
+
+		"access.denied":         "You do not have access.",
+		"access.denied_command": "You do not have access to this command.",
+		"callback.stale":        "This button is outdated, please open the menu again.",
+		"bot.overloaded":        "The bot is overloaded, please try again later.",
+
+		"menu.balance":  "💳 Balance",
+		"menu.order":    "✍️Place an order",
+		"menu.referral": "🤝 Partners",
+		"menu.profile":  "🧩Profile",
+		"menu.site":     "⚡️Website (-55%)",
+
+		"branding.greeting":  "👋 Hi, {name}! I am StageSMM_Bot, your trusty assistant for promoting projects and accounts on social media. 🚀 Grow your projects with our help!",
+		"branding.site_text": "⚡️On our website [StageSMM](https://stagesmm.com/) you can order everything the bot offers in a more convenient way.\n\n☝️The main advantages of the website:\n\n🔸 Prices in ALL categories are 55% lower than in the bot \n🔸 A HUGE number of services\n🔸 An intuitive interface\n🔸 Easy top-ups with plenty of payment methods\n\n♦️And finally, the top-up promo code `STAGE10` .With it your balance is topped up 10% more than you paid♦️",
+
+		"subscription.required": "To use the bot, please subscribe to the channels. After subscribing, send /start again",
+		"subscription.button":   "Subscribe to the channel",
+		"subscription.bonus":    "🎁 Congratulations, you have received a subscription bonus!\n\n🌟 Your balance has been topped up by {amount} RUB",
+
+		"balance.text":   "💳 Your balance: {balance}",
+		"balance.top_up": "⚡️Top up balance",
+		"balance.promo":  "🎁Promo code",
+
+		"profile.text":     "🤵‍♂️ User:{name}\n 🔎 ID:{id}\n 💳 Your balance:{balance}",
+		"profile.orders":   "📝My orders",
+		"profile.settings": "⚙️Settings",
+		"profile.help":     "⛑ Help",
+
+		"support.text":   "Support: ",
+		"support.button": "Contact",
+
+		"settings.currency":            "⚙️Switch currency to:",
+		"settings.language":            "🌐 Bot language:",
+		"settings.favorite_alerts.on":  "🔔 Favorites alerts: on",
+		"settings.favorite_alerts.off": "🔕 Favorites alerts: off",
+
+		"currency.changed.RUB":  "Currency changed to rubles.",
+		"currency.changed.USD":  "Currency changed to dollars.",
+		"currency.changed.EUR":  "Currency changed to euros.",
+		"currency.changed.UAH":  "Currency changed to hryvnias.",
+		"currency.changed.KZT":  "Currency changed to tenge.",
+		"currency.changed.USDT": "Currency changed to USDT.",
+
+		"orders.error": "Failed to load your orders.",
+		"orders.empty": "You have not made any purchases yet.",
+		"orders.title": "📝 Your orders:",
+		"orders.item":  "Service: {service}\nLink: {link}\nQuantity: {quantity}\nStatus: {status}",
+
+		"order.status.PENDING":     "Pending",
+		"order.status.COMPLETED":   "Completed",
+		"order.status.IN_PROGRESS": "In progress",
+		"order.status.PARTIAL":     "Partially completed",
+		"order.status.CANCELED":    "Canceled",
+		"order.status.unknown":     "Unknown status",
+
+		"favorites.button":           "❤️‍🔥Favorites",
+		"favorites.empty":            "There are no services in your favorites yet.",
+		"favorites.title":            "Your favorite services:",
+		"favorites.added":            "Service added to favorites",
+		"favorites.removed":          "Service removed from favorites",
+		"favorites.error":            "Failed to update favorites",
+		"favorites.alerts.muted":     "Favorites alerts are off",
+		"favorites.alerts.unmuted":   "Favorites alerts are on",
+		"favorites.alert.price_up":   "📈 The price of your favorite service \"{name}\" went up: {old} → {new} per 1000",
+		"favorites.alert.price_down": "📉 The price of your favorite service \"{name}\" went down: {old} → {new} per 1000",
+		"favorites.alert.removed":    "❌ Your favorite service \"{name}\" is no longer available. Last price: {old} per 1000",
+		"favorites.alert.returned":   "✅ Your favorite service \"{name}\" is available again. Price: {new} per 1000",
+
+		"search.button":    "🔍 Search",
+		"search.prompt":    "🔍 Type the service you are looking for, for example: instagram followers\n\nFilters:\n#network - a single social network\n<100 or \"under 100\" - max price per 1000\nrefill - with refill, cancel - with cancellation\n\nEvery message starts a new search. Press \"Cancel\" to finish.",
+		"search.not_found": "Nothing found for \"{query}\". Try other words or remove filters.",
+		"search.error":     "Failed to search services, please try again later.",
+		"search.item":      "{name} · {price}",
+
+		"catalog.choose_network":     "✨ Choose a social network to promote:",
+		"catalog.choose_category":    "Choose a category:",
+		"catalog.choose_service":     "Choose a service:",
+		"catalog.back_to_categories": "🔙 Back to categories",
+		"catalog.prev":               "⬅️ Back",
+		"catalog.next":               "➡️ Next",
+		"catalog.page":               "Page {page} of {total}",
+
+		"service.info":            "ℹ️ Service details\n\n🔢 Service ID: {id}\n📝 Service: {name}\n\n📝 Category: {category}\n\n💸 Price per 1000: {price}\n\n📉 Minimum quantity: {min}\n📈 Maximum quantity: {max}",
+		"service.not_found":       "Service not found",
+		"service.back":            "🔙Back to services",
+		"service.order":           "➕Order",
+		"service.favorite_add":    "✅Add to favorites",
+		"service.favorite_remove": "❌Remove from favorites",
+
+		"order.start":          "💬 You are ordering the service: {name}.\n\n Service ID {id}. \n\nTo place the order, send a link.",
+		"order.bad_link":       "Please enter a valid link.",
+		"order.enter_quantity": "Enter the quantity. Minimum: {min}, maximum: {max}.",
+		"order.bad_number":     "Please enter a valid number.",
+		"order.quantity_range": "The quantity must be between {min} and {max}.",
+		"order.balance_error":  "Failed to load your balance.",
+		"order.price":          "Service price: {price}. Your balance: {balance}.",
+		"order.price_short":    "Your balance is too low. Service price: {price}. Your balance: {balance}.",
+		"order.buy":            "💰Buy",
+		"order.failed":         "Failed to place the order. Please try again.",
+		"order.balance_access": "Failed to access your balance.",
+		"order.insufficient":   "Your balance is too low to place the order.",
+		"order.create_error":   "Failed to create the order: {error}",
+		"order.created":        "Order created successfully. Service ID: {service}",
+		"order.created_wait":   "Order created, please wait.",
+		"order.restart":        "Your request cannot be processed. Please start over.",
+		"order.no_service":     "Error: service ID is missing.",
+		"order.service_error":  "Failed to load the service.",
+
+		"promo.enter":        "✍️Enter your promo code:",
+		"promo.not_found":    "Promo code not found.",
+		"promo.exhausted":    "This promo code has already been used the maximum number of times.",
+		"promo.already_used": "You have already used this promo code.",
+		"promo.activated":    "🎁 Congratulations, you have activated a promo code!\n\n🌟 Your balance has been topped up by {amount} RUB",
+		"promo.applied":      "Promo code applied successfully.",
+
+		"link.not_found":    "Special link not found.",
+		"link.exhausted":    "This special link has already been used the maximum number of times.",
+		"link.already_used": "You have already followed this special link.",
+
+		"payment.choose_system":     "Choose a payment method",
+		"payment.sbp":               "SBP|RUB",
+		"payment.ru_card":           "RU Card|RUB",
+		"payment.other_crypto":      "Other crypto",
+		"payment.enter_amount.USD":  "Enter the amount in dollars.",
+		"payment.enter_amount.RUB":  "Enter the amount in rubles.",
+		"payment.enter_amount.EUR":  "Enter the amount in euros.",
+		"payment.enter_amount.UAH":  "Enter the amount in hryvnias.",
+		"payment.enter_amount.KZT":  "Enter the amount in tenge.",
+		"payment.enter_amount.USDT": "Enter the amount in USDT.",
+		"payment.bad_amount":        "Please enter a valid amount.",
+		"payment.create_error":      "Failed to create the payment.",
+		"payment.no_link":           "Failed to get the payment link, please try again.",
+		"payment.pay":               "Pay",
+		"payment.link":              "To top up {amount}, press the pay button:",
+		"payment.check_balance":     "Check your balance after paying.",
+		"payment.description":       "Balance top-up",
+
+		"admin.new_user":                 "New user: {name}\nID: {id}\nRegion: {region}\nPremium: {premium}",
+		"admin.createpromo.usage":        "Wrong format. Use: /createpromo [name] [discount] [maximum number of uses]",
+		"admin.createpromo.bad_discount": "Wrong discount format.",
+		"admin.createpromo.bad_limit":    "Wrong number of uses format.",
+		"admin.createpromo.exists":       "A promo code with this name already exists.",
+		"admin.createpromo.created":      "Promo code created: {code}",
+		"admin.createurl.usage":          "Wrong format. Use: /createurl [name] [amount] [number of clicks]",
+		"admin.createurl.bad_amount":     "Wrong amount format.",
+		"admin.createurl.bad_clicks":     "Wrong number of clicks format.",
+		"admin.createurl.exists":         "A link with this name has already been created.",
+		"admin.createurl.created":        "Link created: {link}",
+		"admin.bonus.on":                 "Subscription bonus enabled.",
+		"admin.bonus.off":                "Subscription bonus disabled.",
+		"admin.broadcast.empty":          "Please specify the message to broadcast.",
+		"admin.broadcast.format_error":   "Failed to format the message: {error}",
+		"admin.broadcast.started":        "Broadcast started.",
+
+		"manager.menu":          "Menu",
+		"manager.cancelled":     "Action cancelled.",
+		"manager.welcome":       "👋Welcome!",
+		"manager.greeting":      "👋Hi {name}! I am the manager bot that will help you create your own promotion bot",
+		"manager.bots_button":   "💼Bots",
+		"manager.bots":          "👋 Here you can add, configure and delete your bots! \n\nYour bots: {count} of {limit}.\n\nSet the service markup with /markup [bot name] [markup in %]",
+		"manager.create_bot":    "🆕Create a bot",
+		"manager.my_bots":       "📋My bots",
+		"manager.withdraw":      "💸Withdraw funds",
+		"manager.bot_not_found": "Bot not found among your bots.",
+		"manager.no_bots":       "You have no bots yet.",
+		"manager.list_title":    "📋 Your bots:",
+
+		"manager.token.prompt":      "❗️Reply to this message with the bot token",
+		"manager.token.prompt_bot":  "❗️Reply to this message with the new token of @{bot}",
+		"manager.token.limit":       "Bot limit exceeded. You can have at most {limit} bots.",
+		"manager.token.invalid":     "Invalid bot token. Please check it and try again.",
+		"manager.token.info_error":  "Failed to get bot information. Please check the token.",
+		"manager.token.duplicate":   "This bot has already been added.",
+		"manager.token.save_error":  "Failed to save the token.",
+		"manager.token.save_failed": "Failed to save the token: {error}",
+		"manager.token.added":       "Bot @{bot} has been added and started.",
+		"manager.token.wrong_bot":   "The token belongs to @{actual}, not @{bot}. Send the token of the right bot.",
+		"manager.token.replaced":    "The token of @{bot} has been updated, the bot is restarting.",
+		"manager.token.revoked":     "⚠️ The token of @{bot} has been revoked or the bot was deleted in BotFather. The bot has been stopped.\n\nSend a new token to resume.",
+		"manager.token.replace":     "🔑Replace token",
+
+		"manager.markup.usage":   "Wrong format. Use: /markup [bot name] [markup in %]",
+		"manager.markup.invalid": "The markup must be a number from 0 to 1000.",
+		"manager.markup.error":   "Failed to save the markup.",
+		"manager.markup.set":     "Markup of @{bot} set to {markup}%",
+
+		"manager.links.link_usage":                 "Wrong format. Use: /linkservice [service id] [provider] [provider service id] [priority]\nWith SMM_ROUTING=preferred the provider with the lower priority gets the order first. Links default to priority 1, the main provider has {default}.",
+		"manager.links.unlink_usage":               "Wrong format. Use: /unlinkservice [service id] [provider]",
+		"manager.links.list_usage":                 "Wrong format. Use: /servicelinks [service id]",
+		"manager.links.service_not_found":          "No service with this id in the catalog.",
+		"manager.links.unknown_provider":           "Unknown provider. Connected: {providers}",
+		"manager.links.provider_service_not_found": "The provider has no service with this id.",
+		"manager.links.error":                      "Failed to save the link.",
+		"manager.links.linked":                     "Service \"{service}\" is linked to \"{name}\" at {provider}.",
+		"manager.links.unlinked":                   "Link of service \"{service}\" to {provider} removed.",
+		"manager.links.not_linked":                 "The service is not linked to this provider.",
+		"manager.links.title":                      "Providers of \"{service}\":",
+		"manager.links.item":                       "{provider}: service {id}, ${rate} per 1000, {min}-{max}, priority {priority}",
+		"manager.links.item_unavailable":           "{provider}: service {id} is no longer offered",
+
+		"manager.catalog_changes.usage":   "Wrong format. Use: /catalogchanges [service id]",
+		"manager.catalog_changes.error":   "Failed to load catalog changes.",
+		"manager.catalog_changes.empty":   "No catalog changes yet.",
+		"manager.catalog_changes.title":   "Latest catalog changes:",
+		"manager.catalog_changes.added":   "{date} ➕ {id} \"{name}\": ${new_rate} per 1000, {new_min}-{new_max}",
+		"manager.catalog_changes.removed": "{date} ➖ {id} \"{name}\" is no longer offered",
+		"manager.catalog_changes.price":   "{date} 💲 {id} \"{name}\": ${old_rate} → ${new_rate} per 1000",
+		"manager.catalog_changes.limits":  "{date} 📏 {id} \"{name}\": {old_min}-{old_max} → {new_min}-{new_max}",
+
+		"manager.state.starting": "🟡 Starting",
+		"manager.state.running":  "🟢 Running",
+		"manager.state.backoff":  "🟠 Restarting after error: {error}",
+		"manager.state.failed":   "⛔️ Error: {error}",
+		"manager.state.revoked":  "⛔️ Token revoked",
+		"manager.state.stopped":  "🔴 Stopped",
+
+		"manager.card":               "🤖 @{bot}\n\nState: {state}\n👥 Users: {users}\n💰 Your earnings: ${earnings}\n💳 Balance: ${balance}\n📈 Markup: {markup}%",
+		"manager.card.start":         "▶️Start",
+		"manager.card.stop":          "⏹Stop",
+		"manager.card.token":         "🔑Change token",
+		"manager.card.stats":         "📊Statistics",
+		"manager.card.branding":      "🎨Appearance",
+		"manager.card.delete":        "🗑Delete",
+		"manager.bot.revoked":        "The token of @{bot} has been revoked. Replace the token first.",
+		"manager.bot.starting":       "Bot @{bot} is starting.",
+		"manager.bot.delete_confirm": "Delete @{bot}? The bot balance (${balance}) will be lost.",
+		"manager.bot.delete_yes":     "✅Yes, delete",
+		"manager.bot.delete_no":      "❌Cancel",
+		"manager.bot.delete_error":   "Failed to delete the bot.",
+		"manager.bot.deleted":        "Bot @{bot} deleted.",
+
+		"manager.branding.greeting":         "👋Greeting",
+		"manager.branding.greeting_prompt":  "Send the greeting text. {name} will be replaced with the user name.",
+		"manager.branding.support":          "🆘Support",
+		"manager.branding.support_prompt":   "Send the support link, for example https://t.me/username",
+		"manager.branding.site_url":         "🔗Website link",
+		"manager.branding.site_url_prompt":  "Send the link to your website.",
+		"manager.branding.site_text":        "📝Website text",
+		"manager.branding.site_text_prompt": "Send the text of the website message.",
+		"manager.branding.channel":          "📢Channel",
+		"manager.branding.channel_prompt":   "Send the channel ID and its link separated by a space, for example: -1001234567890 https://t.me/channel\nThe bot must be an administrator of the channel. Send «no» to disable the required subscription.",
+		"manager.branding.channel_off":      "no",
+		"manager.branding.menu":             "⌨️Menu buttons",
+		"manager.branding.menu_prompt":      "Send 5 lines with the button labels: balance, order, partners, profile, website.",
+		"manager.branding.card":             "🎨 Appearance of @{bot}\n\nGreeting: {greeting}\n\nSupport: {support}\nWebsite: {site}\nChannel: {channel}\nButtons: {menu}\n\nTo restore the default value, send «{reset}» when editing.",
+		"manager.branding.site_hidden":      "hidden",
+		"manager.branding.channel_none":     "not required",
+		"manager.branding.hide_site":        "🙈Hide website",
+		"manager.branding.show_site":        "👁Show website",
+		"manager.branding.saved":            "✅ Settings of @{bot} saved.",
+		"manager.branding.save_error":       "Failed to save the settings.",
+		"manager.branding.bad_link":         "Invalid link. The link must start with https://",
+		"manager.branding.channel_format":   "Send the channel ID and the link separated by a space.",
+		"manager.branding.bad_channel_id":   "Invalid channel ID.",
+		"manager.branding.bad_channel_link": "Invalid channel link.",
+		"manager.branding.menu_labels":      "Button labels must be different, non-empty, at most 32 characters long and must not start with /.",
+		"manager.branding.unknown":          "Unknown setting.",
+
+		"manager.stats.today":        "Today",
+		"manager.stats.7d":           "7 days",
+		"manager.stats.30d":          "30 days",
+		"manager.stats.all":          "All time",
+		"manager.stats.title":        "📊 Statistics of @{bot}: {period}",
+		"manager.stats.new_users":    "👤 New users: {count}",
+		"manager.stats.active_users": "🔥 Active users: {count}",
+		"manager.stats.deposits":     "💵 Top-ups:",
+		"manager.stats.orders":       "📦 Orders:",
+		"manager.stats.none":         "none",
+		"manager.stats.group":        "{key}: {count} for ${amount}",
+		"manager.stats.gross":        "💰 Turnover: ${amount}",
+		"manager.stats.margin":       "📈 Your earnings: ${amount}",
+		"manager.stats.top":          "🏆 Popular services:",
+		"manager.stats.csv":          "📄Export CSV",
+		"manager.stats.caption":      "📊 Statistics of @{bot}",
+		"manager.stats.error":        "Failed to load the statistics.",
+
+		"manager.withdraw.choose":         "💸 Choose the bot whose balance you want to withdraw from:",
+		"manager.withdraw.minimum":        "Minimum withdrawal: ${min}. Bot balance: ${balance}.",
+		"manager.withdraw.available":      "Available for withdrawal: ${balance}. Enter the amount in dollars.",
+		"manager.withdraw.bad_amount":     "Enter a valid amount of at least ${min}.",
+		"manager.withdraw.exceeds":        "The amount exceeds the bot balance (${balance}). Enter another amount.",
+		"manager.withdraw.destination":    "Enter the card number or wallet address for the payout.",
+		"manager.withdraw.insufficient":   "The bot balance is too low.",
+		"manager.withdraw.create_error":   "Failed to create the withdrawal request. Please try again later.",
+		"manager.withdraw.created":        "✅ Withdrawal request #{id} for ${amount} created. The amount is reserved, please wait for the administrator's decision.",
+		"manager.withdraw.request":        "💸 Withdrawal request #{id}\nOwner: @{owner} (ID {owner_id})\nBot: @{bot}\nAmount: ${amount}\nDetails: {destination}",
+		"manager.withdraw.approve":        "✅Approve",
+		"manager.withdraw.reject":         "❌Reject",
+		"manager.withdraw.resolved":       "The request has already been processed.",
+		"manager.withdraw.error":          "Failed to process the request.",
+		"manager.withdraw.approved":       "Request ✅ approved by administrator {admin}",
+		"manager.withdraw.rejected":       "Request ❌ rejected by administrator {admin}",
+		"manager.withdraw.owner_approved": "✅ Withdrawal request #{id} for ${amount} approved. The funds will be sent to {destination}.",
+		"manager.withdraw.owner_rejected": "❌ Withdrawal request #{id} for ${amount} rejected. The amount has been returned to the bot balance.",
+	},
+	Plurals: map[string]Plural{
+		"search.found": {
+			One:   "🔍 {count} service found for \"{query}\":",
+			Other: "🔍 {count} services found for \"{query}\":",
+		},
+		"referral.stats": {
+			One:   "🏂{count} person invited\n💸Earned from your referrals: ${earned}\n\n 🔘Invite friends and partners and get 10% of every purchase on your balance. \n\n ✨Your referral link: {link}",
+			Other: "🏂{count} people invited\n💸Earned from your referrals: ${earned}\n\n 🔘Invite friends and partners and get 10% of every purchase on your balance. \n\n ✨Your referral link: {link}",
+		},
+		"manager.menu_lines": {
+			One:   "Exactly {count} line is required.",
+			Other: "Exactly {count} lines are required.",
+		},
+		"manager.stats.top_service": {
+			One:   "{place}. {name} — {count} order, ${amount}",
+			Other: "{place}. {name} — {count} orders, ${amount}",
+		},
+	},
+}
@@ -0,0 +1,185 @@
+// Пакет i18n содержит каталоги текстов бота и выбор языка пользователя
+package i18n
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Язык текстов по умолчанию: для пользователей без сохраненного языка и уведомлений администраторам
+const Default = "ru"
+
+// Форма слова для числительного
+type Form int
+
+const (
+	One Form = iota
+	Few
+	Many
+	Other
+)
+
+// Формы сообщения с количеством. В русском используются One (1, 21), Few (2-4, 22-24)
+// и Many (5-20, 25), в английском One и Other
+type Plural struct {
+	One, Few, Many, Other string
+}
+
+func (p Plural) form(f Form) string {
+	switch f {
+	case One:
+		return p.One
+	case Few:
+		return p.Few
+	case Many:
+		return p.Many
+	}
+	return p.Other
+}
+
+type Catalog struct {
+	Lang string
+	// Правило выбора формы для количества n
+	Rule     func(n int) Form
+	Messages map[string]string
+	Plurals  map[string]Plural
+}
+
+var (
+	catalogs = map[string]*Catalog{}
+	// Порядок языков в настройках
+	languages []string
+)
+
+func register(c *Catalog) {
+	catalogs[c.Lang] = c
+	languages = append(languages, c.Lang)
+}
+
+// Поддерживаемые языки в порядке показа
+func Languages() []string {
+	return append([]string(nil), languages...)
+}
+
+// Язык из From.LanguageCode Telegram, например "en-US". Неизвестный язык
+// заменяется английским, пустой - языком по умолчанию
+func Match(languageCode string) string {
+	code := strings.ToLower(strings.TrimSpace(languageCode))
+	if code == "" {
+		return Default
+	}
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	return "en"
+}
+
+// Значения подстановок {имя} в тексте
+type Args map[string]interface{}
+
+// Тексты на одном языке
+type Localizer struct {
+	catalog *Catalog
+}
+
+// Неизвестный язык заменяется языком по умолчанию
+func For(lang string) Localizer {
+	if c, ok := catalogs[lang]; ok {
+		return Localizer{catalog: c}
+	}
+	return Localizer{catalog: catalogs[Default]}
+}
+
+func (l Localizer) Lang() string {
+	return l.catalog.Lang
+}
+
+// Текст сообщения id. Сообщения нет в каталоге языка - берется текст на языке по умолчанию
+func (l Localizer) T(id string, args ...Args) string {
+	text, ok := l.catalog.Messages[id]
+	if !ok {
+		text, ok = catalogs[Default].Messages[id]
+	}
+	if !ok {
+		log.Printf("i18n: нет текста %q", id)
+		return id
+	}
+	return format(text, args)
+}
+
+// Текст сообщения id в форме для количества n. Количество подставляется в {count}
+func (l Localizer) N(id string, n int, args ...Args) string {
+	catalog := l.catalog
+	plural, ok := catalog.Plurals[id]
+	if !ok {
+		catalog = catalogs[Default]
+		plural, ok = catalog.Plurals[id]
+	}
+	if !ok {
+		log.Printf("i18n: нет текста %q", id)
+		return id
+	}
+	return format(plural.form(catalog.Rule(n)), append(args, Args{"count": n}))
+}
+
+// Текст сообщения id на всех языках, например для кнопок обычной клавиатуры
+func All(id string) []string {
+	texts := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		texts = append(texts, For(lang).T(id))
+	}
+	return texts
+}
+
+// Совпадает ли text с сообщением id на одном из языков
+func Matches(id, text string) bool {
+	for _, t := range All(id) {
+		if t == text {
+			return true
+		}
+	}
+	return false
+}
+
+func format(text string, args []Args) string {
+	var pairs []string
+	for _, a := range args {
+		for key, value := range a {
+			pairs = append(pairs, "{"+key+"}", fmt.Sprint(value))
+		}
+	}
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func russianRule(n int) Form {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return One
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+func englishRule(n int) Form {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+func init() {
+	register(ru)
+	register(en)
+}
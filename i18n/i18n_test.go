@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+func placeholders(text string) []string {
+	found := placeholder.FindAllString(text, -1)
+	sort.Strings(found)
+	var unique []string
+	for i, p := range found {
+		if i == 0 || found[i-1] != p {
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Формы, которые выбирает правило языка
+func usedForms(c *Catalog) map[Form]bool {
+	forms := make(map[Form]bool)
+	for n := 0; n < 200; n++ {
+		forms[c.Rule(n)] = true
+	}
+	return forms
+}
+
+func TestCatalogsAreComplete(t *testing.T) {
+	base := catalogs[Default]
+	for _, lang := range Languages() {
+		c := catalogs[lang]
+		for id, text := range base.Messages {
+			other, ok := c.Messages[id]
+			if !ok {
+				t.Errorf("%s: нет текста %q", lang, id)
+				continue
+			}
+			if !equal(placeholders(text), placeholders(other)) {
+				t.Errorf("%s: подстановки %q: %v, в %s: %v", lang, id, placeholders(other), Default, placeholders(text))
+			}
+		}
+		for id := range c.Messages {
+			if _, ok := base.Messages[id]; !ok {
+				t.Errorf("%s: лишний текст %q", lang, id)
+			}
+		}
+
+		forms := usedForms(c)
+		for id := range base.Plurals {
+			plural, ok := c.Plurals[id]
+			if !ok {
+				t.Errorf("%s: нет форм %q", lang, id)
+				continue
+			}
+			for form := range forms {
+				if plural.form(form) == "" {
+					t.Errorf("%s: пустая форма %d у %q", lang, form, id)
+				}
+			}
+		}
+		for id := range c.Plurals {
+			if _, ok := base.Plurals[id]; !ok {
+				t.Errorf("%s: лишние формы %q", lang, id)
+			}
+		}
+	}
+}
+
+func TestPluralForms(t *testing.T) {
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"ru", 1, "1 заказ,"},
+		{"ru", 3, "3 заказа,"},
+		{"ru", 11, "11 заказов,"},
+		{"ru", 22, "22 заказа,"},
+		{"ru", 25, "25 заказов,"},
+		{"en", 1, "1 order,"},
+		{"en", 2, "2 orders,"},
+	}
+	for _, tc := range cases {
+		got := For(tc.lang).N("manager.stats.top_service", tc.n, Args{"place": 1, "name": "x", "amount": "1.00"})
+		if !strings.Contains(got, tc.want) {
+			t.Errorf("%s %d: %q не содержит %q", tc.lang, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{"": "ru", "ru": "ru", "en-US": "en", "EN": "en", "de": "en", "ru_RU": "ru"}
+	for code, want := range cases {
+		if got := Match(code); got != want {
+			t.Errorf("Match(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package i18n
+
+var ru = &Catalog{
+	Lang: "ru",
+	Rule: russianRule,
+	Messages: map[string]string{
+		"language.name":    "🇷🇺 Русский",
+		"language.changed": "Язык изменен на русский.",
+
+		"common.cancel":    "Отмена",
+		"common.cancelled": "Отменено",
+		"common.error":     "Произошла ошибка.",
+		"common.back":      "⬅️Назад",
+
+		"access.denied":         "У вас нет прав доступа.",
+		"access.denied_command": "У вас нет прав доступа к этой команде.",
+		"callback.stale":        "Кнопка устарела, откройте меню заново.",
+		"bot.overloaded":        "Бот перегружен, попробуйте позже.",
+
+		"menu.balance":  "💳 Баланс",
+		"menu.order":    "✍️Сделать заказ",
+		"menu.referral": "🤝 Партнерам",
+		"menu.profile":  "🧩Профиль",
+		"menu.site":     "⚡️Сайт (-55%)",
+
+		"branding.greeting":  "👋 Привет, {name}! Я - StageSMM_Bot, ваш верный помощник для продвижения проектов и аккаунтов в социальных сетях. 🚀 Продвигай свои проекты с нашей помощью!",
+		"branding.site_text": "⚡️На нашем сайте [StageSMM](https://stagesmm.com/) вы можете накрутить все, что есть в боте, в более удобном формате.\n\n☝️Главными плюсами сайта являются:\n\n🔸 Цены ПО ВСЕМ категориям на 55% дешевле цен бота \n🔸 ОГРОМНОЕ количество услуг\n🔸 Интуитивно понятный интерфейс\n🔸 Легкие пополнения с кучей способов оплат\n\n♦️И наконец промокод на пополнение `STAGE10` .Используя его вы сможете пополнять баланс на 10% больше оплаченного♦️",
+
+		"subscription.required": "Чтобы пользоваться ботом, вам нужно подписаться на каналы. После подписки заново напишите /start",
+		"subscription.button":   "Подписаться на канал",
+		"subscription.bonus":    "🎁 Поздравляем, Вы получили бонус за подписку!\n\n🌟 Ваш баланс пополнен на {amount}р",
+
+		"balance.text":   "💳 Ваш баланс: {balance}",
+		"balance.top_up": "⚡️Пополнить баланс",
+		"balance.promo":  "🎁Промокод",
+
+		"profile.text":     "🤵‍♂️ Пользователь:{name}\n 🔎 ID:{id}\n 💳 Ваш баланс:{balance}",
+		"profile.orders":   "📝Мои заказы",
+		"profile.settings": "⚙️Настройки",
+		"profile.help":     "⛑ Помощь",
+
+		"support.text":   "Техническая поддержка: ",
+		"support.button": "Написать",
+
+		"settings.currency":            "⚙️Сменить валюту на:",
+		"settings.language":            "🌐 Язык бота:",
+		"settings.favorite_alerts.on":  "🔔 Уведомления об избранном: вкл",
+		"settings.favorite_alerts.off": "🔕 Уведомления об избранном: выкл",
+
+		"currency.changed.RUB":  "Валюта изменена на рубли.",
+		"currency.changed.USD":  "Валюта изменена на доллары.",
+		"currency.changed.EUR":  "Валюта изменена на евро.",
+		"currency.changed.UAH":  "Валюта изменена на гривны.",
+		"currency.changed.KZT":  "Валюта изменена на тенге.",
+		"currency.changed.USDT": "Валюта изменена на USDT.",
+
+		"orders.error": "Произошла ошибка при получении информации о ваших заказах.",
+		"orders.empty": "Вы еще не совершали покупок.",
+		"orders.title": "📝 Ваши заказы:",
+		"orders.item":  "Номер услуги: {service}\nСсылка: {link}\nКоличество: {quantity}\nСтатус: {status}",
+
+		"order.status.PENDING":     "Ожидание",
+		"order.status.COMPLETED":   "Выполнен",
+		"order.status.IN_PROGRESS": "В процессе",
+		"order.status.PARTIAL":     "Частично выполнен",
+		"order.status.CANCELED":    "Отменен",
+		"order.status.unknown":     "Неизвестный статус",
+
+		"favorites.button":           "❤️‍🔥Избранное",
+		"favorites.empty":            "В избранном пока нет услуг.",
+		"favorites.title":            "Ваши избранные услуги:",
+		"favorites.added":            "Услуга добавлена в избранное",
+		"favorites.removed":          "Услуга удалена из избранного",
+		"favorites.error":            "Ошибка при обновлении избранных услуг",
+		"favorites.alerts.muted":     "Уведомления об избранных услугах отключены",
+		"favorites.alerts.unmuted":   "Уведомления об избранных услугах включены",
+		"favorites.alert.price_up":   "📈 Цена услуги «{name}» из избранного выросла: {old} → {new} за 1000",
+		"favorites.alert.price_down": "📉 Цена услуги «{name}» из избранного снизилась: {old} → {new} за 1000",
+		"favorites.alert.removed":    "❌ Услуга «{name}» из избранного больше недоступна. Последняя цена: {old} за 1000",
+		"favorites.alert.returned":   "✅ Услуга «{name}» из избранного снова доступна. Цена: {new} за 1000",
+
+		"search.button":    "🔍 Поиск",
+		"search.prompt":    "🔍 Напишите, какую услугу ищете, например: подписчики инстаграм\n\nФильтры:\n#соцсеть - только одна соцсеть\n<100 или «до 100» - цена за 1000 не выше\nrefill - с докруткой, cancel - с отменой\n\nКаждое сообщение - новый поиск. Чтобы закончить, нажмите «Отмена».",
+		"search.not_found": "По запросу «{query}» ничего не найдено. Попробуйте другие слова или уберите фильтры.",
+		"search.error":     "Ошибка при поиске услуг, попробуйте позже.",
+		"search.item":      "{name} · {price}",
+
+		"catalog.choose_network":     "✨ Выберите социальную сеть для продвижения:",
+		"catalog.choose_category":    "Выберите категорию:",
+		"catalog.choose_service":     "Выберите услугу:",
+		"catalog.back_to_categories": "🔙 Вернуться к категориям",
+		"catalog.prev":               "⬅️ Назад",
+		"catalog.next":               "➡️ Вперед",
+		"catalog.page":               "Страница {page} из {total}",
+
+		"service.info":            "ℹ️ Информация об услуге\n\n🔢 ID услуги: {id}\n📝 Услуга: {name}\n\n📝 Категория: {category}\n\n💸 Цена за 1000: {price}\n\n📉 Минимальное количество: {min}\n📈 Максимальное количество: {max}",
+		"service.not_found":       "Услуга не найдена",
+		"service.back":            "🔙Вернуться к услугам",
+		"service.order":           "➕Заказать",
+		"service.favorite_add":    "✅Добавить в избранное",
+		"service.favorite_remove": "❌Удалить из избранного",
+
+		"order.start":          "💬 Вы заказываете услугу: {name}.\n\n ID услуги {id}. \n\nДля оформления заказа укажите ссылку.",
+		"order.bad_link":       "Введите ссылку корректно.",
+		"order.enter_quantity": "Введите количество. Минимальное: {min}, максимальное: {max}.",
+		"order.bad_number":     "Пожалуйста, введите действительное число.",
+		"order.quantity_range": "Количество должно быть в диапазоне от {min} до {max}.",
+		"order.balance_error":  "Произошла ошибка при получении информации о вашем балансе.",
+		"order.price":          "Цена услуги: {price}. Ваш баланс: {balance}.",
+		"order.price_short":    "На вашем балансе недостаточно средств. Цена услуги: {price}. Ваш баланс: {balance}.",
+		"order.buy":            "💰Купить",
+		"order.failed":         "Ошибка при оформлении заказа. Пожалуйста, попробуйте снова.",
+		"order.balance_access": "Произошла ошибка при доступе к вашему балансу.",
+		"order.insufficient":   "На вашем балансе недостаточно средств для оформления заказа.",
+		"order.create_error":   "Ошибка при создании заказа: {error}",
+		"order.created":        "Заказ успешно создан. ID услуги: {service}",
+		"order.created_wait":   "Заказ создан, ожидайте.",
+		"order.restart":        "Ваш запрос не может быть обработан. Пожалуйста, начните процесс заново.",
+		"order.no_service":     "Ошибка: ID сервиса не указан.",
+		"order.service_error":  "Ошибка при получении данных сервиса.",
+
+		"promo.enter":        "✍️Введите ваш промокод:",
+		"promo.not_found":    "Промокод не найден.",
+		"promo.exhausted":    "Этот промокод уже использован максимальное количество раз.",
+		"promo.already_used": "Вы уже использовали этот промокод.",
+		"promo.activated":    "🎁 Поздравляем, Вы активировали промокод!\n\n🌟 Ваш баланс пополнен на {amount}р",
+		"promo.applied":      "Промокод успешно применен.",
+
+		"link.not_found":    "Спец. ссылка не найдена.",
+		"link.exhausted":    "Эта спец. ссылка уже использована максимальное количество раз.",
+		"link.already_used": "Вы уже переходили по этой спец. ссылке.",
+
+		"payment.choose_system":     "Выберите платежную систему",
+		"payment.sbp":               "СБП|RUB",
+		"payment.ru_card":           "RU Карта|RUB",
+		"payment.other_crypto":      "Другая Крипта",
+		"payment.enter_amount.USD":  "Введите желаемую сумму в долларах.",
+		"payment.enter_amount.RUB":  "Введите желаемую сумму в рублях.",
+		"payment.enter_amount.EUR":  "Введите желаемую сумму в евро.",
+		"payment.enter_amount.UAH":  "Введите желаемую сумму в гривнах.",
+		"payment.enter_amount.KZT":  "Введите желаемую сумму в тенге.",
+		"payment.enter_amount.USDT": "Введите желаемую сумму в USDT.",
+		"payment.bad_amount":        "Введите корректную сумму.",
+		"payment.create_error":      "Ошибка при создании платежа.",
+		"payment.no_link":           "Не удалось получить ссылку на платеж, попробуйте снова.",
+		"payment.pay":               "Оплатить",
+		"payment.link":              "Для пополнения на сумму {amount} нажмите на кнопку оплатить:",
+		"payment.check_balance":     "После оплаты проверьте баланс.",
+		"payment.description":       "Пополнение баланса",
+
+		"admin.new_user":                 "Новый пользователь: {name}\nID: {id}\nРегион: {region}\nPremium: {premium}",
+		"admin.createpromo.usage":        "Неверный формат. Используйте: /createpromo [название] [скидка] [максимальное количество использований]",
+		"admin.createpromo.bad_discount": "Неверный формат скидки.",
+		"admin.createpromo.bad_limit":    "Неверный формат количества использований.",
+		"admin.createpromo.exists":       "Промокод с таким названием уже существует.",
+		"admin.createpromo.created":      "Промокод создан: {code}",
+		"admin.createurl.usage":          "Неверный формат. Используйте: /createurl [название] [сумма] [кол-во переходов]",
+		"admin.createurl.bad_amount":     "Ошибка в формате суммы.",
+		"admin.createurl.bad_clicks":     "Ошибка в формате количества переходов.",
+		"admin.createurl.exists":         "Ссылка с таким названием уже была создана ранее.",
+		"admin.createurl.created":        "Ссылка создана: {link}",
+		"admin.bonus.on":                 "Бонус за подписку активирован.",
+		"admin.bonus.off":                "Бонус за подписку деактивирован.",
+		"admin.broadcast.empty":          "Пожалуйста, укажите сообщение для рассылки.",
+		"admin.broadcast.format_error":   "Ошибка форматирования сообщения: {error}",
+		"admin.broadcast.started":        "Рассылка началась.",
+
+		"manager.menu":          "Меню",
+		"manager.cancelled":     "Действие отменено.",
+		"manager.welcome":       "👋Добро пожаловать!",
+		"manager.greeting":      "👋Привет {name}! Я бот-менеджер, который поможет тебе создать своего бота по накрутке",
+		"manager.bots_button":   "💼Боты",
+		"manager.bots":          "👋 Тут ты можешь добавлять, настраивать и удалять своих ботов! \n\nТекущее количество твоих ботов: {count} из {limit}.\n\nНаценка на услуги задается командой /markup [имя бота] [наценка в %]",
+		"manager.create_bot":    "🆕Создать бота",
+		"manager.my_bots":       "📋Мои боты",
+		"manager.withdraw":      "💸Вывод средств",
+		"manager.bot_not_found": "Бот не найден среди ваших ботов.",
+		"manager.no_bots":       "У вас пока нет ботов.",
+		"manager.list_title":    "📋 Ваши боты:",
+
+		"manager.token.prompt":      "❗️Ответьте на это сообщение токеном бота",
+		"manager.token.prompt_bot":  "❗️Ответьте на это сообщение новым токеном бота @{bot}",
+		"manager.token.limit":       "Превышен лимит количества ботов. Максимум можно иметь {limit} ботов.",
+		"manager.token.invalid":     "Неверный токен бота. Пожалуйста, проверьте и попробуйте снова.",
+		"manager.token.info_error":  "Не удалось получить информацию о боте. Пожалуйста, проверьте токен.",
+		"manager.token.duplicate":   "Этот бот уже добавлен.",
+		"manager.token.save_error":  "Ошибка при сохранении токена.",
+		"manager.token.save_failed": "Ошибка при сохранении токена: {error}",
+		"manager.token.added":       "Бот @{bot} был успешно добавлен и включен.",
+		"manager.token.wrong_bot":   "Токен принадлежит боту @{actual}, а не @{bot}. Отправьте токен нужного бота.",
+		"manager.token.replaced":    "Токен бота @{bot} обновлен, бот перезапускается.",
+		"manager.token.revoked":     "⚠️ Токен бота @{bot} отозван или бот удален в BotFather. Бот остановлен.\n\nОтправьте новый токен, чтобы возобновить работу.",
+		"manager.token.replace":     "🔑Заменить токен",
+
+		"manager.markup.usage":   "Неверный формат. Используйте: /markup [имя бота] [наценка в %]",
+		"manager.markup.invalid": "Наценка должна быть числом от 0 до 1000.",
+		"manager.markup.error":   "Не удалось сохранить наценку.",
+		"manager.markup.set":     "Наценка бота @{bot} установлена: {markup}%",
+
+		"manager.links.link_usage":                 "Неверный формат. Используйте: /linkservice [id услуги] [поставщик] [id услуги у поставщика] [приоритет]\nПри SMM_ROUTING=preferred заказ сначала получает поставщик с меньшим приоритетом. Приоритет привязки по умолчанию 1, основного поставщика - {default}.",
+		"manager.links.unlink_usage":               "Неверный формат. Используйте: /unlinkservice [id услуги] [поставщик]",
+		"manager.links.list_usage":                 "Неверный формат. Используйте: /servicelinks [id услуги]",
+		"manager.links.service_not_found":          "Услуга с таким id не найдена в каталоге.",
+		"manager.links.unknown_provider":           "Неизвестный поставщик. Подключены: {providers}",
+		"manager.links.provider_service_not_found": "У поставщика нет услуги с таким id.",
+		"manager.links.error":                      "Не удалось сохранить привязку.",
+		"manager.links.linked":                     "Услуга «{service}» привязана к «{name}» у {provider}.",
+		"manager.links.unlinked":                   "Привязка услуги «{service}» к {provider} удалена.",
+		"manager.links.not_linked":                 "Услуга не привязана к этому поставщику.",
+		"manager.links.title":                      "Поставщики услуги «{service}»:",
+		"manager.links.item":                       "{provider}: услуга {id}, ${rate} за 1000, {min}-{max}, приоритет {priority}",
+		"manager.links.item_unavailable":           "{provider}: услуга {id} больше не предлагается",
+
+		"manager.catalog_changes.usage":   "Неверный формат. Используйте: /catalogchanges [id услуги]",
+		"manager.catalog_changes.error":   "Не удалось получить изменения каталога.",
+		"manager.catalog_changes.empty":   "Изменений каталога пока нет.",
+		"manager.catalog_changes.title":   "Последние изменения каталога:",
+		"manager.catalog_changes.added":   "{date} ➕ {id} «{name}»: ${new_rate} за 1000, {new_min}-{new_max}",
+		"manager.catalog_changes.removed": "{date} ➖ {id} «{name}» снята с продажи",
+		"manager.catalog_changes.price":   "{date} 💲 {id} «{name}»: ${old_rate} → ${new_rate} за 1000",
+		"manager.catalog_changes.limits":  "{date} 📏 {id} «{name}»: {old_min}-{old_max} → {new_min}-{new_max}",
+
+		"manager.state.starting": "🟡 Запускается",
+		"manager.state.running":  "🟢 Запущен",
+		"manager.state.backoff":  "🟠 Перезапуск после ошибки: {error}",
+		"manager.state.failed":   "⛔️ Ошибка: {error}",
+		"manager.state.revoked":  "⛔️ Токен отозван",
+		"manager.state.stopped":  "🔴 Остановлен",
+
+		"manager.card":               "🤖 @{bot}\n\nСостояние: {state}\n👥 Пользователей: {users}\n💰 Ваш доход: ${earnings}\n💳 Баланс: ${balance}\n📈 Наценка: {markup}%",
+		"manager.card.start":         "▶️Запустить",
+		"manager.card.stop":          "⏹Остановить",
+		"manager.card.token":         "🔑Сменить токен",
+		"manager.card.stats":         "📊Статистика",
+		"manager.card.branding":      "🎨Оформление",
+		"manager.card.delete":        "🗑Удалить",
+		"manager.bot.revoked":        "Токен бота @{bot} отозван. Сначала замените токен.",
+		"manager.bot.starting":       "Бот @{bot} запускается.",
+		"manager.bot.delete_confirm": "Удалить бота @{bot}? Баланс бота (${balance}) будет потерян.",
+		"manager.bot.delete_yes":     "✅Да, удалить",
+		"manager.bot.delete_no":      "❌Отмена",
+		"manager.bot.delete_error":   "Не удалось удалить бота.",
+		"manager.bot.deleted":        "Бот @{bot} удален.",
+
+		"manager.branding.greeting":         "👋Приветствие",
+		"manager.branding.greeting_prompt":  "Отправьте текст приветствия. {name} будет заменено на имя пользователя.",
+		"manager.branding.support":          "🆘Поддержка",
+		"manager.branding.support_prompt":   "Отправьте ссылку на поддержку, например https://t.me/username",
+		"manager.branding.site_url":         "🔗Ссылка сайта",
+		"manager.branding.site_url_prompt":  "Отправьте ссылку на ваш сайт.",
+		"manager.branding.site_text":        "📝Текст сайта",
+		"manager.branding.site_text_prompt": "Отправьте текст сообщения о сайте.",
+		"manager.branding.channel":          "📢Канал",
+		"manager.branding.channel_prompt":   "Отправьте ID канала и ссылку на него через пробел, например: -1001234567890 https://t.me/channel\nБот должен быть администратором канала. Отправьте «нет», чтобы отключить обязательную подписку.",
+		"manager.branding.channel_off":      "нет",
+		"manager.branding.menu":             "⌨️Кнопки меню",
+		"manager.branding.menu_prompt":      "Отправьте 5 строк с названиями кнопок: баланс, заказ, партнерам, профиль, сайт.",
+		"manager.branding.card":             "🎨 Оформление @{bot}\n\nПриветствие: {greeting}\n\nПоддержка: {support}\nСайт: {site}\nКанал: {channel}\nКнопки: {menu}\n\nЧтобы вернуть значение по умолчанию, отправьте «{reset}» при изменении.",
+		"manager.branding.site_hidden":      "скрыт",
+		"manager.branding.channel_none":     "не требуется",
+		"manager.branding.hide_site":        "🙈Скрыть сайт",
+		"manager.branding.show_site":        "👁Показать сайт",
+		"manager.branding.saved":            "✅ Настройки бота @{bot} сохранены.",
+		"manager.branding.save_error":       "Ошибка при сохранении настроек.",
+		"manager.branding.bad_link":         "Неверная ссылка. Ссылка должна начинаться с https://",
+		"manager.branding.channel_format":   "Отправьте ID канала и ссылку через пробел.",
+		"manager.branding.bad_channel_id":   "Неверный ID канала.",
+		"manager.branding.bad_channel_link": "Неверная ссылка на канал.",
+		"manager.branding.menu_labels":      "Названия кнопок должны быть разными, непустыми, не длиннее 32 символов и не начинаться с /.",
+		"manager.branding.unknown":          "Неизвестная настройка.",
+
+		"manager.stats.today":        "Сегодня",
+		"manager.stats.7d":           "7 дней",
+		"manager.stats.30d":          "30 дней",
+		"manager.stats.all":          "Все время",
+		"manager.stats.title":        "📊 Статистика @{bot}: {period}",
+		"manager.stats.new_users":    "👤 Новых пользователей: {count}",
+		"manager.stats.active_users": "🔥 Активных пользователей: {count}",
+		"manager.stats.deposits":     "💵 Пополнения:",
+		"manager.stats.orders":       "📦 Заказы:",
+		"manager.stats.none":         "нет",
+		"manager.stats.group":        "{key}: {count} на ${amount}",
+		"manager.stats.gross":        "💰 Оборот: ${amount}",
+		"manager.stats.margin":       "📈 Ваш доход: ${amount}",
+		"manager.stats.top":          "🏆 Популярные услуги:",
+		"manager.stats.csv":          "📄Выгрузить CSV",
+		"manager.stats.caption":      "📊 Статистика @{bot}",
+		"manager.stats.error":        "Не удалось получить статистику.",
+
+		"manager.withdraw.choose":         "💸 Выберите бота, с баланса которого хотите вывести средства:",
+		"manager.withdraw.minimum":        "Минимальная сумма вывода: ${min}. Баланс бота: ${balance}.",
+		"manager.withdraw.available":      "Доступно к выводу: ${balance}. Введите сумму вывода в долларах.",
+		"manager.withdraw.bad_amount":     "Введите корректную сумму не меньше ${min}.",
+		"manager.withdraw.exceeds":        "Сумма превышает баланс бота (${balance}). Введите другую сумму.",
+		"manager.withdraw.destination":    "Укажите номер карты или адрес кошелька для выплаты.",
+		"manager.withdraw.insufficient":   "На балансе бота недостаточно средств.",
+		"manager.withdraw.create_error":   "Не удалось создать заявку на вывод. Попробуйте позже.",
+		"manager.withdraw.created":        "✅ Заявка #{id} на вывод ${amount} создана. Сумма зарезервирована, ожидайте решения администратора.",
+		"manager.withdraw.request":        "💸 Заявка на вывод #{id}\nВладелец: @{owner} (ID {owner_id})\nБот: @{bot}\nСумма: ${amount}\nРеквизиты: {destination}",
+		"manager.withdraw.approve":        "✅Одобрить",
+		"manager.withdraw.reject":         "❌Отклонить",
+		"manager.withdraw.resolved":       "Заявка уже обработана.",
+		"manager.withdraw.error":          "Ошибка при обработке заявки.",
+		"manager.withdraw.approved":       "Заявка ✅ одобрена администратором {admin}",
+		"manager.withdraw.rejected":       "Заявка ❌ отклонена администратором {admin}",
+		"manager.withdraw.owner_approved": "✅ Заявка #{id} на вывод ${amount} одобрена. Средства будут отправлены на {destination}.",
+		"manager.withdraw.owner_rejected": "❌ Заявка #{id} на вывод ${amount} отклонена. Сумма возвращена на баланс бота.",
+	},
+	Plurals: map[string]Plural{
+		"search.found": {
+			One:  "🔍 По запросу «{query}» найдена {count} услуга:",
+			Few:  "🔍 По запросу «{query}» найдено {count} услуги:",
+			Many: "🔍 По запросу «{query}» найдено {count} услуг:",
+		},
+		"referral.stats": {
+			One:  "🏂Приглашен {count} человек\n💸Заработано с ваших рефералов: ${earned}\n\n 🔘Приглашайте друзей и партнёров и получайте 10% на баланс с каждой покупки. \n\n ✨Ваша партнёрская ссылка: {link}",
+			Few:  "🏂Приглашено {count} человека\n💸Заработано с ваших рефералов: ${earned}\n\n 🔘Приглашайте друзей и партнёров и получайте 10% на баланс с каждой покупки. \n\n ✨Ваша партнёрская ссылка: {link}",
+			Many: "🏂Приглашено {count} человек\n💸Заработано с ваших рефералов: ${earned}\n\n 🔘Приглашайте друзей и партнёров и получайте 10% на баланс с каждой покупки. \n\n ✨Ваша партнёрская ссылка: {link}",
+		},
+		"manager.menu_lines": {
+			One:  "Нужна ровно {count} строка.",
+			Few:  "Нужно ровно {count} строки.",
+			Many: "Нужно ровно {count} строк.",
+		},
+		"manager.stats.top_service": {
+			One:  "{place}. {name} — {count} заказ, ${amount}",
+			Few:  "{place}. {name} — {count} заказа, ${amount}",
+			Many: "{place}. {name} — {count} заказов, ${amount}",
+		},
+	},
+}
@@ -0,0 +1,176 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Коды завершения процесса
+const (
+	ExitClean = 0
+	// Не все обработчики и фоновые задачи завершились до дедлайна или остановка завершилась с ошибкой
+	ExitDirty = 1
+	// Фоновая задача остановила приложение из-за ошибки
+	ExitFailed = 2
+)
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+type task struct {
+	name string
+	done chan struct{}
+}
+
+// Управляет запуском и остановкой приложения. Фоновые задачи получают контекст,
+// который отменяется по SIGINT/SIGTERM, после чего по порядку вызываются шаги остановки
+type Manager struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+
+	mu      sync.Mutex
+	hooks   []hook
+	tasks   []task
+	closers []hook
+	failed  error
+}
+
+// timeout - общий дедлайн на все шаги остановки и завершение фоновых задач
+func New(timeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel, timeout: timeout}
+}
+
+// Контекст приложения, отменяется в начале остановки
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Запускает фоновую задачу. Задача должна завершиться после отмены ctx
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	t := task{name: name, done: make(chan struct{})}
+	m.mu.Lock()
+	m.tasks = append(m.tasks, t)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(t.done)
+		fn(m.ctx)
+	}()
+}
+
+// Добавляет шаг остановки. Шаги выполняются в порядке добавления, после отмены
+// контекста приложения и до ожидания фоновых задач
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// Добавляет освобождение ресурса, например пула соединений с базой. Выполняется
+// последним, когда фоновые задачи уже завершились или истек дедлайн
+func (m *Manager) OnClose(name string, fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, hook{name: name, fn: func(context.Context) error { return fn() }})
+}
+
+// Останавливает приложение из-за ошибки, например если HTTP-сервер не смог занять порт
+func (m *Manager) Fail(err error) {
+	m.mu.Lock()
+	if m.failed == nil {
+		m.failed = err
+	}
+	m.mu.Unlock()
+	log.Printf("Остановка приложения из-за ошибки: %v", err)
+	m.cancel()
+}
+
+// Ждет SIGINT/SIGTERM или Fail, останавливает приложение и возвращает код завершения.
+// Повторный сигнал во время остановки завершает процесс сразу
+func (m *Manager) Wait() int {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		log.Printf("Получен сигнал %v, остановка...", sig)
+	case <-m.ctx.Done():
+	}
+
+	go func() {
+		sig := <-signals
+		log.Printf("Повторный сигнал %v, принудительное завершение", sig)
+		os.Exit(ExitDirty)
+	}()
+
+	code := m.shutdown()
+	m.mu.Lock()
+	if m.failed != nil {
+		code = ExitFailed
+	}
+	m.mu.Unlock()
+	return code
+}
+
+func (m *Manager) shutdown() int {
+	m.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	hooks := append([]hook(nil), m.hooks...)
+	tasks := append([]task(nil), m.tasks...)
+	closers := append([]hook(nil), m.closers...)
+	m.mu.Unlock()
+
+	code := ExitClean
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			log.Printf("Остановка: %s: %v", h.name, err)
+			code = ExitDirty
+			continue
+		}
+		log.Printf("Остановка: %s - готово", h.name)
+	}
+
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			log.Printf("Остановка: задача %s не завершилась до дедлайна", t.name)
+			code = ExitDirty
+		}
+	}
+
+	for _, c := range closers {
+		if err := c.fn(ctx); err != nil {
+			log.Printf("Остановка: %s: %v", c.name, err)
+			code = ExitDirty
+		}
+	}
+	if code == ExitClean {
+		log.Println("Приложение остановлено")
+	}
+	return code
+}
+
+// Ждет закрытия done, но не дольше дедлайна ctx
+func WaitDone(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("не завершено до дедлайна")
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Cekretik/BoostBot/database"
+)
+
+// Логгер библиотеки Telegram, скрывающий токены ботов в URL из текстов ошибок
+type tokenRedactingLogger struct{}
+
+func (tokenRedactingLogger) Println(v ...interface{}) {
+	log.Print(database.RedactTokens(fmt.Sprintln(v...)))
+}
+
+func (tokenRedactingLogger) Printf(format string, v ...interface{}) {
+	log.Print(database.RedactTokens(fmt.Sprintf(format, v...)))
+}
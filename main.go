@@ -1,390 +1,219 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/callback"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
 	"github.com/Cekretik/BoostBot/functionality"
-	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/lifecycle"
 	"github.com/Cekretik/BoostBot/payment"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/supervisor"
+	"github.com/Cekretik/BoostBot/telegram"
+	"github.com/Cekretik/BoostBot/workerpool"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 )
 
+// Сколько приложение ждет завершения обработчиков и фоновых задач после SIGINT/SIGTERM
+const shutdownTimeout = 30 * time.Second
+
+// Настройки, загруженные при запуске
+var settings *config.Config
+
 func main() {
-	err := godotenv.Load()
+	configSources := config.DefaultSources()
+	cfg, err := config.Load(configSources)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal(err)
+	}
+	settings = cfg
+	config.Apply(cfg)
+
+	app := lifecycle.New(shutdownTimeout)
+	functionality.SetTaskRunner(app.Go)
+	app.Go("config watch", func(ctx context.Context) {
+		config.Watch(ctx, configSources, cfg, time.Minute)
+	})
+
+	api.Configure(cfg.StageSMM)
+	if err := api.ConfigureProvider(cfg.Provider); err != nil {
+		log.Fatal(err)
 	}
+	currency.Configure(cfg.Currency)
+	payment.Configure(cfg.Payment)
+	functionality.Configure(cfg.Telegram)
 
-	db, err := database.InitDB()
+	db, err := database.InitDB(cfg.Database)
 	if err != nil {
 		log.Panic(err)
 	}
-	go BotManager(db)
-	go RunBots(db)
-
-	doneCategories := make(chan bool)
-	doneOrder := make(chan bool)
-	go database.UpdateCategoriesInDB(db, doneCategories)
-	go database.UpdateSubcategoriesInDB(db, doneCategories)
-	go database.UpdateServicesInDB(db, doneCategories)
-	go database.UpdateOrdersPeriodically(db, doneOrder)
-	go api.UpdateCurrencyRatePeriodically()
-	go payment.StartHTTPServer(db)
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-
-	select {}
-}
+	// Пул соединений закрывается последним, когда обработчики и синхронизации уже завершились
+	app.OnClose("database", func() error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
 
-func BotManager(db *gorm.DB) {
-	err := godotenv.Load()
+	mainBot, err := database.EnsureMainBot(db, cfg.Telegram.Token)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Panic(err)
+	}
+	if err := database.MigrateLegacyRowsToBot(db, mainBot.ID); err != nil {
+		log.Panic(err)
+	}
+	tgbotapi.SetLogger(tokenRedactingLogger{})
+
+	// Диалоги пользователей хранятся в базе и переживают перезапуск
+	fsm.Default = fsm.New(fsm.NewDBStore(db), fsm.DefaultTTL)
+	app.Go("conversations purge", func(ctx context.Context) {
+		fsm.Default.PurgeExpiredPeriodically(ctx, 10*time.Minute)
+	})
+
+	// CALLBACK_SECRET включает подпись данных кнопок, без него данные не подписываются
+	var callbackKey []byte
+	if cfg.Telegram.CallbackSecret != "" {
+		callbackKey = []byte(cfg.Telegram.CallbackSecret)
 	}
+	callback.Default = callback.NewCodec(callbackKey, callback.NewDBStore(db))
+	app.Go("callback payloads purge", func(ctx context.Context) {
+		callback.Default.PurgeExpiredPeriodically(ctx, time.Hour)
+	})
+
+	// Остановка: боты перестают принимать обновления и дорабатывают принятые,
+	// затем HTTP-сервер дожидается текущих запросов, затем закрывается база
+	botSupervisor = NewBotSupervisor(db)
+	app.Go("manager bot", func(ctx context.Context) {
+		BotManager(ctx, db)
+	})
+	app.Go("bots", RunBots)
+	app.OnShutdown("bots", botSupervisor.StopAll)
+
+	server := payment.NewHTTPServer(db)
+	go func() {
+		log.Printf("HTTP server started on %v", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.Fail(err)
+		}
+	}()
+	app.OnShutdown("http server", server.Shutdown)
+
+	favoriteNotifier := functionality.NewFavoriteNotifier(db)
+	app.Go("favorite alerts", favoriteNotifier.Run)
+	app.Go("catalog sync", func(ctx context.Context) {
+		database.UpdateCatalogPeriodically(ctx, db, favoriteNotifier.Enqueue)
+	})
+	app.Go("orders sync", func(ctx context.Context) {
+		database.UpdateOrdersPeriodically(ctx, db)
+	})
+	app.Go("currency rates", currency.UpdateRatesPeriodically)
+
+	os.Exit(app.Wait())
+}
 
-	token := os.Getenv("TOKEN_BOT")
+func BotManager(ctx context.Context, db *gorm.DB) {
+	token := settings.Telegram.Token
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		log.Panic(err)
 	}
+	managerBot.Store(bot)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	mainBot, err := database.GetBotByToken(db, token)
+	if err != nil {
+		log.Panic(err)
+	}
+	sender.Register(bot, botSenderConfig(db, mainBot.ID))
+	defer sender.Unregister(bot)
+	updates, stopUpdates, err := OpenUpdatesChannel(db, bot, mainBot.ID)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			chatID := update.CallbackQuery.Message.Chat.ID
-			callbackData := update.CallbackQuery.Data
-			switch callbackData {
-			case "create_bot":
-				InitiateTokenInput(bot, chatID)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "bots":
-				HandleBotStart(bot, update.CallbackQuery, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "backtomenu":
-				HandleBackButton(bot, update.CallbackQuery, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			}
-		}
-		if update.Message != nil {
-			chatID := update.Message.Chat.ID
-			if strings.HasPrefix(update.Message.Text, "/start") {
-				WelcomeMessage(bot, chatID)
-				SendMenuButton(bot, chatID, db)
-				continue
-			} else if update.Message.Text == "" {
-				WelcomeMessage(bot, chatID)
-				SendMenuButton(bot, chatID, db)
-				continue
-			}
-			if update.Message.Text == "Меню" {
-				SendMenuButton(bot, chatID, db)
-				continue
-			}
-		}
-		if update.Message != nil && update.Message.Text != "" {
-			if status, exists := BotStatuses[update.Message.Chat.ID]; exists && status.CurrentState == "awaiting_token" {
-				HandleTokenInput(bot, update, db) // Обработка ввода токена
+	r := newManagerRouter(db, bot.Self.UserName)
+	pool := workerpool.New(updatePoolConfig(mainBot), func(update tgbotapi.Update) {
+		r.Handle(bot, update)
+	})
+	defer pool.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			stopUpdates()
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
 			}
+			submitUpdate(bot, pool, update)
 		}
+	}
+}
 
+// Настройки отправителя бота: чаты, ответившие 403, отмечаются недоступными
+func botSenderConfig(db *gorm.DB, botID int64) sender.Config {
+	cfg := sender.DefaultConfig()
+	cfg.OnBlocked = func(chatID int64) {
+		if err := database.MarkUserUnreachable(db, botID, chatID); err != nil {
+			log.Printf("Не удалось отметить пользователя %d бота %d недоступным: %v", chatID, botID, err)
+		}
 	}
+	return cfg
 }
 
-func ProcessMessages(bot *tgbotapi.BotAPI, db *gorm.DB) {
-	itemsPerPage := 10
-	err := godotenv.Load()
+func ProcessMessages(ctx context.Context, bot *tgbotapi.BotAPI, db *gorm.DB, botID int64) error {
+	r := newClientRouter(ctx, db, botID, bot.Self.UserName)
+
+	botOwner, err := database.GetBotByID(db, botID)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return err
 	}
-
-	channelIDStr := os.Getenv("CHANNEL_ID")
-	channelID, err := strconv.ParseInt(channelIDStr, 10, 64)
+	sender.Register(bot, botSenderConfig(db, botID))
+	defer sender.Unregister(bot)
+	telegram.Register(botID, bot)
+	defer telegram.Unregister(botID, bot)
+
+	pool := workerpool.New(updatePoolConfig(botOwner), func(update tgbotapi.Update) {
+		r.Handle(bot, update)
+	})
+	// Бот не останавливается, пока не обработаны уже принятые обновления
+	defer pool.Wait()
+
+	updates, stopUpdates, err := OpenUpdatesChannel(db, bot, botID)
 	if err != nil {
-		log.Panic("Ошибка при преобразовании CHANNEL_ID:", err)
+		return err
 	}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
-
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			chatID := update.CallbackQuery.Message.Chat.ID
-			callbackData := update.CallbackQuery.Data
-			switch callbackData {
-			case "replenishBalance":
-				payment.HandleReplenishCommand(bot, update.CallbackQuery.Message.Chat.ID)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "cryptomus_USDT", "cryptomus_BTC", "cryptomus_MATIC", "cryptomus_OTHER":
-				payment.HandleCryptomusButton(bot, update.CallbackQuery.Message.Chat.ID, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-
-			case "AAIO_SBP", "AAIO_RU":
-				payment.HandleAAIOButton(bot, update.CallbackQuery.Message.Chat.ID, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "changeCurrencyToRUB":
-				functionality.HandleChangeCurrency(bot, chatID, db, true)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "changeCurrencyToUSD":
-				functionality.HandleChangeCurrency(bot, chatID, db, false)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "profile:favorites":
-				functionality.HandleFavoritesCommand(bot, db, chatID)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "promo":
-				functionality.HandlePromoCommand(bot, chatID, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "allorders":
-				functionality.HandleOrdersCommand(bot, update.CallbackQuery.Message.Chat.ID, db)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "settings":
-				functionality.SendSettingsKeyboard(bot, chatID)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-			case "techsup":
-				functionality.TechSupMessage(bot, chatID)
-				bot.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, ""))
-
-			}
-			if strings.HasPrefix(update.CallbackQuery.Data, "addFavorite:") || strings.HasPrefix(update.CallbackQuery.Data, "removeFavorite:") {
-				functionality.HandleAddToFavoritesCallback(bot, db, update.CallbackQuery)
-			}
-			if strings.HasPrefix(callbackData, "subcategory:") || strings.HasPrefix(callbackData, "prevServ:") || strings.HasPrefix(callbackData, "nextServ:") {
-				var subcategoryID string
-				if strings.HasPrefix(callbackData, "subcategory:") {
-					subcategoryID = strings.TrimPrefix(callbackData, "subcategory:")
-				} else {
-					parts := strings.Split(callbackData, ":")
-					subcategoryID = parts[1]
-				}
-
-				totalServicePages, err := functionality.GetTotalPagesForService(db, itemsPerPage, subcategoryID)
-				if err != nil {
-					log.Printf("Error getting total pages for services: %v", err)
-					continue
-				}
-				functionality.HandleServiceCallBackQuery(bot, db, update.CallbackQuery, totalServicePages)
-			} else if strings.HasPrefix(callbackData, "serviceInfo:") {
-				functionality.HandleServiceCallBackQuery(bot, db, update.CallbackQuery, 0)
-			} else if strings.HasPrefix(callbackData, "backToServices:") {
-				subcategoryID := strings.TrimPrefix(callbackData, "backToServices:")
-				totalServicePages, err := functionality.GetTotalPagesForService(db, itemsPerPage, subcategoryID)
-				if err != nil {
-					log.Printf("Error getting total pages for services: %v", err)
-					continue
-				}
-
-				functionality.HandleServiceCallBackQuery(bot, db, update.CallbackQuery, totalServicePages)
-			} else if strings.HasPrefix(callbackData, "backToSubcategories:") {
-				categoryID := strings.TrimPrefix(callbackData, "backToSubcategories:")
-				totalPages, err := functionality.GetTotalPagesForCategory(db, itemsPerPage, categoryID)
-				if err != nil {
-					log.Printf("Error getting total pages for category: %v", err)
-					continue
-				}
-				functionality.HandleServiceCallBackQuery(bot, db, update.CallbackQuery, totalPages)
-			} else if strings.HasPrefix(callbackData, "category:") || strings.HasPrefix(callbackData, "prevCat:") || strings.HasPrefix(callbackData, "nextCat:") || strings.HasPrefix(callbackData, "backToCategories:") {
-				var categoryID string
-				if strings.HasPrefix(callbackData, "category:") {
-					categoryID = strings.TrimPrefix(callbackData, "category:")
-				} else {
-					parts := strings.Split(callbackData, ":")
-					categoryID = parts[1]
-				}
-
-				totalPages, err := functionality.GetTotalPagesForCategory(db, itemsPerPage, categoryID)
-				if err != nil {
-					log.Printf("Error getting total pages for category: %v", err)
-					continue
-				}
-
-				functionality.HandleCallbackQuery(bot, db, update.CallbackQuery, totalPages)
-			} else if strings.HasPrefix(callbackData, "order:") {
-				serviceID := strings.TrimPrefix(callbackData, "order:")
-				if serviceID == "" {
-					log.Printf("Service ID is empty in callback data: %s", callbackData)
-					bot.Send(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Ошибка: ID сервиса не указан."))
-					continue
-				}
-				serviceIDInt, err := strconv.Atoi(serviceID)
-				if err != nil {
-					log.Printf("Error converting service ID to integer: %v", err)
-					bot.Send(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Ошибка: ID сервиса не указан."))
-					continue
-				}
-				service, err := database.GetService(db, serviceIDInt)
-				if err != nil {
-					log.Printf("Error getting service '%s': %v", serviceID, err)
-					bot.Send(tgbotapi.NewMessage(update.CallbackQuery.Message.Chat.ID, "Ошибка при получении данных сервиса."))
-					continue
-				}
-				functionality.HandleOrderCommand(bot, update.CallbackQuery.Message.Chat.ID, service)
+	tokenCheck := time.NewTicker(tokenCheckInterval)
+	defer tokenCheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			stopUpdates()
+			return nil
+		case <-tokenCheck.C:
+			// Ошибки получения обновлений библиотека только логирует, поэтому отзыв токена проверяется отдельно
+			if _, err := bot.GetMe(); supervisor.IsTokenRevoked(err) {
+				stopUpdates()
+				return supervisor.ErrTokenRevoked
 			}
-
-			// Обработка кнопки "Купить"
-			if strings.HasPrefix(callbackData, "buy") {
-				chatID := update.CallbackQuery.Message.Chat.ID
-				if userStatus, exists := functionality.UserStatuses[chatID]; exists {
-					serviceID, err := strconv.Atoi(userStatus.PendingServiceID)
-					if err != nil {
-						log.Printf("Error converting service ID to integer: %v", err)
-						bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: ID сервиса не указан."))
-						continue
-					}
-					service, err := database.GetService(db, serviceID)
-					if err != nil {
-						log.Printf("Error getting service '%s': %v", userStatus.PendingServiceID, err)
-						bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении данных сервиса."))
-						continue
-					}
-					functionality.HandlePurchase(db, bot, chatID, service)
-				} else {
-					bot.Send(tgbotapi.NewMessage(chatID, "Ваш запрос не может быть обработан. Пожалуйста, начните процесс заново."))
-				}
-			}
-		}
-		if update.Message != nil && update.Message.Text != "" {
-			chatID := update.Message.Chat.ID
-
-			if status, exists := functionality.UserPromoStatuses[chatID]; exists && status.PromoState == "awaitingPromoCode" {
-				functionality.ProcessPromoCodeInput(bot, chatID, update.Message.Text, db)
-				delete(functionality.UserPromoStatuses, chatID) // Удаление статуса после обработки
-				continue
-			}
-			if update.Message.Text == "Отмена" {
-				if _, exists := functionality.UserPromoStatuses[chatID]; exists {
-					delete(functionality.UserPromoStatuses, chatID)
-					functionality.SendStandardKeyboard(bot, chatID)
-					continue
-				}
-			}
-			if status, exists := functionality.UserPromoStatuses[chatID]; exists && status.PromoState == "awaitingPromoCode" {
-				functionality.ProcessPromoCodeInput(bot, chatID, update.Message.Text, db)
-				delete(functionality.UserPromoStatuses, chatID)
-				continue
-			}
-		}
-		if update.Message != nil {
-			chatID := update.Message.Chat.ID
-			userPaymentStatus, exists := payment.UserPaymentStatuses[chatID]
-			if update.Message.Text == "Отмена" {
-				if _, exists := functionality.UserStatuses[chatID]; exists {
-					delete(functionality.UserStatuses, chatID)
-					functionality.SendStandardKeyboard(bot, chatID)
-					continue
-				} else if _, exists := payment.UserPaymentStatuses[chatID]; exists {
-					delete(payment.UserPaymentStatuses, chatID)
-					functionality.SendStandardKeyboard(bot, chatID)
-					continue
-				}
-			}
-			functionality.NotifyAdminsAboutNewUser(bot, update.Message.From, update.Message.From.IsPremium, db)
-			if exists && userPaymentStatus.CurrentState == "awaitingAmount" {
-				payment.HandlePaymentInput(db, bot, chatID, update.Message.Text)
-				continue
-			} else if exists && userPaymentStatus.CurrentState == "awaitingAmountAAIO" {
-				payment.HandlePaymentInputAAIO(db, bot, chatID, update.Message.Text)
-				continue
-			}
-			if strings.HasPrefix(update.Message.Text, "/start") {
-				args := strings.Split(update.Message.Text, " ")
-				if len(args) > 1 {
-					param := args[1]
-					// Проверяем, является ли параметр специальной ссылкой
-					if strings.Contains(param, "_") {
-						functionality.ProcessSpecialLink(bot, update.Message.Chat.ID, param, db)
-					} else {
-						// Обработка реферального ID
-						referrerID, err := strconv.ParseInt(param, 10, 64)
-						if err == nil && referrerID != 0 {
-							// Проверяем, существует ли пользователь-реферер
-							var referrer models.UserState
-							if err := db.Where("user_id = ?", referrerID).First(&referrer).Error; err == nil {
-								// Проверяем, что реферер и реферал - разные люди
-								if referrer.UserID != int64(update.Message.From.ID) {
-									// Создаем запись о реферале, если она еще не существует
-									var existingReferral models.Referral
-									if err := db.Where("referrer_id = ? AND referred_id = ?", referrerID, update.Message.From.ID).First(&existingReferral).Error; err != nil {
-										// Добавляем нового реферала
-										newReferral := models.Referral{
-											ReferrerID:   referrerID,
-											ReferredID:   int64(update.Message.From.ID),
-											AmountEarned: 0,
-										}
-										db.Create(&newReferral)
-									}
-								}
-							}
-						}
-					}
-				}
-			} else if strings.HasPrefix(update.Message.Text, "/createpromo") {
-				functionality.HandleCreatePromoCommand(bot, update, db)
-				continue
-			} else if strings.HasPrefix(update.Message.Text, "/createurl") {
-				functionality.HandleCreateUrlCommand(bot, update, db)
-				continue
-			} else if strings.HasPrefix(update.Message.Text, "/broadcast ") {
-				functionality.HandleBroadcastCommand(bot, update, db)
-				continue
-			} else if strings.HasPrefix(update.Message.Text, "/bonus") {
-				functionality.HandleBonusCommand(bot, update, db)
-				continue
-			}
-			if userStatus, exists := functionality.UserStatuses[chatID]; exists && userStatus.CurrentState != "" {
-				serviceID, err := strconv.Atoi(userStatus.PendingServiceID)
-				if err != nil {
-					log.Printf("Error converting service ID to integer: %v", err)
-					bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: ID сервиса не указан."))
-					continue
-				}
-				service, err := database.GetService(db, serviceID)
-				if err != nil {
-					log.Printf("Error getting service '%s': %v", userStatus.PendingServiceID, err)
-					bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении данных сервиса."))
-					continue
-				}
-				functionality.HandleUserInput(db, bot, update, service)
-			} else {
-				userID := update.Message.From.ID
-				userName := update.Message.From.UserName
-				balance := 0.0
-				isSubscribed, err := functionality.CheckSubscriptionStatus(bot, db, channelID, int64(userID), balance, userName)
-				if err != nil {
-					log.Printf("Error checking subscription status: %v", err)
-					continue
-				}
-
-				if isSubscribed {
-					if update.Message.Text == "💳 Баланс" {
-						functionality.HandleBalanceCommand(bot, update.Message.Chat.ID, db)
-					} else if update.Message.Text == "🤝 Партнерам" {
-						functionality.ShowReferralStats(bot, db, update.Message.Chat.ID)
-					} else if update.Message.Text == "✍️Сделать заказ" {
-						functionality.SendPromotionMessage(bot, update.Message.Chat.ID, db)
-					} else if update.Message.Text == "🧩Профиль" {
-						functionality.HandleProfileCommand(bot, update.Message.Chat.ID, db)
-					} else if update.Message.Text == "⚡️Сайт (-55%)" {
-						functionality.SendSiteMessage(bot, update.Message.Chat.ID)
-					} else {
-						functionality.SendPromotionMessage(bot, update.Message.Chat.ID, db)
-					}
-				} else {
-					functionality.SendSubscriptionMessage(bot, update.Message.Chat.ID)
-				}
+		case update, ok := <-updates:
+			if !ok {
+				return errors.New("updates channel closed")
 			}
+			submitUpdate(bot, pool, update)
 		}
 	}
 }
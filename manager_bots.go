@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+const botsPerPage = 5
+
+func getOwnedBot(db *gorm.DB, ownerID, botID int64) (models.BotOwners, error) {
+	var botOwner models.BotOwners
+	err := db.Where("id = ? AND user_id = ? AND token != ''", botID, ownerID).First(&botOwner).Error
+	return botOwner, err
+}
+
+func HandleBotList(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, page int) {
+	chatID := callbackQuery.Message.Chat.ID
+	messageID := callbackQuery.Message.MessageID
+	tr := ownerTr(callbackQuery.From)
+
+	if page < 1 {
+		page = 1
+	}
+
+	var total int64
+	if err := db.Model(&models.BotOwners{}).Where("user_id = ? AND token != ''", chatID).Count(&total).Error; err != nil {
+		log.Printf("Ошибка при получении количества ботов пользователя: %v", err)
+		return
+	}
+	totalPages := int((total + botsPerPage - 1) / botsPerPage)
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	var bots []models.BotOwners
+	err := db.Where("user_id = ? AND token != ''", chatID).Order("id").
+		Offset((page - 1) * botsPerPage).Limit(botsPerPage).Find(&bots).Error
+	if err != nil {
+		log.Printf("Ошибка при получении ботов пользователя: %v", err)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, b := range bots {
+		state := "🔴"
+		if IsBotRunning(b.ID) {
+			state = "🟢"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s @%s", state, b.BotName), fmt.Sprintf("botinfo:%d", b.ID)),
+		))
+	}
+
+	var paginationRow []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		paginationRow = append(paginationRow, tgbotapi.NewInlineKeyboardButtonData("⬅️", fmt.Sprintf("mybots:%d", page-1)))
+	}
+	paginationRow = append(paginationRow, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page, totalPages), "page_info"))
+	if page < totalPages {
+		paginationRow = append(paginationRow, tgbotapi.NewInlineKeyboardButtonData("➡️", fmt.Sprintf("mybots:%d", page+1)))
+	}
+	rows = append(rows, paginationRow)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), "bots")))
+
+	messageText := tr.T("manager.list_title")
+	if total == 0 {
+		messageText = tr.T("manager.no_bots")
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, messageText)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	editMsg.ReplyMarkup = &keyboard
+	sender.Send(bot, editMsg)
+}
+
+func HandleBotInfo(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	sendBotCard(bot, chatID, callbackQuery.Message.MessageID, db, botOwner, tr)
+}
+
+func sendBotCard(bot telegram.Client, chatID int64, messageID int, db *gorm.DB, botOwner models.BotOwners, tr i18n.Localizer) {
+	var userCount int64
+	db.Model(&models.UserState{}).Where("bot_id = ?", botOwner.ID).Count(&userCount)
+
+	// Доход владельца: наценка с заказов за вычетом возвратов, как "Ваш доход" в статистике
+	var earnings float64
+	db.Model(&models.OwnerEarnings{}).Where("bot_id = ? AND type IN ?", botOwner.ID, []string{"order", "refund"}).
+		Select("COALESCE(SUM(amount), 0)").Scan(&earnings)
+
+	running := botOwner.Running
+	state := DescribeBotState(tr, botOwner.ID)
+	if botOwner.TokenRevoked {
+		state = tr.T("manager.state.revoked")
+	}
+
+	messageText := tr.T("manager.card", i18n.Args{
+		"bot":      botOwner.BotName,
+		"state":    state,
+		"users":    userCount,
+		"earnings": fmt.Sprintf("%.2f", earnings),
+		"balance":  fmt.Sprintf("%.2f", botOwner.Balance),
+		"markup":   fmt.Sprintf("%.2f", botOwner.Markup),
+	})
+
+	toggleButton := tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.start"), fmt.Sprintf("botstart:%d", botOwner.ID))
+	if running {
+		toggleButton = tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.stop"), fmt.Sprintf("botstop:%d", botOwner.ID))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			toggleButton,
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.token"), fmt.Sprintf("bottoken:%d", botOwner.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.stats"), fmt.Sprintf("botstats:%d:today", botOwner.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.branding"), fmt.Sprintf("botbrand:%d", botOwner.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.delete"), fmt.Sprintf("botdelete:%d", botOwner.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), "mybots:1"),
+		),
+	)
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, messageText)
+	editMsg.ReplyMarkup = &keyboard
+	sender.Send(bot, editMsg)
+}
+
+// action - действие из callback вида "<action>:<id>", например botstop
+func HandleBotAction(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, action string, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	messageID := callbackQuery.Message.MessageID
+	tr := ownerTr(callbackQuery.From)
+
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+
+	switch action {
+	case "botstop":
+		if err := db.Model(&models.BotOwners{}).Where("id = ?", botID).Update("running", false).Error; err != nil {
+			log.Printf("Не удалось обновить статус бота %d: %v", botID, err)
+			return
+		}
+		StopBot(botID)
+		botOwner.Running = false
+		sendBotCard(bot, chatID, messageID, db, botOwner, tr)
+	case "botstart":
+		if botOwner.TokenRevoked {
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot.revoked", i18n.Args{"bot": botOwner.BotName})))
+			return
+		}
+		if err := db.Model(&models.BotOwners{}).Where("id = ?", botID).Update("running", true).Error; err != nil {
+			log.Printf("Не удалось обновить статус бота %d: %v", botID, err)
+			return
+		}
+		StartBot(botOwner)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot.starting", i18n.Args{"bot": botOwner.BotName})))
+	case "bottoken":
+		setManagerState(chatID, StateAwaitingToken, BotStatus{BotID: botID})
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.prompt_bot", i18n.Args{"bot": botOwner.BotName})))
+	case "botdelete":
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.bot.delete_yes"), fmt.Sprintf("botdeleteconfirm:%d", botID)),
+				tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.bot.delete_no"), fmt.Sprintf("botinfo:%d", botID)),
+			),
+		)
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, tr.T("manager.bot.delete_confirm", i18n.Args{"bot": botOwner.BotName, "balance": fmt.Sprintf("%.2f", botOwner.Balance)}))
+		editMsg.ReplyMarkup = &keyboard
+		sender.Send(bot, editMsg)
+	case "botdeleteconfirm":
+		if err := db.Model(&models.BotOwners{}).Where("id = ?", botID).Update("running", false).Error; err != nil {
+			log.Printf("Не удалось обновить статус бота %d: %v", botID, err)
+			return
+		}
+		StopBot(botID)
+		if err := db.Where("id = ?", botID).Delete(&models.BotOwners{}).Error; err != nil {
+			log.Printf("Не удалось удалить бота %d: %v", botID, err)
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot.delete_error")))
+			return
+		}
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, tr.T("manager.bot.deleted", i18n.Args{"bot": botOwner.BotName}))
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.my_bots"), "mybots:1")),
+		)
+		editMsg.ReplyMarkup = &keyboard
+		sender.Send(bot, editMsg)
+	}
+}
+
+// Замена токена существующего бота после проверки в HandleTokenInput
+func replaceBotToken(bot telegram.Client, db *gorm.DB, chatID, botID int64, token string, botInfo tgbotapi.User, tr i18n.Localizer) {
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	if botInfo.UserName != botOwner.BotName {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.wrong_bot", i18n.Args{"actual": botInfo.UserName, "bot": botOwner.BotName})))
+		return
+	}
+
+	if err := database.SetBotToken(&botOwner, token); err != nil {
+		log.Printf("Ошибка при шифровании токена бота %d: %v", botID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.save_error")))
+		return
+	}
+	updates := map[string]interface{}{"token": botOwner.Token, "token_hash": botOwner.TokenHash, "running": true, "token_revoked": false}
+	if err := db.Model(&models.BotOwners{}).Where("id = ?", botID).Updates(updates).Error; err != nil {
+		log.Printf("Не удалось обновить токен бота %d: %v", botID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.save_error")))
+		return
+	}
+	clearManagerState(chatID)
+	RestartBot(botOwner)
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.replaced", i18n.Args{"bot": botOwner.BotName})))
+}
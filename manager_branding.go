@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+const resetBrandingValue = "-"
+
+// TitleID и PromptID - ключи текстов в каталогах i18n
+type brandingField struct {
+	Key      string
+	TitleID  string
+	PromptID string
+}
+
+var brandingFields = []brandingField{
+	{"greeting", "manager.branding.greeting", "manager.branding.greeting_prompt"},
+	{"support", "manager.branding.support", "manager.branding.support_prompt"},
+	{"site_url", "manager.branding.site_url", "manager.branding.site_url_prompt"},
+	{"site_text", "manager.branding.site_text", "manager.branding.site_text_prompt"},
+	{"channel", "manager.branding.channel", "manager.branding.channel_prompt"},
+	{"menu", "manager.branding.menu", "manager.branding.menu_prompt"},
+}
+
+func findBrandingField(key string) (brandingField, bool) {
+	for _, field := range brandingFields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return brandingField{}, false
+}
+
+func HandleBrandingMenu(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	sendBrandingCard(bot, chatID, callbackQuery.Message.MessageID, db, botID, botOwner.BotName, tr)
+}
+
+func sendBrandingCard(bot telegram.Client, chatID int64, messageID int, db *gorm.DB, botID int64, botName string, tr i18n.Localizer) {
+	branding := functionality.GetBranding(db, botID, tr)
+
+	site := tr.T("manager.branding.site_hidden")
+	if branding.ShowSite {
+		site = branding.SiteURL
+	}
+	channel := tr.T("manager.branding.channel_none")
+	if branding.ChannelID != 0 {
+		channel = fmt.Sprintf("%d %s", branding.ChannelID, branding.ChannelLink)
+	}
+	menu := strings.Join([]string{branding.Menu.Balance, branding.Menu.Order, branding.Menu.Referral, branding.Menu.Profile, branding.Menu.Site}, " | ")
+	messageText := tr.T("manager.branding.card", i18n.Args{
+		"bot":      botName,
+		"greeting": branding.Greeting,
+		"support":  branding.SupportLink,
+		"site":     site,
+		"channel":  channel,
+		"menu":     menu,
+		"reset":    resetBrandingValue,
+	})
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(brandingFields); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T(brandingFields[i].TitleID), fmt.Sprintf("brandset:%d:%s", botID, brandingFields[i].Key)),
+		)
+		if i+1 < len(brandingFields) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(tr.T(brandingFields[i+1].TitleID), fmt.Sprintf("brandset:%d:%s", botID, brandingFields[i+1].Key)))
+		}
+		rows = append(rows, row)
+	}
+	siteToggle := tr.T("manager.branding.hide_site")
+	if !branding.ShowSite {
+		siteToggle = tr.T("manager.branding.show_site")
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(siteToggle, fmt.Sprintf("brandsite:%d", botID))),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), fmt.Sprintf("botinfo:%d", botID))),
+	)
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, messageText)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	editMsg.ReplyMarkup = &keyboard
+	editMsg.DisableWebPagePreview = true
+	sender.Send(bot, editMsg)
+}
+
+// Показ или скрытие кнопки сайта
+func HandleBrandingSiteToggle(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	settings, err := database.GetBotSettings(db, botID)
+	if err != nil {
+		log.Printf("Ошибка при получении настроек бота %d: %v", botID, err)
+		return
+	}
+	if err := database.UpdateBotSettings(db, botID, map[string]interface{}{"hide_site": !settings.HideSite}); err != nil {
+		log.Printf("Ошибка при сохранении настроек бота %d: %v", botID, err)
+		return
+	}
+	sendBrandingCard(bot, chatID, callbackQuery.Message.MessageID, db, botID, botOwner.BotName, tr)
+}
+
+// Запрос нового значения поля оформления
+func HandleBrandingField(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64, key string) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	if _, err := getOwnedBot(db, chatID, botID); err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	field, ok := findBrandingField(key)
+	if !ok {
+		return
+	}
+	setManagerState(chatID, StateAwaitingBrandValue, BotStatus{BotID: botID, Field: field.Key})
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T(field.PromptID)))
+}
+
+func HandleBrandingInput(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	_, status := getManagerState(chatID)
+	botOwner, err := getOwnedBot(db, chatID, status.BotID)
+	if err != nil {
+		clearManagerState(chatID)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+
+	values, errText := parseBrandingValue(tr, status.Field, strings.TrimSpace(update.Message.Text))
+	if errText != "" {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, errText))
+		return
+	}
+	if err := database.UpdateBotSettings(db, status.BotID, values); err != nil {
+		log.Printf("Ошибка при сохранении настроек бота %d: %v", status.BotID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.branding.save_error")))
+		return
+	}
+	clearManagerState(chatID)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.card.branding"), fmt.Sprintf("botbrand:%d", status.BotID))),
+	)
+	msg := tgbotapi.NewMessage(chatID, tr.T("manager.branding.saved", i18n.Args{"bot": botOwner.BotName}))
+	msg.ReplyMarkup = keyboard
+	sender.Send(bot, msg)
+}
+
+func isValidLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// Значения колонок bot_settings для введенного текста или текст ошибки
+func parseBrandingValue(tr i18n.Localizer, field, text string) (map[string]interface{}, string) {
+	reset := text == resetBrandingValue
+
+	switch field {
+	case "greeting":
+		if reset {
+			text = ""
+		}
+		return map[string]interface{}{"greeting": text}, ""
+	case "support", "site_url":
+		column := map[string]string{"support": "support_link", "site_url": "site_url"}[field]
+		if reset {
+			return map[string]interface{}{column: ""}, ""
+		}
+		if !isValidLink(text) {
+			return nil, tr.T("manager.branding.bad_link")
+		}
+		return map[string]interface{}{column: text}, ""
+	case "site_text":
+		if reset {
+			text = ""
+		}
+		return map[string]interface{}{"site_text": text}, ""
+	case "channel":
+		if reset {
+			return map[string]interface{}{"channel_id": 0, "channel_link": "", "channel_disabled": false}, ""
+		}
+		// Подписку можно отключить словом на любом языке
+		for _, off := range i18n.All("manager.branding.channel_off") {
+			if strings.EqualFold(text, off) {
+				return map[string]interface{}{"channel_id": 0, "channel_link": "", "channel_disabled": true}, ""
+			}
+		}
+		args := strings.Fields(text)
+		if len(args) != 2 {
+			return nil, tr.T("manager.branding.channel_format")
+		}
+		channelID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || channelID == 0 {
+			return nil, tr.T("manager.branding.bad_channel_id")
+		}
+		if !isValidLink(args[1]) {
+			return nil, tr.T("manager.branding.bad_channel_link")
+		}
+		return map[string]interface{}{"channel_id": channelID, "channel_link": args[1], "channel_disabled": false}, ""
+	case "menu":
+		columns := []string{"menu_balance", "menu_order", "menu_referral", "menu_profile", "menu_site"}
+		values := make(map[string]interface{}, len(columns))
+		if reset {
+			for _, column := range columns {
+				values[column] = ""
+			}
+			return values, ""
+		}
+		lines := strings.Split(text, "\n")
+		if len(lines) != len(columns) {
+			return nil, tr.N("manager.menu_lines", len(columns))
+		}
+		seen := make(map[string]bool, len(lines))
+		for i, line := range lines {
+			label := strings.TrimSpace(line)
+			if label == "" || strings.HasPrefix(label, "/") || len([]rune(label)) > 32 || seen[label] {
+				return nil, tr.T("manager.branding.menu_labels")
+			}
+			seen[label] = true
+			values[columns[i]] = label
+		}
+		return values, ""
+	}
+	return nil, tr.T("manager.branding.unknown")
+}
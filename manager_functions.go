@@ -3,36 +3,77 @@ package main
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
 	"gorm.io/gorm"
 )
 
+const (
+	StateAwaitingToken               fsm.State = "awaiting_token"
+	StateAwaitingWithdrawAmount      fsm.State = "awaiting_withdraw_amount"
+	StateAwaitingWithdrawDestination fsm.State = "awaiting_withdraw_destination"
+	StateAwaitingBrandValue          fsm.State = "awaiting_brand_value"
+)
+
+// Данные диалога в боте-менеджере
 type BotStatus struct {
-	ChatID       int64
-	CurrentState string
+	BotID  int64   `json:"bot_id,omitempty"`
+	Amount float64 `json:"amount,omitempty"`
+	Field  string  `json:"field,omitempty"`
+}
+
+// Максимальное число ботов у одного владельца
+const maxBotsPerOwner = 10
+
+// Диалоги бота-менеджера хранятся с bot_id = 0, у клиентских ботов bot_id - ID из bot_owners
+const managerConversationBotID = 0
+
+func setManagerState(chatID int64, state fsm.State, status BotStatus) {
+	if err := fsm.Default.Set(managerConversationBotID, chatID, state, status); err != nil {
+		log.Printf("Ошибка при сохранении диалога %d: %v", chatID, err)
+	}
 }
 
-var BotStatuses map[int64]*BotStatus = make(map[int64]*BotStatus)
+func getManagerState(chatID int64) (fsm.State, BotStatus) {
+	state, status, _ := fsm.Get[BotStatus](fsm.Default, managerConversationBotID, chatID)
+	return state, status
+}
 
-var NewTokensChannel chan string = make(chan string, 100)
+func clearManagerState(chatID int64) bool {
+	return fsm.Default.Clear(managerConversationBotID, chatID)
+}
 
-func CreateQuickReplyMarkup() tgbotapi.ReplyKeyboardMarkup {
-	MenuButton := tgbotapi.NewKeyboardButton("Меню")
+// Бот-менеджер не хранит язык владельца и отвечает на языке его клиента Telegram
+func ownerTr(from *tgbotapi.User) i18n.Localizer {
+	if from == nil {
+		return i18n.For(i18n.Default)
+	}
+	return i18n.For(i18n.Match(from.LanguageCode))
+}
+
+func CreateQuickReplyMarkup(tr i18n.Localizer) tgbotapi.ReplyKeyboardMarkup {
+	MenuButton := tgbotapi.NewKeyboardButton(tr.T("manager.menu"))
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(MenuButton),
 	)
 }
 
-func WelcomeMessage(bot *tgbotapi.BotAPI, chatID int64) {
-	replyKeyboard := CreateQuickReplyMarkup()
-	replyMsg := tgbotapi.NewMessage(chatID, "👋Добро пожаловать!")
+func WelcomeMessage(bot telegram.Client, chatID int64, tr i18n.Localizer) {
+	replyKeyboard := CreateQuickReplyMarkup(tr)
+	replyMsg := tgbotapi.NewMessage(chatID, tr.T("manager.welcome"))
 	replyMsg.ReplyMarkup = replyKeyboard
-	bot.Send(replyMsg)
+	sender.Send(bot, replyMsg)
 }
 
-func SendMenuButton(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
+func SendMenuButton(bot telegram.Client, chatID int64, db *gorm.DB, tr i18n.Localizer) {
 	var botOwners models.BotOwners
 	err := db.Where("user_id = ?", chatID).First(&botOwners).Error
 	if err != nil {
@@ -40,22 +81,22 @@ func SendMenuButton(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
 		return
 	}
 
-	greetingMessage := fmt.Sprintf("👋Привет %v! Я бот-менеджер, который поможет тебе создать своего бота по накрутке", botOwners.UserName)
-	messageText := greetingMessage
+	messageText := tr.T("manager.greeting", i18n.Args{"name": botOwners.UserName})
 	inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💼Боты", "bots"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.bots_button"), "bots"),
 		),
 	)
 	msg := tgbotapi.NewMessage(chatID, messageText)
 	msg.ReplyMarkup = inlineKeyboard
-	bot.Send(msg)
+	sender.Send(bot, msg)
 
 }
 
-func HandleBackButton(bot *tgbotapi.BotAPI, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB) {
+func HandleBackButton(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB) {
 	chatID := callbackQuery.Message.Chat.ID
 	messageID := callbackQuery.Message.MessageID
+	tr := ownerTr(callbackQuery.From)
 
 	var botOwners models.BotOwners
 	err := db.Where("user_id = ?", chatID).First(&botOwners).Error
@@ -64,22 +105,22 @@ func HandleBackButton(bot *tgbotapi.BotAPI, callbackQuery *tgbotapi.CallbackQuer
 		return
 	}
 
-	greetingMessage := fmt.Sprintf("👋Привет %v! Я бот-менеджер, который поможет тебе создать своего бота по накрутке", botOwners.UserName)
-	messageText := greetingMessage
+	messageText := tr.T("manager.greeting", i18n.Args{"name": botOwners.UserName})
 	inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💼Боты", "bots"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.bots_button"), "bots"),
 		),
 	)
 
 	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, messageText)
 	editMsg.ReplyMarkup = &inlineKeyboard
-	bot.Send(editMsg)
+	sender.Send(bot, editMsg)
 }
 
-func HandleBotStart(bot *tgbotapi.BotAPI, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB) {
+func HandleBotStart(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB) {
 	chatID := callbackQuery.Message.Chat.ID
 	messageID := callbackQuery.Message.MessageID
+	tr := ownerTr(callbackQuery.From)
 
 	var botCount int64
 	err := db.Model(&models.BotOwners{}).Where("user_id = ? AND token != ''", chatID).Count(&botCount).Error
@@ -88,79 +129,123 @@ func HandleBotStart(bot *tgbotapi.BotAPI, callbackQuery *tgbotapi.CallbackQuery,
 		return
 	}
 
-	messageText := fmt.Sprintf("👋 Тут ты можешь добавлять, настраивать и удалять своих ботов! \n\nТекущее количество твоих ботов: %d из 10.", botCount)
+	messageText := tr.T("manager.bots", i18n.Args{"count": botCount, "limit": maxBotsPerOwner})
 
 	inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🆕Создать бота", "create_bot"),
-			tgbotapi.NewInlineKeyboardButtonData("⬅️Назад", "backtomenu"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.create_bot"), "create_bot"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), "backtomenu"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.my_bots"), "mybots:1"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.withdraw"), "withdraw"),
 		),
 	)
 
 	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, messageText)
 	editMsg.ReplyMarkup = &inlineKeyboard
-	bot.Send(editMsg)
+	sender.Send(bot, editMsg)
 }
-func InitiateTokenInput(bot *tgbotapi.BotAPI, chatID int64) {
-	BotStatuses[chatID] = &BotStatus{
-		ChatID:       chatID,
-		CurrentState: "awaiting_token",
-	}
-	msg := tgbotapi.NewMessage(chatID, "❗️Ответьте на это сообщение токеном бота")
-	bot.Send(msg)
+func InitiateTokenInput(bot telegram.Client, chatID int64, tr i18n.Localizer) {
+	setManagerState(chatID, StateAwaitingToken, BotStatus{})
+	msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.prompt"))
+	sender.Send(bot, msg)
 }
 
-func HandleTokenInput(bot *tgbotapi.BotAPI, update tgbotapi.Update, db *gorm.DB) {
+func HandleTokenInput(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
 	chatID := update.Message.Chat.ID
 	token := update.Message.Text
 	userName := update.Message.From.UserName
+	tr := ownerTr(update.Message.From)
 
-	if status, exists := BotStatuses[chatID]; exists && status.CurrentState == "awaiting_token" {
+	if state, status := getManagerState(chatID); state == StateAwaitingToken {
 		var botCount int64
-		db.Model(&models.BotOwners{}).Where("user_id = ?", chatID).Count(&botCount)
+		db.Model(&models.BotOwners{}).Where("user_id = ? AND token != ''", chatID).Count(&botCount)
 
-		if botCount >= 10 {
-			msg := tgbotapi.NewMessage(chatID, "Превышен лимит количества ботов. Максимум можно иметь 10 ботов.")
-			bot.Send(msg)
+		if status.BotID == 0 && botCount >= maxBotsPerOwner {
+			msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.limit", i18n.Args{"limit": maxBotsPerOwner}))
+			sender.Send(bot, msg)
 			return
 		}
 
 		tempBot, err := tgbotapi.NewBotAPI(token)
 		if err != nil {
-			log.Printf("Ошибка при инициализации бота с токеном %s: %v", token, err)
-			msg := tgbotapi.NewMessage(chatID, "Неверный токен бота. Пожалуйста, проверьте и попробуйте снова.")
-			bot.Send(msg)
+			log.Printf("Ошибка при проверке токена, введенного пользователем %d: %v", chatID, database.RedactTokens(err.Error()))
+			msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.invalid"))
+			sender.Send(bot, msg)
 			return
 		}
 
 		botInfo, err := tempBot.GetMe()
 		if err != nil {
 			log.Printf("Ошибка при получении информации о боте: %v", err)
-			msg := tgbotapi.NewMessage(chatID, "Не удалось получить информацию о боте. Пожалуйста, проверьте токен.")
-			bot.Send(msg)
+			msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.info_error"))
+			sender.Send(bot, msg)
+			return
+		}
+
+		if _, err := database.GetBotByToken(db, token); err == nil {
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.duplicate")))
+			return
+		}
+
+		if status.BotID != 0 {
+			replaceBotToken(bot, db, chatID, status.BotID, token, botInfo, tr)
 			return
 		}
 
 		userBotStatus := models.BotOwners{
 			UserID:   chatID,
 			UserName: userName,
-			Token:    token,
 			Running:  true,
 			BotName:  botInfo.UserName,
 			Balance:  0,
 		}
+		if err := database.SetBotToken(&userBotStatus, token); err != nil {
+			log.Printf("Ошибка при шифровании токена бота @%s: %v", botInfo.UserName, err)
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.token.save_error")))
+			return
+		}
 
 		if err := db.Create(&userBotStatus).Error; err != nil {
-			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка при сохранении токена: %v", err))
-			bot.Send(msg)
+			msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.save_failed", i18n.Args{"error": err}))
+			sender.Send(bot, msg)
 			return
 		}
 
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Бот @%s был успешно добавлен и включен.", botInfo.UserName))
-		bot.Send(msg)
-		go func() {
-			NewTokensChannel <- token
-		}()
-		delete(BotStatuses, chatID)
+		msg := tgbotapi.NewMessage(chatID, tr.T("manager.token.added", i18n.Args{"bot": botInfo.UserName}))
+		sender.Send(bot, msg)
+		StartBot(userBotStatus)
+		clearManagerState(chatID)
+	}
+}
+
+func HandleMarkupCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 3 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.markup.usage")))
+		return
+	}
+
+	botName := strings.TrimPrefix(args[1], "@")
+	markup, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || markup < 0 || markup > 1000 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.markup.invalid")))
+		return
 	}
+
+	result := db.Model(&models.BotOwners{}).Where("user_id = ? AND bot_name = ? AND token != ''", chatID, botName).Update("markup", markup)
+	if result.Error != nil {
+		log.Printf("Ошибка при обновлении наценки бота @%s: %v", botName, result.Error)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.markup.error")))
+		return
+	}
+	if result.RowsAffected == 0 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.markup.set", i18n.Args{"bot": botName, "markup": fmt.Sprintf("%.2f", markup)})))
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+// /linkservice [id услуги] [поставщик] [id у поставщика] [приоритет]
+func HandleLinkServiceCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	args := strings.Fields(update.Message.Text)
+	usage := tr.T("manager.links.link_usage", i18n.Args{"default": config.Current().DefaultProviderPriority})
+	if len(args) != 4 && len(args) != 5 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+
+	service, ok := catalogServiceArg(bot, chatID, db, tr, args[1])
+	if !ok {
+		return
+	}
+	provider, ok := api.Get(args[2])
+	if !ok || provider == api.Default {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.unknown_provider", i18n.Args{"providers": strings.Join(api.Names(), ", ")})))
+		return
+	}
+	priority := 1
+	if len(args) == 5 {
+		p, err := strconv.Atoi(args[4])
+		if err != nil {
+			sender.Send(bot, tgbotapi.NewMessage(chatID, usage))
+			return
+		}
+		priority = p
+	}
+
+	providerService, found, err := api.FindService(provider, args[3])
+	if err != nil {
+		log.Printf("Error fetching catalog from %s: %v", provider.Name(), err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.error")))
+		return
+	}
+	if !found {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.provider_service_not_found")))
+		return
+	}
+
+	link := models.ServiceLink{
+		CatalogServiceID:  service.ID,
+		Provider:          provider.Name(),
+		ProviderServiceID: args[3],
+		Priority:          priority,
+		Rate:              providerService.Rate,
+		Min:               providerService.Min,
+		Max:               providerService.Max,
+	}
+	if err := database.SaveServiceLink(db, link); err != nil {
+		log.Printf("Error saving link of service %d: %v", service.ID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.error")))
+		return
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.linked", i18n.Args{
+		"service":  service.Name,
+		"provider": provider.Name(),
+		"name":     providerService.Name,
+	})))
+}
+
+// /unlinkservice [id услуги] [поставщик]
+func HandleUnlinkServiceCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 3 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.unlink_usage")))
+		return
+	}
+	service, ok := catalogServiceArg(bot, chatID, db, tr, args[1])
+	if !ok {
+		return
+	}
+	deleted, err := database.DeleteServiceLink(db, service.ID, args[2])
+	if err != nil {
+		log.Printf("Error deleting link of service %d: %v", service.ID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.error")))
+		return
+	}
+	if !deleted {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.not_linked")))
+		return
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.unlinked", i18n.Args{"service": service.Name, "provider": args[2]})))
+}
+
+// /servicelinks [id услуги] - поставщики услуги со ставками и лимитами
+func HandleServiceLinksCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	args := strings.Fields(update.Message.Text)
+	if len(args) != 2 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.list_usage")))
+		return
+	}
+	service, ok := catalogServiceArg(bot, chatID, db, tr, args[1])
+	if !ok {
+		return
+	}
+	links, err := database.GetServiceLinks(db, service.ID)
+	if err != nil {
+		log.Printf("Error getting links of service %d: %v", service.ID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.error")))
+		return
+	}
+
+	lines := []string{
+		tr.T("manager.links.title", i18n.Args{"service": service.Name}),
+		tr.T("manager.links.item", i18n.Args{
+			"provider": api.Default.Name(),
+			"id":       service.ID,
+			"rate":     money(service.Rate),
+			"min":      service.Min,
+			"max":      service.Max,
+			"priority": config.Current().DefaultProviderPriority,
+		}),
+	}
+	for _, link := range links {
+		key := "manager.links.item"
+		if link.Unavailable {
+			key = "manager.links.item_unavailable"
+		}
+		lines = append(lines, tr.T(key, i18n.Args{
+			"provider": link.Provider,
+			"id":       link.ProviderServiceID,
+			"rate":     money(link.Rate),
+			"min":      link.Min,
+			"max":      link.Max,
+			"priority": link.Priority,
+		}))
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, strings.Join(lines, "\n")))
+}
+
+func catalogServiceArg(bot telegram.Client, chatID int64, db *gorm.DB, tr i18n.Localizer, arg string) (models.Services, bool) {
+	id, err := strconv.Atoi(arg)
+	if err == nil {
+		service, err := database.GetService(db, id)
+		if err == nil {
+			return service, true
+		}
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.links.service_not_found")))
+	return models.Services{}, false
+}
+
+// Изменений в ответе /catalogchanges
+const catalogChangesLimit = 20
+
+// /catalogchanges [id услуги] - последние изменения каталога после синхронизаций
+func HandleCatalogChangesCommand(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	args := strings.Fields(update.Message.Text)
+	serviceID := 0
+	if len(args) > 2 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.catalog_changes.usage")))
+		return
+	}
+	if len(args) == 2 {
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.catalog_changes.usage")))
+			return
+		}
+		serviceID = id
+	}
+
+	changes, err := database.GetCatalogChanges(db, serviceID, catalogChangesLimit)
+	if err != nil {
+		log.Printf("Error getting catalog changes: %v", err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.catalog_changes.error")))
+		return
+	}
+	if len(changes) == 0 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.catalog_changes.empty")))
+		return
+	}
+
+	lines := []string{tr.T("manager.catalog_changes.title")}
+	for _, change := range changes {
+		values := i18n.Args{
+			"date":     change.CreatedAt.Format("02.01 15:04"),
+			"id":       change.ServiceID,
+			"name":     change.Name,
+			"old_rate": money(change.OldRate),
+			"new_rate": money(change.NewRate),
+			"old_min":  change.OldMin,
+			"new_min":  change.NewMin,
+			"old_max":  change.OldMax,
+			"new_max":  change.NewMax,
+		}
+		switch change.Kind {
+		case "added", "removed":
+			lines = append(lines, tr.T("manager.catalog_changes."+change.Kind, values))
+		default:
+			if change.PriceChanged {
+				lines = append(lines, tr.T("manager.catalog_changes.price", values))
+			}
+			if change.LimitsChanged {
+				lines = append(lines, tr.T("manager.catalog_changes.limits", values))
+			}
+		}
+	}
+	sender.Send(bot, tgbotapi.NewMessage(chatID, strings.Join(lines, "\n")))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"github.com/Cekretik/BoostBot/fsm"
+	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/router"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+type callbackHandler func(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB)
+
+func onCallback(db *gorm.DB, h callbackHandler) router.HandlerFunc {
+	return func(c *router.Context) {
+		h(c.Bot, c.Callback(), db)
+	}
+}
+
+// Обработчик кнопки бота владельца, ID бота берется из параметра {id:int} маршрута
+type botCallbackHandler func(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64)
+
+func onBotCallback(db *gorm.DB, h botCallbackHandler) router.HandlerFunc {
+	return func(c *router.Context) {
+		h(c.Bot, c.Callback(), db, c.Params.Int64("id"))
+	}
+}
+
+type updateHandler func(bot telegram.Client, update tgbotapi.Update, db *gorm.DB)
+
+func onUpdate(db *gorm.DB, h updateHandler) router.HandlerFunc {
+	return func(c *router.Context) {
+		h(c.Bot, c.Update, db)
+	}
+}
+
+func isChannelAdmin(c *router.Context) bool {
+	return functionality.IsAdmin(c.Bot, c.UserID())
+}
+
+// Язык отправителя обновления
+func contextTr(c *router.Context) i18n.Localizer {
+	return ownerTr(c.Update.SentFrom())
+}
+
+func managerState(c *router.Context) string {
+	// Состояния ждут текстовый ответ, остальные сообщения обрабатываются как обычно
+	if c.Text() == "" {
+		return ""
+	}
+	return string(fsm.Default.State(managerConversationBotID, c.ChatID()))
+}
+
+func newManagerRouter(db *gorm.DB, botName string) *router.Router {
+	r := router.New()
+	r.Use(router.Recover(), router.Logging(botName), router.AnswerCallback())
+	r.StateResolver(managerState)
+	requireAdmin := router.RequireAdmin(isChannelAdmin, func(c *router.Context) {
+		if c.Callback() != nil {
+			c.Answer(contextTr(c).T("access.denied"))
+			return
+		}
+		c.Reply(contextTr(c).T("access.denied_command"))
+	})
+
+	showMenu := func(c *router.Context) {
+		tr := contextTr(c)
+		WelcomeMessage(c.Bot, c.ChatID(), tr)
+		SendMenuButton(c.Bot, c.ChatID(), db, tr)
+	}
+	r.Command("start", showMenu)
+	r.Command("markup", onUpdate(db, HandleMarkupCommand))
+	// Привязка услуг других поставщиков к каталогу, только для администраторов платформы
+	r.Command("linkservice", onUpdate(db, HandleLinkServiceCommand), requireAdmin)
+	r.Command("unlinkservice", onUpdate(db, HandleUnlinkServiceCommand), requireAdmin)
+	r.Command("servicelinks", onUpdate(db, HandleServiceLinksCommand), requireAdmin)
+	r.Command("catalogchanges", onUpdate(db, HandleCatalogChangesCommand), requireAdmin)
+	// Кнопки меню и отмены принимаются на любом языке каталога
+	for _, text := range i18n.All("manager.menu") {
+		r.Text(text, func(c *router.Context) {
+			SendMenuButton(c.Bot, c.ChatID(), db, contextTr(c))
+		})
+	}
+	for _, text := range i18n.All("common.cancel") {
+		r.Text(text, func(c *router.Context) {
+			if !clearManagerState(c.ChatID()) {
+				showMenu(c)
+				return
+			}
+			tr := contextTr(c)
+			c.Reply(tr.T("manager.cancelled"))
+			SendMenuButton(c.Bot, c.ChatID(), db, tr)
+		})
+	}
+	r.Fallback(func(c *router.Context) {
+		if c.Text() == "" {
+			showMenu(c)
+		}
+	})
+
+	r.State(string(StateAwaitingToken), onUpdate(db, HandleTokenInput))
+	r.State(string(StateAwaitingWithdrawAmount), onUpdate(db, HandleWithdrawAmountInput))
+	r.State(string(StateAwaitingWithdrawDestination), onUpdate(db, HandleWithdrawDestinationInput))
+	r.State(string(StateAwaitingBrandValue), onUpdate(db, HandleBrandingInput))
+
+	r.Callback("create_bot", func(c *router.Context) {
+		InitiateTokenInput(c.Bot, c.ChatID(), contextTr(c))
+	})
+	r.Callback("bots", onCallback(db, HandleBotStart))
+	r.Callback("backtomenu", onCallback(db, HandleBackButton))
+	r.Callback("withdraw", onCallback(db, HandleWithdrawButton))
+	r.Callback("page_info", func(c *router.Context) {})
+
+	r.Callback("mybots:{page:int}", func(c *router.Context) {
+		HandleBotList(c.Bot, c.Callback(), db, c.Params.Int("page"))
+	})
+	r.Callback("botinfo:{id:int}", onBotCallback(db, HandleBotInfo))
+	for _, action := range []string{"botstop", "botstart", "bottoken", "botdelete", "botdeleteconfirm"} {
+		action := action
+		r.Callback(action+":{id:int}", func(c *router.Context) {
+			HandleBotAction(c.Bot, c.Callback(), db, action, c.Params.Int64("id"))
+		})
+	}
+	r.Callback("botstats:{id:int}:{period}", func(c *router.Context) {
+		HandleBotStats(c.Bot, c.Callback(), db, c.Params.Int64("id"), c.Params.String("period"))
+	})
+	r.Callback("botstatscsv:{id:int}", onBotCallback(db, HandleBotStatsCSV))
+	r.Callback("botbrand:{id:int}", onBotCallback(db, HandleBrandingMenu))
+	r.Callback("brandset:{id:int}:{field}", func(c *router.Context) {
+		HandleBrandingField(c.Bot, c.Callback(), db, c.Params.Int64("id"), c.Params.String("field"))
+	})
+	r.Callback("brandsite:{id:int}", onBotCallback(db, HandleBrandingSiteToggle))
+	r.Callback("withdraw:{id:int}", onBotCallback(db, InitiateWithdrawal))
+
+	decideWithdrawal := func(approve bool) router.HandlerFunc {
+		return func(c *router.Context) {
+			HandleWithdrawalDecision(c.Bot, c.Callback(), db, uint(c.Params.Int64("id")), approve)
+			c.MarkAnswered()
+		}
+	}
+	r.Callback("withdraw_approve:{id:int}", decideWithdrawal(true), requireAdmin)
+	r.Callback("withdraw_reject:{id:int}", decideWithdrawal(false), requireAdmin)
+
+	return r
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+type statsPeriod struct {
+	Key string
+}
+
+var statsPeriods = []statsPeriod{{"today"}, {"7d"}, {"30d"}, {"all"}}
+
+// Название периода на языке владельца
+func (p statsPeriod) Title(tr i18n.Localizer) string {
+	return tr.T("manager.stats." + p.Key)
+}
+
+func statsPeriodStart(key string, now time.Time) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch key {
+	case "today":
+		return today
+	case "7d":
+		return today.AddDate(0, 0, -6)
+	case "30d":
+		return today.AddDate(0, 0, -29)
+	default:
+		return time.Time{}
+	}
+}
+
+func findStatsPeriod(key string) statsPeriod {
+	for _, period := range statsPeriods {
+		if period.Key == key {
+			return period
+		}
+	}
+	return statsPeriods[0]
+}
+
+// Статистика бота за период, неизвестный период заменяется первым
+func HandleBotStats(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64, periodKey string) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+
+	period := findStatsPeriod(periodKey)
+	stats, err := database.GetBotStats(db, botID, statsPeriodStart(period.Key, time.Now()))
+	if err != nil {
+		log.Printf("Ошибка при подсчете статистики бота %d: %v", botID, err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.stats.error")))
+		return
+	}
+
+	var periodRow []tgbotapi.InlineKeyboardButton
+	for _, p := range statsPeriods {
+		title := p.Title(tr)
+		if p.Key == period.Key {
+			title = "• " + title
+		}
+		periodRow = append(periodRow, tgbotapi.NewInlineKeyboardButtonData(title, fmt.Sprintf("botstats:%d:%s", botID, p.Key)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		periodRow,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.stats.csv"), fmt.Sprintf("botstatscsv:%d", botID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("common.back"), fmt.Sprintf("botinfo:%d", botID)),
+		),
+	)
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, callbackQuery.Message.MessageID, formatBotStats(tr, botOwner.BotName, period.Title(tr), stats))
+	editMsg.ReplyMarkup = &keyboard
+	sender.Send(bot, editMsg)
+}
+
+// Выгрузка статистики в CSV
+func HandleBotStatsCSV(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+	botOwner, err := getOwnedBot(db, chatID, botID)
+	if err != nil {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	sendBotStatsCSV(bot, chatID, db, botID, botOwner.BotName, tr)
+}
+
+func formatBotStats(tr i18n.Localizer, botName, periodTitle string, stats database.BotStats) string {
+	line := func(id string, args i18n.Args) string { return tr.T(id, args) + "\n" }
+
+	var sb strings.Builder
+	sb.WriteString(line("manager.stats.title", i18n.Args{"bot": botName, "period": periodTitle}) + "\n")
+	sb.WriteString(line("manager.stats.new_users", i18n.Args{"count": stats.NewUsers}))
+	sb.WriteString(line("manager.stats.active_users", i18n.Args{"count": stats.ActiveUsers}) + "\n")
+
+	sb.WriteString(tr.T("manager.stats.deposits") + "\n")
+	if len(stats.Deposits) == 0 {
+		sb.WriteString("  " + tr.T("manager.stats.none") + "\n")
+	}
+	for _, deposit := range stats.Deposits {
+		sb.WriteString("  " + line("manager.stats.group", i18n.Args{"key": deposit.Key, "count": deposit.Count, "amount": money(deposit.Amount)}))
+	}
+
+	sb.WriteString("\n" + tr.T("manager.stats.orders") + "\n")
+	if len(stats.Orders) == 0 {
+		sb.WriteString("  " + tr.T("manager.stats.none") + "\n")
+	}
+	for _, order := range stats.Orders {
+		status := functionality.TranslateOrderStatus(tr, order.Key)
+		sb.WriteString("  " + line("manager.stats.group", i18n.Args{"key": status, "count": order.Count, "amount": money(order.Amount)}))
+	}
+
+	sb.WriteString("\n" + line("manager.stats.gross", i18n.Args{"amount": money(stats.GrossRevenue)}))
+	sb.WriteString(line("manager.stats.margin", i18n.Args{"amount": money(stats.OwnerMargin)}))
+
+	if len(stats.TopServices) > 0 {
+		sb.WriteString("\n" + tr.T("manager.stats.top") + "\n")
+		for i, service := range stats.TopServices {
+			sb.WriteString("  " + tr.N("manager.stats.top_service", int(service.Orders), i18n.Args{
+				"place":  i + 1,
+				"name":   service.Name,
+				"amount": money(service.Revenue),
+			}) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Выгрузка статистики за все периоды одним CSV-документом
+func sendBotStatsCSV(bot telegram.Client, chatID int64, db *gorm.DB, botID int64, botName string, tr i18n.Localizer) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"period", "metric", "key", "count", "amount"})
+
+	money := func(amount float64) string { return strconv.FormatFloat(amount, 'f', 2, 64) }
+	count := func(n int64) string { return strconv.FormatInt(n, 10) }
+
+	now := time.Now()
+	for _, period := range statsPeriods {
+		stats, err := database.GetBotStats(db, botID, statsPeriodStart(period.Key, now))
+		if err != nil {
+			log.Printf("Ошибка при подсчете статистики бота %d: %v", botID, err)
+			sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.stats.error")))
+			return
+		}
+
+		w.Write([]string{period.Key, "new_users", "", count(stats.NewUsers), ""})
+		w.Write([]string{period.Key, "active_users", "", count(stats.ActiveUsers), ""})
+		for _, deposit := range stats.Deposits {
+			w.Write([]string{period.Key, "deposits", deposit.Key, count(deposit.Count), money(deposit.Amount)})
+		}
+		for _, order := range stats.Orders {
+			w.Write([]string{period.Key, "orders", order.Key, count(order.Count), money(order.Amount)})
+		}
+		w.Write([]string{period.Key, "gross_revenue", "", "", money(stats.GrossRevenue)})
+		w.Write([]string{period.Key, "owner_margin", "", "", money(stats.OwnerMargin)})
+		for _, service := range stats.TopServices {
+			w.Write([]string{period.Key, "top_service", service.ServiceID + " " + service.Name, count(service.Orders), money(service.Revenue)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Printf("Ошибка при формировании CSV статистики бота %d: %v", botID, err)
+		return
+	}
+
+	file := tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("stats_%s_%s.csv", botName, now.Format("2006-01-02")),
+		Bytes: buf.Bytes(),
+	}
+	doc := tgbotapi.NewDocument(chatID, file)
+	doc.Caption = tr.T("manager.stats.caption", i18n.Args{"bot": botName})
+	if _, err := sender.Send(bot, doc); err != nil {
+		log.Printf("Ошибка при отправке CSV статистики бота %d: %v", botID, err)
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"log"
 
 	"github.com/Cekretik/BoostBot/models"
-	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"github.com/Cekretik/BoostBot/telegram"
 	"gorm.io/gorm"
 )
 
-func UpdateUserStatus(bot *tgbotapi.BotAPI, db *gorm.DB, userID int64, userName string) error {
+func UpdateUserStatus(bot telegram.Client, db *gorm.DB, userID int64, userName string) error {
 	var botOwners models.BotOwners
 	result := db.Where("user_id = ?", userID).First(&botOwners)
 
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+const minWithdrawalAmount = 1.0
+
+// Сумма в долларах для текстов вида ${amount}
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func HandleWithdrawButton(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+
+	var bots []models.BotOwners
+	if err := db.Where("user_id = ? AND token != ''", chatID).Find(&bots).Error; err != nil {
+		log.Printf("Ошибка при получении ботов пользователя: %v", err)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, b := range bots {
+		buttonText := fmt.Sprintf("@%s — $%.2f", b.BotName, b.Balance)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("withdraw:%d", b.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.no_bots")))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.choose"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sender.Send(bot, msg)
+}
+
+func InitiateWithdrawal(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, botID int64) {
+	chatID := callbackQuery.Message.Chat.ID
+	tr := ownerTr(callbackQuery.From)
+
+	botOwner, err := database.GetBotByID(db, botID)
+	if err != nil || botOwner.UserID != chatID {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.bot_not_found")))
+		return
+	}
+	if botOwner.Balance < minWithdrawalAmount {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.minimum", i18n.Args{"min": money(minWithdrawalAmount), "balance": money(botOwner.Balance)})))
+		return
+	}
+
+	setManagerState(chatID, StateAwaitingWithdrawAmount, BotStatus{BotID: botID})
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.available", i18n.Args{"balance": money(botOwner.Balance)})))
+}
+
+func HandleWithdrawAmountInput(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	_, status := getManagerState(chatID)
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(update.Message.Text, ",", "."), 64)
+	if err != nil || amount < minWithdrawalAmount {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.bad_amount", i18n.Args{"min": money(minWithdrawalAmount)})))
+		return
+	}
+
+	botOwner, err := database.GetBotByID(db, status.BotID)
+	if err != nil {
+		log.Printf("Ошибка при получении бота %d: %v", status.BotID, err)
+		clearManagerState(chatID)
+		return
+	}
+	if amount > botOwner.Balance {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.exceeds", i18n.Args{"balance": money(botOwner.Balance)})))
+		return
+	}
+
+	status.Amount = amount
+	setManagerState(chatID, StateAwaitingWithdrawDestination, status)
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.destination")))
+}
+
+func HandleWithdrawDestinationInput(bot telegram.Client, update tgbotapi.Update, db *gorm.DB) {
+	chatID := update.Message.Chat.ID
+	tr := ownerTr(update.Message.From)
+	_, status := getManagerState(chatID)
+	destination := strings.TrimSpace(update.Message.Text)
+	if destination == "" {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.destination")))
+		return
+	}
+
+	withdrawal, err := database.CreateWithdrawal(db, status.BotID, chatID, status.Amount, destination)
+	clearManagerState(chatID)
+	if errors.Is(err, database.ErrInsufficientBalance) {
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.insufficient")))
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при создании заявки на вывод: %v", err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.create_error")))
+		return
+	}
+
+	sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("manager.withdraw.created", i18n.Args{"id": withdrawal.ID, "amount": money(withdrawal.Amount)})))
+	notifyAdminsAboutWithdrawal(bot, db, withdrawal)
+}
+
+func notifyAdminsAboutWithdrawal(bot telegram.Client, db *gorm.DB, withdrawal models.Withdrawals) {
+	channelID := settings.Telegram.ChannelID
+	admins, err := bot.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+		ChatConfig: tgbotapi.ChatConfig{ChatID: channelID},
+	})
+	if err != nil {
+		log.Printf("Ошибка при получении списка администраторов: %v", err)
+		return
+	}
+
+	// Заявки администраторам и решения по ним владельцу отправляются на языке по умолчанию
+	tr := i18n.For(i18n.Default)
+	botOwner, _ := database.GetBotByID(db, withdrawal.BotID)
+	messageText := tr.T("manager.withdraw.request", i18n.Args{
+		"id":          withdrawal.ID,
+		"owner":       botOwner.UserName,
+		"owner_id":    withdrawal.UserID,
+		"bot":         botOwner.BotName,
+		"amount":      money(withdrawal.Amount),
+		"destination": withdrawal.Destination,
+	})
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.withdraw.approve"), fmt.Sprintf("withdraw_approve:%d", withdrawal.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("manager.withdraw.reject"), fmt.Sprintf("withdraw_reject:%d", withdrawal.ID)),
+		),
+	)
+
+	for _, admin := range admins {
+		if admin.User.IsBot {
+			continue
+		}
+		msg := tgbotapi.NewMessage(admin.User.ID, messageText)
+		msg.ReplyMarkup = keyboard
+		sender.Send(bot, msg)
+	}
+}
+
+// Права администратора проверяются маршрутизатором
+func HandleWithdrawalDecision(bot telegram.Client, callbackQuery *tgbotapi.CallbackQuery, db *gorm.DB, withdrawalID uint, approve bool) {
+	adminID := callbackQuery.From.ID
+	adminTr := ownerTr(callbackQuery.From)
+
+	withdrawal, err := database.ResolveWithdrawal(db, withdrawalID, adminID, approve)
+	if errors.Is(err, database.ErrWithdrawalResolved) {
+		sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, adminTr.T("manager.withdraw.resolved")))
+		return
+	}
+	if err != nil {
+		log.Printf("Ошибка при обработке заявки на вывод #%d: %v", withdrawalID, err)
+		sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, adminTr.T("manager.withdraw.error")))
+		return
+	}
+
+	tr := i18n.For(i18n.Default)
+	args := i18n.Args{"id": withdrawal.ID, "amount": money(withdrawal.Amount), "destination": withdrawal.Destination, "admin": adminID}
+	resultText := tr.T("manager.withdraw.approved", args)
+	ownerText := tr.T("manager.withdraw.owner_approved", args)
+	if !approve {
+		resultText = tr.T("manager.withdraw.rejected", args)
+		ownerText = tr.T("manager.withdraw.owner_rejected", args)
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(callbackQuery.Message.Chat.ID, callbackQuery.Message.MessageID,
+		callbackQuery.Message.Text+"\n\n"+resultText)
+	sender.Send(bot, editMsg)
+	sender.Send(bot, tgbotapi.NewMessage(withdrawal.UserID, ownerText))
+	sender.Request(bot, tgbotapi.NewCallback(callbackQuery.ID, ""))
+}
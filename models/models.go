@@ -1,20 +1,29 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type UserState struct {
 	gorm.Model
-	UserID               int64      `gorm:"column:user_id" json:"user_id"`
-	UserName             string     `gorm:"column:user_name" json:"user_name"`
-	Subscribed           bool       `gorm:"column:subscribed" json:"subscribed"`
-	PreviouslySubscribed bool       `gorm:"column:previously_subscribed" json:"previously_subscribed"`
-	IsNewUser            bool       `gorm:"column:is_new_user" json:"is_new_user"`
-	ChannelID            int64      `gorm:"column:channel_id" json:"channel_id"`
-	Balance              float64    `gorm:"column:balance" json:"balance"`
-	Currency             string     `gorm:"column:currency" json:"currency"`
-	Favorites            []Services `gorm:"many2many:user_favorites;"`
+	BotID                int64   `gorm:"column:bot_id;index" json:"bot_id"`
+	UserID               int64   `gorm:"column:user_id" json:"user_id"`
+	UserName             string  `gorm:"column:user_name" json:"user_name"`
+	Subscribed           bool    `gorm:"column:subscribed" json:"subscribed"`
+	PreviouslySubscribed bool    `gorm:"column:previously_subscribed" json:"previously_subscribed"`
+	IsNewUser            bool    `gorm:"column:is_new_user" json:"is_new_user"`
+	ChannelID            int64   `gorm:"column:channel_id" json:"channel_id"`
+	Balance              float64 `gorm:"column:balance" json:"balance"`
+	Currency             string  `gorm:"column:currency" json:"currency"`
+	// Язык текстов бота, пустой - язык по умолчанию
+	Language string `gorm:"column:language" json:"language"`
+	// Бот заблокирован пользователем, рассылки его пропускают до следующего сообщения
+	Unreachable bool `gorm:"column:unreachable" json:"unreachable"`
+	// Не присылать уведомления об изменении цен и наличия избранных услуг
+	FavoriteAlertsMuted bool       `gorm:"column:favorite_alerts_muted" json:"favorite_alerts_muted"`
+	Favorites           []Services `gorm:"many2many:user_favorites;"`
 }
 type Category struct {
 	gorm.Model
@@ -45,6 +54,40 @@ type Services struct {
 	Users      []UserState `gorm:"many2many:user_favorites;"`
 }
 
+// Изменение услуги каталога, найденное синхронизацией. Одна запись на услугу за запуск
+type CatalogChange struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"column:created_at;index"`
+	ServiceID int       `gorm:"column:service_id;index"`
+	Name      string    `gorm:"column:name"`
+	// added, removed или updated
+	Kind          string  `gorm:"column:kind;index"`
+	PriceChanged  bool    `gorm:"column:price_changed"`
+	LimitsChanged bool    `gorm:"column:limits_changed"`
+	OldRate       float64 `gorm:"column:old_rate"`
+	NewRate       float64 `gorm:"column:new_rate"`
+	OldMin        int     `gorm:"column:old_min"`
+	NewMin        int     `gorm:"column:new_min"`
+	OldMax        int     `gorm:"column:old_max"`
+	NewMax        int     `gorm:"column:new_max"`
+}
+
+// Услуга другого поставщика, привязанная к услуге каталога. Ставка и лимиты
+// обновляются при синхронизации каталога
+type ServiceLink struct {
+	gorm.Model
+	CatalogServiceID  int    `gorm:"column:catalog_service_id;index"`
+	Provider          string `gorm:"column:provider"`
+	ProviderServiceID string `gorm:"column:provider_service_id"`
+	// Меньше - раньше при SMM_ROUTING=preferred, у основного поставщика SMM_DEFAULT_PRIORITY
+	Priority int     `gorm:"column:priority"`
+	Rate     float64 `gorm:"column:rate"`
+	Min      int     `gorm:"column:min"`
+	Max      int     `gorm:"column:max"`
+	// Поставщик больше не предлагает услугу
+	Unavailable bool `gorm:"column:unavailable"`
+}
+
 // Struct for POST orders
 type Order struct {
 	ID           int    `json:"id"`
@@ -80,8 +123,11 @@ type ServiceDetails struct {
 
 type UserOrders struct {
 	gorm.Model
-	ChatID      string  `gorm:"column:user_id" json:"userId"`
+	BotID  int64  `gorm:"column:bot_id;index" json:"botId"`
+	ChatID string `gorm:"column:user_id" json:"userId"`
+	// Номер заказа у поставщика Provider. Пустой поставщик у старых заказов - основной
 	OrderID     int     `gorm:"column:order_id" json:"id"`
+	Provider    string  `gorm:"column:provider;index" json:"provider"`
 	ServiceID   string  `gorm:"column:service_id" json:"serviceId"`
 	Cost        float64 `gorm:"column:cost" json:"cost"`
 	ServiceType string  `gorm:"column:service_type" json:"serviceType"`
@@ -91,6 +137,7 @@ type UserOrders struct {
 	Charge      float64 `gorm:"column:charge" json:"charge"`
 	StartCount  int     `gorm:"column:start_count" json:"startCount"`
 	Remains     int     `gorm:"column:remains" json:"remains"`
+	OwnerMargin float64 `gorm:"column:owner_margin" json:"-"`
 }
 
 type RefundedOrder struct {
@@ -98,16 +145,21 @@ type RefundedOrder struct {
 }
 
 type Payments struct {
+	BotID   int64   `gorm:"column:bot_id;index" json:"bot_id"`
 	ChatID  int     `gorm:"column:user_id" json:"userId"`
 	OrderID string  `gorm:"column:order_id" json:"order_id"`
 	Amount  float64 `gorm:"column:amount" json:"amount"`
 	Url     string  `gorm:"column:url" json:"url"`
 	Status  string  `gorm:"column:status" json:"status"`
 	Type    string  `gorm:"column:type" json:"type"`
+	// Время обновления статуса считается временем зачисления оплаченного платежа
+	CreatedAt time.Time `gorm:"column:created_at" json:"-"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"-"`
 }
 
 type Referral struct {
 	gorm.Model
+	BotID        int64   `gorm:"column:bot_id;index"`
 	ReferrerID   int64   `gorm:"column:referrer_id"`
 	ReferredID   int64   `gorm:"column:referred_id"`
 	AmountEarned float64 `gorm:"column:amount_earned"`
@@ -122,6 +174,7 @@ type PromoCode struct {
 }
 
 type UsedPromoCode struct {
+	BotID     int64  `gorm:"column:bot_id;index"`
 	UserID    int64  `gorm:"column:user_id"`
 	PromoCode string `gorm:"column:promo_code"`
 	Used      bool   `gorm:"column:used"`
@@ -129,11 +182,80 @@ type UsedPromoCode struct {
 
 type BotOwners struct {
 	gorm.Model
-	ID       int64   `gorm:"column:id" json:"id"`
-	UserName string  `gorm:"column:user_name" json:"user_name"`
-	UserID   int64   `gorm:"primaryKey column:user_id"`
-	Token    string  `gorm:"column:token" json:"token"`
-	Running  bool    `gorm:"column:running" json:"running"`
-	BotName  string  `gorm:"column:bot_name" json:"bot_name"`
-	Balance  float64 `gorm:"column:balance" json:"balance"`
+	ID       int64  `gorm:"column:id" json:"id"`
+	UserName string `gorm:"column:user_name" json:"user_name"`
+	UserID   int64  `gorm:"primaryKey column:user_id"`
+	Token    string `gorm:"column:token" json:"-"`
+	// SHA-256 открытого токена для поиска, сам токен хранится зашифрованным
+	TokenHash string  `gorm:"column:token_hash;index" json:"-"`
+	Running   bool    `gorm:"column:running" json:"running"`
+	BotName   string  `gorm:"column:bot_name" json:"bot_name"`
+	Balance   float64 `gorm:"column:balance" json:"balance"`
+	Markup    float64 `gorm:"column:markup" json:"markup"`
+	// Секрет пути и заголовка X-Telegram-Bot-Api-Secret-Token для вебхука
+	WebhookSecret string `gorm:"column:webhook_secret" json:"-"`
+	// Telegram отклонил токен, бот ждет замены токена владельцем
+	TokenRevoked bool `gorm:"column:token_revoked" json:"token_revoked"`
+	// Параллельная обработка обновлений, 0 - значения по умолчанию из окружения
+	Workers          int `gorm:"column:workers" json:"workers"`
+	UpdateQueueLimit int `gorm:"column:update_queue_limit" json:"update_queue_limit"`
+}
+
+// Начисления и списания с баланса владельца бота
+type OwnerEarnings struct {
+	gorm.Model
+	BotID   int64   `gorm:"column:bot_id;index"`
+	OrderID int     `gorm:"column:order_id"`
+	Amount  float64 `gorm:"column:amount"`
+	Type    string  `gorm:"column:type"`
+}
+
+// Заявка владельца бота на вывод средств
+type Withdrawals struct {
+	gorm.Model
+	BotID       int64      `gorm:"column:bot_id;index"`
+	UserID      int64      `gorm:"column:user_id;index"`
+	Amount      float64    `gorm:"column:amount"`
+	Destination string     `gorm:"column:destination"`
+	Status      string     `gorm:"column:status"`
+	ReviewedBy  int64      `gorm:"column:reviewed_by"`
+	ReviewedAt  *time.Time `gorm:"column:reviewed_at"`
+}
+
+// Оформление клона. Пустые поля заменяются значениями по умолчанию
+type BotSettings struct {
+	gorm.Model
+	BotID           int64  `gorm:"column:bot_id;uniqueIndex"`
+	Greeting        string `gorm:"column:greeting"`
+	SupportLink     string `gorm:"column:support_link"`
+	SiteText        string `gorm:"column:site_text"`
+	SiteURL         string `gorm:"column:site_url"`
+	HideSite        bool   `gorm:"column:hide_site"`
+	ChannelID       int64  `gorm:"column:channel_id"`
+	ChannelLink     string `gorm:"column:channel_link"`
+	ChannelDisabled bool   `gorm:"column:channel_disabled"`
+	MenuBalance     string `gorm:"column:menu_balance"`
+	MenuOrder       string `gorm:"column:menu_order"`
+	MenuReferral    string `gorm:"column:menu_referral"`
+	MenuProfile     string `gorm:"column:menu_profile"`
+	MenuSite        string `gorm:"column:menu_site"`
+}
+
+// Активный диалог пользователя с ботом, см. пакет fsm
+type Conversation struct {
+	BotID     int64     `gorm:"column:bot_id;primaryKey;autoIncrement:false"`
+	ChatID    int64     `gorm:"column:chat_id;primaryKey;autoIncrement:false"`
+	State     string    `gorm:"column:state"`
+	Payload   string    `gorm:"column:payload"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// Данные кнопки, которые не поместились в 64 байта callback_data, см. пакет callback
+type CallbackPayload struct {
+	ID        uint64    `gorm:"column:id;primaryKey"`
+	Hash      string    `gorm:"column:hash;uniqueIndex"`
+	Data      []byte    `gorm:"column:data"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index"`
+	CreatedAt time.Time `gorm:"column:created_at"`
 }
@@ -8,11 +8,9 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/Cekretik/BoostBot/database"
 	"github.com/Cekretik/BoostBot/models"
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 )
 
@@ -23,15 +21,8 @@ var (
 	SecretKey2     string
 )
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	MerchantID = os.Getenv("AAIO_SHOPID")
-	SecretKey1 = os.Getenv("AAIO_KEY1")
-	SecretKey2 = os.Getenv("AAIO_KEY2")
-}
+// Валюты, в которых AAIO принимает оплату. Суммы в остальных валютах переводятся в доллары
+var aaioCurrencies = map[string]bool{"RUB": true, "USD": true, "EUR": true, "UAH": true}
 
 type Payment struct {
 	ID     uint
@@ -107,7 +98,7 @@ func handleAAIONotification(db *gorm.DB, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := database.UpdateUserBalance(db, int64(payment.ChatID), payment.Amount); err != nil {
+	if err := database.UpdateUserBalance(db, payment.BotID, int64(payment.ChatID), payment.Amount); err != nil {
 		log.Printf("balance %v", payment.Amount)
 		log.Printf("Error updating user balance: %v", err)
 		http.Error(w, "Error updating user balance", http.StatusInternalServerError)
@@ -9,9 +9,6 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
-
-	"github.com/joho/godotenv"
 )
 
 type CryptomusResult struct {
@@ -45,15 +42,6 @@ var merchant string
 var apiKey string
 var urlCallback string
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	urlCallback = os.Getenv("URL_CALLBACK")
-	merchant = os.Getenv("CRYPTOMUS_MERCHANT")
-	apiKey = os.Getenv("CRYPTOMUS_APIKEY")
-}
 func generateSign(data map[string]string, apiKey string) string {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -5,45 +5,53 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
-	"github.com/Cekretik/BoostBot/api"
+	"github.com/Cekretik/BoostBot/config"
+	"github.com/Cekretik/BoostBot/currency"
 	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/fsm"
 	"github.com/Cekretik/BoostBot/functionality"
+	"github.com/Cekretik/BoostBot/i18n"
 	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	"github.com/Cekretik/BoostBot/webhook"
 	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
-	"github.com/joho/godotenv"
 	"gorm.io/gorm"
 )
 
 var port string
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	port = os.Getenv("PORT")
+func Configure(cfg config.Payment) {
+	port = cfg.Port
+	urlCallback = cfg.URLCallback
+	merchant = cfg.CryptomusMerchant
+	apiKey = cfg.CryptomusAPIKey
+	MerchantID = cfg.AAIOShopID
+	SecretKey1 = cfg.AAIOKey1
+	SecretKey2 = cfg.AAIOKey2
 }
 
-type UserPaymentStatus struct {
-	ChatID          int64
-	CurrentState    string
-	ReplenishAmount float64
-	OrderID         string
-	PaymentStatus   string
+const (
+	StatePaymentAwaitingSystem     fsm.State = "payment:awaitingPaymentSystem"
+	StatePaymentAwaitingAmount     fsm.State = "payment:awaitingAmount"
+	StatePaymentAwaitingAmountAAIO fsm.State = "payment:awaitingAmountAAIO"
+)
+
+// Данные пополнения баланса
+type PaymentConversation struct {
+	OrderID string `json:"order_id,omitempty"`
 }
 
 type CreatePaymentRequest struct {
+	BotID    int64   `json:"bot_id"`
 	ChatID   int64   `json:"chat_id"`
 	Amount   float64 `json:"amount"`
 	Currency string  `json:"currency"`
 }
 
-var UserPaymentStatuses map[int64]*UserPaymentStatus = make(map[int64]*UserPaymentStatus)
-
 type CryptomusWebhookData struct {
 	Type              string `json:"type"`
 	UUID              string `json:"uuid"`
@@ -71,17 +79,17 @@ type CryptomusWebhookData struct {
 	Sign string `json:"sign"`
 }
 
-func HandleReplenishCommand(bot *tgbotapi.BotAPI, chatID int64) {
-	userPaymentStatus := updateUserStatus(chatID)
-	userPaymentStatus.CurrentState = "awaitingPaymentSystem"
+func HandleReplenishCommand(bot telegram.Client, db *gorm.DB, botID, chatID int64) {
+	fsm.Default.Set(botID, chatID, StatePaymentAwaitingSystem, PaymentConversation{})
 
-	msgText := ("Выберите платежную систему")
+	tr := functionality.Tr(db, botID, chatID)
+	msgText := tr.T("payment.choose_system")
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("СБП|RUB", "AAIO_SBP"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("payment.sbp"), "AAIO_SBP"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("RU Карта|RUB", "AAIO_RU"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("payment.ru_card"), "AAIO_RU"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("USDT", "cryptomus_USDT"),
@@ -93,154 +101,119 @@ func HandleReplenishCommand(bot *tgbotapi.BotAPI, chatID int64) {
 			tgbotapi.NewInlineKeyboardButtonData("MATIC", "cryptomus_MATIC"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Другая Крипта", "cryptomus_OTHER"),
-		),
-	)
-	cancelKeyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Отмена"),
+			tgbotapi.NewInlineKeyboardButtonData(tr.T("payment.other_crypto"), "cryptomus_OTHER"),
 		),
 	)
 	msg := tgbotapi.NewMessage(chatID, msgText)
-	msg.ReplyMarkup = cancelKeyboard
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sender.Send(bot, msg)
 }
 
-func HandleCryptomusButton(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
-	userPaymentStatus := updateUserStatus(chatID)
-	userPaymentStatus.CurrentState = "awaitingAmount"
-	log.Printf("chuba %v", userPaymentStatus)
-	userPaymentStatus.OrderID = createOrderID(chatID, time.Now().Unix())
+func HandleCryptomusButton(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	fsm.Default.Set(botID, chatID, StatePaymentAwaitingAmount, PaymentConversation{
+		OrderID: createOrderID(botID, chatID, time.Now().Unix()),
+	})
 
+	tr := functionality.Tr(db, botID, chatID)
 	var user models.UserState
-	if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
-		bot.Send(tgbotapi.NewMessage(chatID, "Произошла ошибка."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("common.error")))
 		return
 	}
 
-	msgText := "Введите желаемую сумму в долларах."
-	if user.Currency == "RUB" {
-		msgText = "Введите желаемую сумму в рублях."
-	}
-
-	cancelKeyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Отмена"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, msgText)
-	msg.ReplyMarkup = cancelKeyboard
-	bot.Send(msg)
+	msg := tgbotapi.NewMessage(chatID, tr.T("payment.enter_amount."+user.Currency))
+	msg.ReplyMarkup = functionality.CancelKeyboard(tr)
+	sender.Send(bot, msg)
 }
 
-func HandleAAIOButton(bot *tgbotapi.BotAPI, chatID int64, db *gorm.DB) {
-	userPaymentStatus := updateUserStatus(chatID)
-	userPaymentStatus.CurrentState = "awaitingAmountAAIO"
-	orderID := createOrderID(chatID, time.Now().Unix())
-	userPaymentStatus.OrderID = orderID
+func HandleAAIOButton(bot telegram.Client, chatID int64, db *gorm.DB, botID int64) {
+	fsm.Default.Set(botID, chatID, StatePaymentAwaitingAmountAAIO, PaymentConversation{
+		OrderID: createOrderID(botID, chatID, time.Now().Unix()),
+	})
 
+	tr := functionality.Tr(db, botID, chatID)
 	var user models.UserState
-	if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
+	if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
 		log.Printf("Error fetching user state: %v", err)
-		bot.Send(tgbotapi.NewMessage(chatID, "Произошла ошибка."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("common.error")))
 		return
 	}
 
-	msgText := "Введите желаемую сумму в долларах."
-	if user.Currency == "RUB" {
-		msgText = "Введите желаемую сумму в рублях."
-	}
-
-	cancelKeyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Отмена"),
-		),
-	)
-
-	msg := tgbotapi.NewMessage(chatID, msgText)
-	msg.ReplyMarkup = cancelKeyboard
-	bot.Send(msg)
-	UserPaymentStatuses[chatID] = userPaymentStatus
+	msg := tgbotapi.NewMessage(chatID, tr.T("payment.enter_amount."+user.Currency))
+	msg.ReplyMarkup = functionality.CancelKeyboard(tr)
+	sender.Send(bot, msg)
 }
-func HandlePaymentInput(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, amountText string) {
-	userPaymentStatus := updateUserStatus(chatID)
-
-	if userPaymentStatus.CurrentState == "awaitingAmount" {
+func HandlePaymentInput(db *gorm.DB, botID int64, bot telegram.Client, chatID int64, amountText string) {
+	state, conv, ok := fsm.Get[PaymentConversation](fsm.Default, botID, chatID)
+	if ok && state == StatePaymentAwaitingAmount {
 		var user models.UserState
-		if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
+		if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
 			log.Printf("Error fetching user state: %v", err)
 			return
 		}
 
 		amount, err := strconv.ParseFloat(amountText, 64)
 		if err != nil || amount <= 0 {
-			msg := tgbotapi.NewMessage(chatID, "Введите корректную сумму.")
-			UserPaymentStatuses[chatID] = userPaymentStatus
-			cancelKeyboard := tgbotapi.NewReplyKeyboard(
-				tgbotapi.NewKeyboardButtonRow(
-					tgbotapi.NewKeyboardButton("Отмена"),
-				),
-			)
-			msg.ReplyMarkup = cancelKeyboard
-			bot.Send(msg)
+			tr := functionality.Tr(db, botID, chatID)
+			msg := tgbotapi.NewMessage(chatID, tr.T("payment.bad_amount"))
+			msg.ReplyMarkup = functionality.CancelKeyboard(tr)
+			sender.Send(bot, msg)
 			return
 		}
 
-		if user.Currency == "RUB" {
-			rate := api.GetCurrentCurrencyRate()
-			amount = functionality.ConvertAmount(amount, rate, false)
+		// Cryptomus принимает сумму в долларах
+		amount, err = currency.ToUSD(amount, currency.Normalize(user.Currency))
+		if err != nil {
+			log.Printf("Error converting payment amount: %v", err)
+			sender.Send(bot, tgbotapi.NewMessage(chatID, functionality.Tr(db, botID, chatID).T("payment.create_error")))
+			return
 		}
 
-		UserPaymentStatuses[chatID] = userPaymentStatus
-		CreateAndSendPaymentLink(db, bot, chatID, amount, userPaymentStatus.OrderID, time.Now().Unix())
+		CreateAndSendPaymentLink(db, botID, bot, chatID, amount, conv.OrderID, time.Now().Unix())
 	}
 }
 
-func HandlePaymentInputAAIO(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, amountText string) {
-	userPaymentStatus := updateUserStatus(chatID)
-	if userPaymentStatus.CurrentState == "awaitingAmountAAIO" {
+func HandlePaymentInputAAIO(db *gorm.DB, botID int64, bot telegram.Client, chatID int64, amountText string) {
+	state, conv, ok := fsm.Get[PaymentConversation](fsm.Default, botID, chatID)
+	if ok && state == StatePaymentAwaitingAmountAAIO {
 		var user models.UserState
-		if err := db.Where("user_id = ?", chatID).First(&user).Error; err != nil {
+		if err := db.Where("bot_id = ? AND user_id = ?", botID, chatID).First(&user).Error; err != nil {
 			log.Printf("Error fetching user state: %v", err)
 			return
 		}
 
 		amount, err := strconv.ParseFloat(amountText, 64)
 		if err != nil || amount <= 0 {
-			msg := tgbotapi.NewMessage(chatID, "Введите корректную сумму.")
-			UserPaymentStatuses[chatID] = userPaymentStatus
-			cancelKeyboard := tgbotapi.NewReplyKeyboard(
-				tgbotapi.NewKeyboardButtonRow(
-					tgbotapi.NewKeyboardButton("Отмена"),
-				),
-			)
-			msg.ReplyMarkup = cancelKeyboard
-			bot.Send(msg)
+			tr := functionality.Tr(db, botID, chatID)
+			msg := tgbotapi.NewMessage(chatID, tr.T("payment.bad_amount"))
+			msg.ReplyMarkup = functionality.CancelKeyboard(tr)
+			sender.Send(bot, msg)
 			return
 		}
-		originalAmount := amount
-		currency := "USD"
-		if user.Currency == "RUB" {
-			currency = "RUB"
-		} else {
-			amount = originalAmount
+		code := currency.Normalize(user.Currency)
+		if !aaioCurrencies[code] {
+			amount, err = currency.ToUSD(amount, code)
+			if err != nil {
+				log.Printf("Error converting payment amount: %v", err)
+				sender.Send(bot, tgbotapi.NewMessage(chatID, functionality.Tr(db, botID, chatID).T("payment.create_error")))
+				return
+			}
+			code = currency.USD
 		}
-		createAndSendPaymentLinkAAIO(db, bot, chatID, amount, userPaymentStatus.OrderID, time.Now().Unix(), currency)
-		userPaymentStatus.CurrentState = ""
-		UserPaymentStatuses[chatID] = userPaymentStatus
+		createAndSendPaymentLinkAAIO(db, botID, bot, chatID, amount, conv.OrderID, time.Now().Unix(), code)
 	}
 }
-func CreateAndSendPaymentLink(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, amount float64, orderID string, timestamp int64) {
+func CreateAndSendPaymentLink(db *gorm.DB, botID int64, bot telegram.Client, chatID int64, amount float64, orderID string, timestamp int64) {
+	tr := functionality.Tr(db, botID, chatID)
 	paymentResponse, err := CreatePayment(fmt.Sprintf("%.4f", amount), "USD", orderID)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при создании платежа."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("payment.create_error")))
 		return
 	}
 
 	newPayment := models.Payments{
+		BotID:   botID,
 		ChatID:  int(chatID),
 		OrderID: orderID,
 		Amount:  amount,
@@ -251,60 +224,62 @@ func CreateAndSendPaymentLink(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, a
 	db.Create(&newPayment)
 	paymentURL := paymentResponse.Result.PaymentURL
 	if paymentURL == "" {
-		bot.Send(tgbotapi.NewMessage(chatID, "Не удалось получить ссылку на платеж,попробуйте снова."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("payment.no_link")))
 	} else {
 		inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonURL("Оплатить", paymentURL),
-				tgbotapi.NewInlineKeyboardButtonData("🎁Промокод", "promo"),
+				tgbotapi.NewInlineKeyboardButtonURL(tr.T("payment.pay"), paymentURL),
+				functionality.ActionButton(tr.T("balance.promo"), functionality.EnterPromo{}),
 			),
 		)
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Для пополнения на сумму $%.4f нажмите на кнопку оплатить:", amount))
+		msg := tgbotapi.NewMessage(chatID, tr.T("payment.link", i18n.Args{"amount": currency.Format(amount, currency.USD)}))
 		msg.ReplyMarkup = inlineKeyboard
-		bot.Send(msg)
-		delete(UserPaymentStatuses, chatID)
-		functionality.SendStandardKeyboardAfterPayment(bot, chatID)
+		sender.Send(bot, msg)
+		fsm.Default.Clear(botID, chatID)
+		functionality.SendStandardKeyboardAfterPayment(bot, chatID, db, botID)
 	}
 }
-func createAndSendPaymentLinkAAIO(db *gorm.DB, bot *tgbotapi.BotAPI, chatID int64, amount float64, orderID string, timestamp int64, currency string) {
-	originalAmount := amount
-	if currency == "RUB" {
-		rate := api.GetCurrentCurrencyRate()
-		amount = functionality.ConvertAmount(originalAmount, rate, false)
+
+// amount задана в валюте code, на баланс зачисляется ее долларовый эквивалент
+func createAndSendPaymentLinkAAIO(db *gorm.DB, botID int64, bot telegram.Client, chatID int64, amount float64, orderID string, timestamp int64, code string) {
+	tr := functionality.Tr(db, botID, chatID)
+	usd, err := currency.ToUSD(amount, code)
+	if err != nil {
+		log.Printf("Error converting payment amount: %v", err)
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("payment.create_error")))
+		return
 	}
 
-	paymentURL, err := CreateAAIOPayment(fmt.Sprintf("%.2f", originalAmount), orderID, currency, "Пополнение баланса", "", "ru")
+	paymentURL, err := CreateAAIOPayment(fmt.Sprintf("%.2f", amount), orderID, code, tr.T("payment.description"), "", tr.Lang())
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при создании платежа."))
+		sender.Send(bot, tgbotapi.NewMessage(chatID, tr.T("payment.create_error")))
 		return
 	}
 
 	newPayment := models.Payments{
+		BotID:   botID,
 		ChatID:  int(chatID),
 		OrderID: orderID,
-		Amount:  amount,
+		Amount:  usd,
 		Url:     paymentURL,
 		Status:  "check",
 		Type:    "aaio",
 	}
 	db.Create(&newPayment)
 
-	paymentMessage := fmt.Sprintf("Для пополнения на сумму $%.4f нажмите на кнопку оплатить:", amount)
-	if currency == "RUB" {
-		paymentMessage = fmt.Sprintf("Для пополнения на сумму %.4f₽ нажмите на кнопку оплатить:", originalAmount)
-	}
+	paymentMessage := tr.T("payment.link", i18n.Args{"amount": currency.Format(amount, code)})
 
 	inlineKeyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("Оплатить", paymentURL),
-			tgbotapi.NewInlineKeyboardButtonData("🎁Промокод", "promo"),
+			tgbotapi.NewInlineKeyboardButtonURL(tr.T("payment.pay"), paymentURL),
+			functionality.ActionButton(tr.T("balance.promo"), functionality.EnterPromo{}),
 		),
 	)
 	msg := tgbotapi.NewMessage(chatID, paymentMessage)
 	msg.ReplyMarkup = inlineKeyboard
-	bot.Send(msg)
-	delete(UserPaymentStatuses, chatID)
-	functionality.SendStandardKeyboardAfterPayment(bot, chatID)
+	sender.Send(bot, msg)
+	fsm.Default.Clear(botID, chatID)
+	functionality.SendStandardKeyboardAfterPayment(bot, chatID, db, botID)
 }
 func handleWebhook(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 	var webhookData CryptomusWebhookData
@@ -327,7 +302,7 @@ func handleWebhook(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 	case "paid":
 		if payment.Status != "paid" {
 			database.UpdatePaymentStatusInDB(db, orderID, "paid")
-			err = database.UpdateUserBalance(db, int64(payment.ChatID), payment.Amount)
+			err = database.UpdateUserBalance(db, payment.BotID, int64(payment.ChatID), payment.Amount)
 			if err != nil {
 				log.Printf("Error updating user balance: %v", err)
 			}
@@ -339,35 +314,13 @@ func handleWebhook(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func updateUserStatus(chatID int64) *UserPaymentStatus {
-	if status, exists := UserPaymentStatuses[chatID]; exists {
-		if IsOrderExpired(status) {
-			status.OrderID = createOrderID(chatID, time.Now().Unix())
-			status.PaymentStatus = "cancel"
-			UserPaymentStatuses[chatID] = status
-		}
-		return status
-	}
-	newUserStatus := &UserPaymentStatus{
-		ChatID:        chatID,
-		OrderID:       createOrderID(chatID, time.Now().Unix()),
-		PaymentStatus: "",
-	}
-	UserPaymentStatuses[chatID] = newUserStatus
-	return newUserStatus
-}
-
-func IsOrderExpired(userStatus *UserPaymentStatus) bool {
-	return userStatus.PaymentStatus == "cancel" || userStatus.PaymentStatus == "fail"
-}
-
 func HandleCreatePayment(w http.ResponseWriter, r *http.Request, db *gorm.DB) {
 	var req CreatePaymentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	orderID := createOrderID(req.ChatID, time.Now().Unix()) // функция для генерации OrderID
+	orderID := createOrderID(req.BotID, req.ChatID, time.Now().Unix()) // функция для генерации OrderID
 	paymentResponse, err := CreatePayment(strconv.FormatFloat(req.Amount, 'f', 2, 64), req.Currency, orderID)
 	if err != nil {
 		http.Error(w, "Failed to create payment", http.StatusInternalServerError)
@@ -375,6 +328,7 @@ func HandleCreatePayment(w http.ResponseWriter, r *http.Request, db *gorm.DB) {
 	}
 
 	newPayment := models.Payments{
+		BotID:   req.BotID,
 		ChatID:  int(req.ChatID),
 		OrderID: orderID,
 		Amount:  req.Amount,
@@ -397,10 +351,10 @@ func HandleCreatePaymentAAIO(w http.ResponseWriter, r *http.Request, db *gorm.DB
 		return
 	}
 
-	orderID := createOrderID(req.ChatID, time.Now().Unix())
+	orderID := createOrderID(req.BotID, req.ChatID, time.Now().Unix())
 
 	amountFormatted := fmt.Sprintf("%.2f", req.Amount)
-	paymentURL, err := CreateAAIOPayment(amountFormatted, orderID, req.Currency, "Пополнение баланса", "", "ru")
+	paymentURL, err := CreateAAIOPayment(amountFormatted, orderID, req.Currency, i18n.For(i18n.Default).T("payment.description"), "", i18n.Default)
 	if err != nil {
 		http.Error(w, "Failed to create payment", http.StatusInternalServerError)
 		return
@@ -408,6 +362,7 @@ func HandleCreatePaymentAAIO(w http.ResponseWriter, r *http.Request, db *gorm.DB
 
 	// Сохранение информации о платеже в БД
 	newPayment := models.Payments{
+		BotID:   req.BotID,
 		ChatID:  int(req.ChatID),
 		OrderID: orderID,
 		Amount:  req.Amount,
@@ -424,11 +379,13 @@ func HandleCreatePaymentAAIO(w http.ResponseWriter, r *http.Request, db *gorm.DB
 	json.NewEncoder(w).Encode(response)
 }
 
-func createOrderID(chatID int64, timestamp int64) string {
-	return fmt.Sprintf("order_%d_%d", chatID, timestamp)
+func createOrderID(botID, chatID int64, timestamp int64) string {
+	return fmt.Sprintf("order_%d_%d_%d", botID, chatID, timestamp)
 }
 
-func StartHTTPServer(db *gorm.DB) {
+// HTTP-сервер уведомлений платежных систем и вебхуков Telegram. Запускается
+// вызывающей стороной через ListenAndServe и останавливается через Shutdown
+func NewHTTPServer(db *gorm.DB) *http.Server {
 	// Существующие обработчики
 	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
 		handleWebhook(db, w, r)
@@ -445,6 +402,7 @@ func StartHTTPServer(db *gorm.DB) {
 		handleAAIONotification(db, w, r)
 	})
 
-	log.Printf("HTTP server started on %v", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	http.Handle(webhook.PathPrefix, webhook.DefaultDispatcher)
+
+	return &http.Server{Addr: port, Handler: http.DefaultServeMux}
 }
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/Cekretik/BoostBot/callback"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Параметры из шаблона callback. Целочисленные параметры проверяются при сопоставлении
+type Params map[string]string
+
+func (p Params) String(name string) string {
+	return p[name]
+}
+
+func (p Params) Int(name string) int {
+	n, _ := strconv.Atoi(p[name])
+	return n
+}
+
+func (p Params) Int64(name string) int64 {
+	n, _ := strconv.ParseInt(p[name], 10, 64)
+	return n
+}
+
+type Context struct {
+	Bot    telegram.Client
+	Update tgbotapi.Update
+	Params Params
+	// Действие кнопки, если callback закодирован пакетом callback
+	Action callback.Action
+	// Состояние диалога, по которому выбран обработчик
+	State string
+	// Описание выбранного маршрута для логов
+	Route string
+
+	answered bool
+}
+
+func (c *Context) Message() *tgbotapi.Message {
+	return c.Update.Message
+}
+
+func (c *Context) Callback() *tgbotapi.CallbackQuery {
+	return c.Update.CallbackQuery
+}
+
+func (c *Context) ChatID() int64 {
+	if c.Update.Message != nil {
+		return c.Update.Message.Chat.ID
+	}
+	if cq := c.Update.CallbackQuery; cq != nil {
+		if cq.Message != nil {
+			return cq.Message.Chat.ID
+		}
+		return cq.From.ID
+	}
+	return 0
+}
+
+// Сообщение с нажатой кнопкой или входящее сообщение
+func (c *Context) MessageID() int {
+	if cq := c.Update.CallbackQuery; cq != nil && cq.Message != nil {
+		return cq.Message.MessageID
+	}
+	if c.Update.Message != nil {
+		return c.Update.Message.MessageID
+	}
+	return 0
+}
+
+func (c *Context) UserID() int64 {
+	if from := c.Update.SentFrom(); from != nil {
+		return from.ID
+	}
+	return 0
+}
+
+func (c *Context) Text() string {
+	if c.Update.Message != nil {
+		return c.Update.Message.Text
+	}
+	return ""
+}
+
+func (c *Context) Reply(text string) {
+	if _, err := sender.Send(c.Bot, tgbotapi.NewMessage(c.ChatID(), text)); err != nil {
+		log.Printf("Error sending message to %d: %v", c.ChatID(), err)
+	}
+}
+
+// Ответ на callback. Повторные вызовы игнорируются
+func (c *Context) Answer(text string) {
+	cq := c.Update.CallbackQuery
+	if cq == nil || c.answered {
+		return
+	}
+	c.answered = true
+	sender.Request(c.Bot, tgbotapi.NewCallback(cq.ID, text))
+}
+
+// Отмечает callback отвеченным, если обработчик ответил на него сам
+func (c *Context) MarkAnswered() {
+	c.answered = true
+}
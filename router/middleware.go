@@ -0,0 +1,70 @@
+package router
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// Перехватывает панику обработчика, чтобы одно обновление не останавливало цикл бота
+func Recover() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Паника при обработке обновления %d (%s): %v\n%s", c.Update.UpdateID, c.Route, r, debug.Stack())
+				}
+			}()
+			next(c)
+		}
+	}
+}
+
+func Logging(botName string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			start := time.Now()
+			next(c)
+			if c.Route == "" {
+				return
+			}
+			log.Printf("@%s: обновление %d от %d -> %s (%s)", botName, c.Update.UpdateID, c.UserID(), c.Route, time.Since(start).Round(time.Millisecond))
+		}
+	}
+}
+
+// Отвечает на callback после обработчика, если тот не ответил сам
+func AnswerCallback() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			next(c)
+			c.Answer("")
+		}
+	}
+}
+
+// Пропускает обновление только администраторам, остальным вызывается deny
+func RequireAdmin(isAdmin func(c *Context) bool, deny HandlerFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if isAdmin(c) {
+				next(c)
+				return
+			}
+			deny(c)
+		}
+	}
+}
+
+// Пропускает обновление только подписчикам, остальным вызывается deny
+func RequireSubscription(isSubscribed func(c *Context) bool, deny HandlerFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			if isSubscribed(c) {
+				next(c)
+				return
+			}
+			deny(c)
+		}
+	}
+}
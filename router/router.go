@@ -0,0 +1,265 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Cekretik/BoostBot/callback"
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+type HandlerFunc func(c *Context)
+
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Возвращает состояние диалога пользователя, пустая строка - диалога нет
+type StateResolver func(c *Context) string
+
+type paramKind int
+
+const (
+	paramString paramKind = iota
+	paramInt
+)
+
+type segment struct {
+	literal string
+	param   string
+	kind    paramKind
+}
+
+type callbackRoute struct {
+	pattern  string
+	segments []segment
+	handler  HandlerFunc
+}
+
+type route struct {
+	name    string
+	handler HandlerFunc
+}
+
+// Маршрутизатор обновлений. Сообщения проверяются в порядке: команда, точный текст,
+// состояние диалога, обработчик по умолчанию. Закодированные callback.Codec данные
+// выбирают обработчик по виду действия, остальные сопоставляются с шаблонами
+// в порядке регистрации
+type Router struct {
+	middleware []Middleware
+	commands   map[string]route
+	texts      map[string]route
+	states     map[string]route
+	actions    map[callback.Kind]route
+	callbacks  []callbackRoute
+	stateOf    StateResolver
+	onMessage  *route
+	onCallback *route
+}
+
+func New() *Router {
+	return &Router{
+		commands: make(map[string]route),
+		texts:    make(map[string]route),
+		states:   make(map[string]route),
+		actions:  make(map[callback.Kind]route),
+	}
+}
+
+// Middleware для всех обновлений, включая не найденные маршруты
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+func chain(h HandlerFunc, mw []Middleware) HandlerFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Команда без слеша, например "start" для /start и /start@bot_name
+func (r *Router) Command(name string, h HandlerFunc, mw ...Middleware) {
+	r.commands[name] = route{name: "/" + name, handler: chain(h, mw)}
+}
+
+func (r *Router) Text(text string, h HandlerFunc, mw ...Middleware) {
+	r.texts[text] = route{name: "text " + text, handler: chain(h, mw)}
+}
+
+func (r *Router) State(state string, h HandlerFunc, mw ...Middleware) {
+	r.states[state] = route{name: "state " + state, handler: chain(h, mw)}
+}
+
+func (r *Router) StateResolver(fn StateResolver) {
+	r.stateOf = fn
+}
+
+// Шаблон callback из сегментов через ":". Сегмент {name} - строковый параметр,
+// {name:int} - целое число. Данные совпадают, только если совпадает число сегментов,
+// поэтому шаблон "buy" не срабатывает на "buyout"
+func (r *Router) Callback(pattern string, h HandlerFunc, mw ...Middleware) {
+	segments, err := compilePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	r.callbacks = append(r.callbacks, callbackRoute{pattern: pattern, segments: segments, handler: chain(h, mw)})
+}
+
+// Обработчик действия того же вида, что и action. Декодированное действие
+// доступно в Context.Action и имеет тот же тип, что и action
+func (r *Router) Action(action callback.Action, h HandlerFunc, mw ...Middleware) {
+	r.actions[action.Kind()] = route{name: fmt.Sprintf("action %T", action), handler: chain(h, mw)}
+}
+
+// Обработчик сообщений, не попавших ни в один маршрут
+func (r *Router) Fallback(h HandlerFunc, mw ...Middleware) {
+	r.onMessage = &route{name: "fallback", handler: chain(h, mw)}
+}
+
+// Обработчик callback, не подходящих ни под один шаблон
+func (r *Router) CallbackFallback(h HandlerFunc, mw ...Middleware) {
+	r.onCallback = &route{name: "callback fallback", handler: chain(h, mw)}
+}
+
+// Делит шаблон по ":" вне фигурных скобок: "botinfo:{id:int}" -> "botinfo", "{id:int}"
+func splitPattern(pattern string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range pattern {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}' && depth > 0:
+			depth--
+		case r == ':' && depth == 0:
+			parts = append(parts, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, pattern[start:])
+}
+
+func compilePattern(pattern string) ([]segment, error) {
+	var segments []segment
+	for _, part := range splitPattern(pattern) {
+		if !strings.HasPrefix(part, "{") {
+			segments = append(segments, segment{literal: part})
+			continue
+		}
+		if !strings.HasSuffix(part, "}") {
+			return nil, fmt.Errorf("router: invalid segment %q in pattern %q", part, pattern)
+		}
+		name, kind, _ := strings.Cut(strings.Trim(part, "{}"), ":")
+		seg := segment{param: name}
+		switch kind {
+		case "":
+			seg.kind = paramString
+		case "int":
+			seg.kind = paramInt
+		default:
+			return nil, fmt.Errorf("router: unknown param type %q in pattern %q", kind, pattern)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("router: empty param name in pattern %q", pattern)
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+func (cr callbackRoute) match(data string) (Params, bool) {
+	parts := strings.Split(data, ":")
+	if len(parts) != len(cr.segments) {
+		return nil, false
+	}
+	var params Params
+	for i, seg := range cr.segments {
+		if seg.param == "" {
+			if parts[i] != seg.literal {
+				return nil, false
+			}
+			continue
+		}
+		if seg.kind == paramInt {
+			if _, err := strconv.ParseInt(parts[i], 10, 64); err != nil {
+				return nil, false
+			}
+		}
+		if params == nil {
+			params = make(Params)
+		}
+		params[seg.param] = parts[i]
+	}
+	return params, true
+}
+
+func commandName(text string) (string, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", false
+	}
+	name := strings.Fields(text)[0][1:]
+	name, _, _ = strings.Cut(name, "@")
+	return name, true
+}
+
+func (r *Router) resolve(c *Context) *route {
+	if cq := c.Update.CallbackQuery; cq != nil {
+		if callback.IsEncoded(cq.Data) {
+			action, err := callback.Default.Decode(cq.Data)
+			if err != nil {
+				log.Printf("Invalid callback data %q: %v", cq.Data, err)
+				return r.onCallback
+			}
+			c.Action = action
+			if rt, ok := r.actions[action.Kind()]; ok {
+				return &rt
+			}
+			return r.onCallback
+		}
+		for _, cr := range r.callbacks {
+			if params, ok := cr.match(cq.Data); ok {
+				c.Params = params
+				return &route{name: cr.pattern, handler: cr.handler}
+			}
+		}
+		return r.onCallback
+	}
+
+	if c.Update.Message == nil {
+		return nil
+	}
+	text := c.Update.Message.Text
+	if name, ok := commandName(text); ok {
+		if rt, ok := r.commands[name]; ok {
+			return &rt
+		}
+	}
+	if rt, ok := r.texts[text]; ok {
+		return &rt
+	}
+	if r.stateOf != nil {
+		if state := r.stateOf(c); state != "" {
+			c.State = state
+			if rt, ok := r.states[state]; ok {
+				return &rt
+			}
+		}
+	}
+	return r.onMessage
+}
+
+// Обработка одного обновления. Глобальные middleware вызываются и для обновлений без маршрута
+func (r *Router) Handle(bot telegram.Client, update tgbotapi.Update) {
+	c := &Context{Bot: bot, Update: update}
+	h := func(c *Context) {
+		rt := r.resolve(c)
+		if rt == nil {
+			return
+		}
+		c.Route = rt.name
+		rt.handler(c)
+	}
+	chain(h, r.middleware)(c)
+}
@@ -0,0 +1,37 @@
+package sender
+
+import "time"
+
+// Ограничитель скорости по алгоритму GCRA: в среднем одно событие за interval,
+// подряд без ожидания - не больше burst событий
+type limiter struct {
+	interval time.Duration
+	burst    int
+	// Теоретическое время следующего события
+	tat time.Time
+}
+
+func newLimiter(interval time.Duration, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{interval: interval, burst: burst}
+}
+
+// Резервирует событие и возвращает, сколько нужно подождать перед ним
+func (l *limiter) reserve(now time.Time) time.Duration {
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+	wait := l.tat.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.tat = l.tat.Add(l.interval)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Ограничитель больше не влияет на отправку и может быть удален
+func (l *limiter) idle(now time.Time) bool {
+	return !l.tat.After(now)
+}
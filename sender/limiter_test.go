@@ -0,0 +1,60 @@
+package sender
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstAndInterval(t *testing.T) {
+	const interval = time.Second
+	l := newLimiter(interval, 3)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Первые burst событий проходят сразу, следующие - по одному за interval
+	for i, want := range []time.Duration{0, 0, 0, interval, 2 * interval, 3 * interval} {
+		if got := l.reserve(now); got != want {
+			t.Errorf("reserve #%d = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Прошедшее время сокращает ожидание
+	now = now.Add(3 * interval)
+	if got := l.reserve(now); got != interval {
+		t.Errorf("after waiting: %v, want %v", got, interval)
+	}
+
+	// За время простоя burst восстанавливается, но не больше чем до burst
+	now = now.Add(10 * interval)
+	if !l.idle(now) {
+		t.Errorf("limiter is not idle after a pause")
+	}
+	for i, want := range []time.Duration{0, 0, 0, interval} {
+		if got := l.reserve(now); got != want {
+			t.Errorf("after pause reserve #%d = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Равномерный поток с шагом interval никогда не ждет
+	now = now.Add(10 * interval)
+	for i := 0; i < 10; i++ {
+		if got := l.reserve(now); got != 0 {
+			t.Errorf("steady reserve #%d = %v", i+1, got)
+		}
+		now = now.Add(interval)
+	}
+}
+
+func TestDefaultConfigSendsOneMessagePerChatInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	s := New(nil, cfg)
+	if wait := s.reserve(chatID); wait != 0 {
+		t.Fatalf("first message waits %v", wait)
+	}
+	// Запас на время между вызовами
+	if wait := s.reserve(chatID); wait < cfg.ChatInterval-10*time.Millisecond || wait > cfg.ChatInterval {
+		t.Errorf("second message waits %v, want %v", wait, cfg.ChatInterval)
+	}
+	if wait := s.reserve(chatID + 1); wait != 0 {
+		t.Errorf("message to another chat waits %v", wait)
+	}
+}
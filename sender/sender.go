@@ -0,0 +1,264 @@
+package sender
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+type Config struct {
+	// Сообщений в секунду на весь бот
+	GlobalPerSecond int
+	// В один чат - одно сообщение за ChatInterval, подряд не больше ChatBurst
+	ChatInterval time.Duration
+	ChatBurst    int
+	// Дополнительный лимит для групп и каналов
+	GroupPerMinute int
+	// Сколько раз повторять отправку после 429 и временных ошибок
+	MaxRetries int
+	// Вызывается, когда чат недоступен: бот заблокирован, удален из группы или пользователь удален
+	OnBlocked func(chatID int64)
+}
+
+func DefaultConfig() Config {
+	return Config{
+		GlobalPerSecond: 30,
+		ChatInterval:    time.Second,
+		ChatBurst:       1,
+		GroupPerMinute:  20,
+		MaxRetries:      3,
+	}
+}
+
+type chatLimits struct {
+	chat  *limiter
+	group *limiter
+}
+
+// Исходящие запросы одного бота. Запросы с chat_id ждут глобального лимита и лимита
+// чата, остальные запросы (ответы на колбэки, getMe) отправляются сразу
+type Sender struct {
+	bot telegram.Client
+	cfg Config
+
+	mu          sync.Mutex
+	global      *limiter
+	chats       map[int64]*chatLimits
+	pausedUntil time.Time
+	lastCleanup time.Time
+}
+
+func New(bot telegram.Client, cfg Config) *Sender {
+	defaults := DefaultConfig()
+	if cfg.GlobalPerSecond <= 0 {
+		cfg.GlobalPerSecond = defaults.GlobalPerSecond
+	}
+	if cfg.ChatInterval <= 0 {
+		cfg.ChatInterval = defaults.ChatInterval
+	}
+	if cfg.GroupPerMinute <= 0 {
+		cfg.GroupPerMinute = defaults.GroupPerMinute
+	}
+	s := &Sender{
+		bot:    bot,
+		cfg:    cfg,
+		global: newLimiter(time.Second/time.Duration(cfg.GlobalPerSecond), cfg.GlobalPerSecond),
+		chats:  make(map[int64]*chatLimits),
+	}
+	return s
+}
+
+func (s *Sender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	err := s.do(c, func() (err error) {
+		msg, err = s.bot.Send(c)
+		return err
+	})
+	return msg, err
+}
+
+func (s *Sender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(c, func() (err error) {
+		resp, err = s.bot.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Sender) do(c tgbotapi.Chattable, call func() error) error {
+	chatID, limited := chatIDOf(c)
+	err := s.retry(chatID, limited, call)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) || isTransient(err, nil) {
+		log.Printf("Error sending %T to chat %d via @%s: %v", c, chatID, telegram.Username(s.bot), err)
+	}
+	return err
+}
+
+func (s *Sender) retry(chatID int64, limited bool, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		if limited {
+			time.Sleep(s.reserve(chatID))
+		}
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *tgbotapi.Error
+		isAPIErr := errors.As(err, &apiErr)
+		switch {
+		case isAPIErr && apiErr.RetryAfter > 0:
+			s.pause(time.Duration(apiErr.RetryAfter) * time.Second)
+			if attempt >= s.cfg.MaxRetries {
+				return err
+			}
+			if !limited {
+				time.Sleep(time.Duration(apiErr.RetryAfter) * time.Second)
+			}
+		case isAPIErr && apiErr.Code == 403:
+			if chatID != 0 && s.cfg.OnBlocked != nil {
+				s.cfg.OnBlocked(chatID)
+			}
+			return err
+		case isTransient(err, apiErr):
+			if attempt >= s.cfg.MaxRetries {
+				return err
+			}
+			time.Sleep(time.Duration(500<<attempt) * time.Millisecond)
+		default:
+			return err
+		}
+	}
+}
+
+// Сетевые ошибки и ошибки сервера Telegram. Ошибки разбора ответа не повторяются:
+// сообщение уже могло быть доставлено
+func isTransient(err error, apiErr *tgbotapi.Error) bool {
+	if apiErr != nil {
+		return apiErr.Code >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// Резервирует отправку в чат и возвращает время ожидания
+func (s *Sender) reserve(chatID int64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.cleanup(now)
+
+	limits, ok := s.chats[chatID]
+	if !ok {
+		limits = &chatLimits{chat: newLimiter(s.cfg.ChatInterval, s.cfg.ChatBurst)}
+		// Отрицательный ID у групп, супергрупп и каналов
+		if chatID < 0 {
+			limits.group = newLimiter(time.Minute/time.Duration(s.cfg.GroupPerMinute), s.cfg.GroupPerMinute)
+		}
+		s.chats[chatID] = limits
+	}
+
+	wait := s.global.reserve(now)
+	if w := limits.chat.reserve(now); w > wait {
+		wait = w
+	}
+	if limits.group != nil {
+		if w := limits.group.reserve(now); w > wait {
+			wait = w
+		}
+	}
+	if paused := s.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+// После 429 Telegram не принимает сообщения бота retry_after секунд
+func (s *Sender) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+func (s *Sender) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < time.Minute {
+		return
+	}
+	s.lastCleanup = now
+	for chatID, limits := range s.chats {
+		if limits.chat.idle(now) && (limits.group == nil || limits.group.idle(now)) {
+			delete(s.chats, chatID)
+		}
+	}
+}
+
+// ChatID запроса, если это отправка или изменение сообщения в чате
+func chatIDOf(c tgbotapi.Chattable) (int64, bool) {
+	v := reflect.Indirect(reflect.ValueOf(c))
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	f := v.FieldByName("ChatID")
+	if !f.IsValid() || f.Kind() != reflect.Int64 || f.Int() == 0 {
+		return 0, false
+	}
+	return f.Int(), true
+}
+
+var (
+	registryMu sync.Mutex
+	senders    = make(map[telegram.Client]*Sender)
+)
+
+// Регистрирует отправителя клиента бота. После перезапуска клона создается новый
+// клиент, поэтому отправитель старого нужно удалить через Unregister
+func Register(bot telegram.Client, cfg Config) *Sender {
+	s := New(bot, cfg)
+	registryMu.Lock()
+	senders[bot] = s
+	registryMu.Unlock()
+	return s
+}
+
+func Unregister(bot telegram.Client) {
+	registryMu.Lock()
+	delete(senders, bot)
+	registryMu.Unlock()
+}
+
+// Отправитель бота. Запуск бота регистрирует его клиент, остановка удаляет. Для
+// незарегистрированного клиента создается разовый отправитель: он не сохраняется,
+// не разделяет лимиты с другими отправками и не сообщает о недоступных чатах
+func For(bot telegram.Client) *Sender {
+	registryMu.Lock()
+	s, ok := senders[bot]
+	registryMu.Unlock()
+	if !ok {
+		log.Printf("Sender of @%s is not registered, sending without shared limits", telegram.Username(bot))
+		return New(bot, DefaultConfig())
+	}
+	return s
+}
+
+func Send(bot telegram.Client, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return For(bot).Send(c)
+}
+
+func Request(bot telegram.Client, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return For(bot).Request(c)
+}
+
+func IsBlocked(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 403
+}
@@ -0,0 +1,90 @@
+package sender
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cekretik/BoostBot/telegram/telegramtest"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+const chatID = 42
+
+func TestRetryAfterTooManyRequests(t *testing.T) {
+	fake := telegramtest.New()
+	fake.FailNext(chatID, &tgbotapi.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 1",
+		ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1},
+	})
+	s := New(fake, DefaultConfig())
+
+	start := time.Now()
+	if _, err := s.Send(tgbotapi.NewMessage(chatID, "hello")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want retry_after", elapsed)
+	}
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if messages := fake.Messages(chatID); len(messages) != 1 || messages[0].Text != "hello" {
+		t.Errorf("messages = %+v", messages)
+	}
+}
+
+func TestForbiddenReportsBlockedChat(t *testing.T) {
+	fake := telegramtest.New()
+	fake.FailChat(chatID, telegramtest.ErrBlocked)
+	var blocked []int64
+	cfg := DefaultConfig()
+	cfg.OnBlocked = func(id int64) { blocked = append(blocked, id) }
+	s := New(fake, cfg)
+
+	_, err := s.Send(tgbotapi.NewMessage(chatID, "hello"))
+	if !IsBlocked(err) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(blocked) != 1 || blocked[0] != chatID {
+		t.Errorf("blocked = %v", blocked)
+	}
+	if got := len(fake.Requests()); got != 1 {
+		t.Errorf("requests = %d, 403 must not be retried", got)
+	}
+}
+
+func TestServerErrorGivesUpAfterMaxRetries(t *testing.T) {
+	fake := telegramtest.New()
+	serverErr := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	fake.FailChat(chatID, serverErr)
+	cfg := DefaultConfig()
+	cfg.MaxRetries = 1
+	s := New(fake, cfg)
+
+	_, err := s.Send(tgbotapi.NewMessage(chatID, "hello"))
+	if !errors.Is(err, serverErr) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := len(fake.Requests()); got != cfg.MaxRetries+1 {
+		t.Errorf("requests = %d, want %d", got, cfg.MaxRetries+1)
+	}
+}
+
+func TestUnregisteredClientIsNotCached(t *testing.T) {
+	fake := telegramtest.New()
+	if For(fake) == For(fake) {
+		t.Fatalf("sender of an unregistered client is cached")
+	}
+	s := Register(fake, DefaultConfig())
+	if For(fake) != s {
+		t.Fatalf("registered sender is not used")
+	}
+	Unregister(fake)
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := senders[fake]; ok {
+		t.Fatalf("sender is kept after Unregister")
+	}
+}
@@ -0,0 +1,356 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Cekretik/BoostBot/database"
+	"github.com/Cekretik/BoostBot/models"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+	"gorm.io/gorm"
+)
+
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	StateBackoff  State = "backoff"
+	StateStopped  State = "stopped"
+	StateFailed   State = "failed"
+	StateRevoked  State = "token_revoked"
+)
+
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 10 * time.Minute
+	// Цикл, проработавший дольше этого времени, считается стабильным и сбрасывает задержку
+	stableRunDuration = 5 * time.Minute
+	// После стольких неудачных запусков подряд бот переводится в failed и больше не перезапускается сам
+	maxConsecutiveFailures = 10
+)
+
+// Telegram отклонил токен бота. Такой бот не перезапускается до замены токена
+var ErrTokenRevoked = errors.New("токен бота отозван")
+
+// Ответы 401 и 404 Telegram возвращает для отозванного или удаленного токена
+func IsTokenRevoked(err error) bool {
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) {
+		return tgErr.Code == 401 || tgErr.Code == 404
+	}
+	return errors.Is(err, ErrTokenRevoked)
+}
+
+// Цикл обработки обновлений одного бота. Должен завершаться при отмене ctx
+type RunFunc func(ctx context.Context, bot *tgbotapi.BotAPI, botID int64) error
+
+// Вызывается после того, как бот с отозванным токеном помечен в bot_owners и остановлен
+type RevokedFunc func(botOwner models.BotOwners)
+
+type Status struct {
+	BotID     int64
+	BotName   string
+	State     State
+	LastError error
+	Failures  int
+	Since     time.Time
+}
+
+type managedBot struct {
+	status Status
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type Supervisor struct {
+	db        *gorm.DB
+	run       RunFunc
+	onRevoked RevokedFunc
+	mu        sync.Mutex
+	bots      map[int64]*managedBot
+	// После StopAll боты больше не запускаются
+	closed bool
+}
+
+func New(db *gorm.DB, run RunFunc, onRevoked RevokedFunc) *Supervisor {
+	return &Supervisor{
+		db:        db,
+		run:       run,
+		onRevoked: onRevoked,
+		bots:      make(map[int64]*managedBot),
+	}
+}
+
+// Запуск бота. Если бот уже работает или ждет перезапуска, ничего не происходит
+func (s *Supervisor) Start(botOwner models.BotOwners) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.bots[botOwner.ID]; ok && m.status.State != StateStopped && m.status.State != StateFailed && m.status.State != StateRevoked {
+		return
+	}
+	s.startLocked(botOwner)
+}
+
+// Перезапуск бота с новыми данными, например после смены токена
+func (s *Supervisor) Restart(botOwner models.BotOwners) {
+	s.Stop(botOwner.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startLocked(botOwner)
+}
+
+func (s *Supervisor) startLocked(botOwner models.BotOwners) {
+	if s.closed {
+		return
+	}
+	if old, ok := s.bots[botOwner.ID]; ok {
+		old.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &managedBot{
+		status: Status{BotID: botOwner.ID, BotName: botOwner.BotName, State: StateStarting, Since: time.Now()},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.bots[botOwner.ID] = m
+	go s.loop(ctx, m, botOwner.Token)
+}
+
+func (s *Supervisor) Stop(botID int64) {
+	s.mu.Lock()
+	m, ok := s.bots[botID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	m.cancel()
+	<-m.done
+
+	s.mu.Lock()
+	if s.bots[botID] == m {
+		delete(s.bots, botID)
+	}
+	s.mu.Unlock()
+}
+
+// Останавливает все боты при завершении приложения. Каждый бот перестает принимать
+// обновления и дорабатывает уже принятые, ожидание ограничено дедлайном ctx
+func (s *Supervisor) StopAll(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	botIDs := make([]int64, 0, len(s.bots))
+	for botID := range s.bots {
+		botIDs = append(botIDs, botID)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, botID := range botIDs {
+			wg.Add(1)
+			go func(botID int64) {
+				defer wg.Done()
+				s.Stop(botID)
+			}(botID)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("не все боты остановились до дедлайна: %w", ctx.Err())
+	}
+}
+
+func (s *Supervisor) Status(botID int64) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.bots[botID]
+	if !ok {
+		return Status{BotID: botID, State: StateStopped}, false
+	}
+	return m.status, true
+}
+
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.bots))
+	for _, m := range s.bots {
+		statuses = append(statuses, m.status)
+	}
+	return statuses
+}
+
+func (s *Supervisor) IsRunning(botID int64) bool {
+	status, _ := s.Status(botID)
+	return status.State == StateRunning
+}
+
+func (s *Supervisor) setState(m *managedBot, state State, err error) {
+	s.mu.Lock()
+	m.status.State = state
+	m.status.LastError = err
+	m.status.Since = time.Now()
+	status := m.status
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Бот %d (@%s): %s, ошибка: %v", status.BotID, status.BotName, state, err)
+	} else {
+		log.Printf("Бот %d (@%s): %s", status.BotID, status.BotName, state)
+	}
+}
+
+func (s *Supervisor) loop(ctx context.Context, m *managedBot, token string) {
+	defer close(m.done)
+	backoff := minBackoff
+
+	for {
+		s.setState(m, StateStarting, nil)
+		startedAt := time.Now()
+		err := s.runOnce(ctx, m, token)
+
+		if ctx.Err() != nil {
+			s.setState(m, StateStopped, nil)
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("цикл обновлений завершился")
+		}
+		if IsTokenRevoked(err) {
+			s.setState(m, StateRevoked, err)
+			s.markRevoked(m.status.BotID)
+			return
+		}
+
+		s.mu.Lock()
+		if time.Since(startedAt) > stableRunDuration {
+			m.status.Failures = 0
+			backoff = minBackoff
+		}
+		m.status.Failures++
+		failures := m.status.Failures
+		s.mu.Unlock()
+
+		if failures >= maxConsecutiveFailures {
+			s.setState(m, StateFailed, err)
+			return
+		}
+
+		s.setState(m, StateBackoff, err)
+		select {
+		case <-ctx.Done():
+			s.setState(m, StateStopped, nil)
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Supervisor) runOnce(ctx context.Context, m *managedBot, token string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	plainToken, err := database.DecryptToken(token)
+	if err != nil {
+		return err
+	}
+	bot, err := tgbotapi.NewBotAPI(plainToken)
+	if err != nil {
+		if IsTokenRevoked(err) {
+			return ErrTokenRevoked
+		}
+		return errors.New(database.RedactTokens(err.Error()))
+	}
+
+	s.mu.Lock()
+	m.status.BotName = bot.Self.UserName
+	s.mu.Unlock()
+	s.setState(m, StateRunning, nil)
+
+	return s.run(ctx, bot, m.status.BotID)
+}
+
+func (s *Supervisor) markRevoked(botID int64) {
+	err := s.db.Model(&models.BotOwners{}).Where("id = ?", botID).
+		Updates(map[string]interface{}{"token_revoked": true, "running": false}).Error
+	if err != nil {
+		log.Printf("Не удалось отметить отозванный токен бота %d: %v", botID, err)
+		return
+	}
+	if s.onRevoked == nil {
+		return
+	}
+	botOwner, err := database.GetBotByID(s.db, botID)
+	if err != nil {
+		log.Printf("Ошибка при получении бота %d: %v", botID, err)
+		return
+	}
+	s.onRevoked(botOwner)
+}
+
+// Синхронизация запущенных ботов с таблицей bot_owners
+func (s *Supervisor) Reconcile() error {
+	var activeBots []models.BotOwners
+	if err := s.db.Where("running = ? AND token_revoked = ? AND token != ''", true, false).Find(&activeBots).Error; err != nil {
+		return err
+	}
+
+	active := make(map[int64]bool, len(activeBots))
+	for _, botOwner := range activeBots {
+		active[botOwner.ID] = true
+		s.mu.Lock()
+		_, managed := s.bots[botOwner.ID]
+		if !managed {
+			s.startLocked(botOwner)
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	var toStop []int64
+	for botID := range s.bots {
+		if !active[botID] {
+			toStop = append(toStop, botID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, botID := range toStop {
+		s.Stop(botID)
+	}
+	return nil
+}
+
+func (s *Supervisor) Run(ctx context.Context, interval time.Duration) {
+	for {
+		if err := s.Reconcile(); err != nil {
+			log.Printf("Ошибка при загрузке активных ботов: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
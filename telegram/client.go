@@ -0,0 +1,27 @@
+package telegram
+
+import tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+
+// Методы Bot API, которыми пользуются обработчики. Реализуется *tgbotapi.BotAPI,
+// в тестах его заменяет telegramtest.Fake
+type Client interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetChatMember(config tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error)
+	GetChatAdministrators(config tgbotapi.ChatAdministratorsConfig) ([]tgbotapi.ChatMember, error)
+	GetMe() (tgbotapi.User, error)
+}
+
+var _ Client = (*tgbotapi.BotAPI)(nil)
+
+// Имя бота. У *tgbotapi.BotAPI берется из данных, полученных при подключении, без запроса к Telegram
+func Username(c Client) string {
+	if bot, ok := c.(*tgbotapi.BotAPI); ok {
+		return bot.Self.UserName
+	}
+	me, err := c.GetMe()
+	if err != nil {
+		return ""
+	}
+	return me.UserName
+}
@@ -0,0 +1,31 @@
+package telegram
+
+import "sync"
+
+// Запущенные боты-клоны по ID, через них фоновые задачи пишут пользователям клонов
+var (
+	registryMu sync.RWMutex
+	bots       = make(map[int64]Client)
+)
+
+func Register(botID int64, c Client) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	bots[botID] = c
+}
+
+// Удаляет бота, если его еще не заменил перезапущенный клиент
+func Unregister(botID int64, c Client) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if bots[botID] == c {
+		delete(bots, botID)
+	}
+}
+
+func Bot(botID int64) (Client, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := bots[botID]
+	return c, ok
+}
@@ -0,0 +1,358 @@
+// Пакет telegramtest содержит поддельный клиент Telegram для сквозных тестов обработчиков
+package telegramtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Ошибка, которую Telegram возвращает боту, заблокированному пользователем
+var ErrBlocked = &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+
+// Сообщение, отправленное ботом. Изменения через editMessage* применяются к уже отправленному сообщению
+type Message struct {
+	ID      int
+	ChatID  int64
+	Text    string
+	Inline  *tgbotapi.InlineKeyboardMarkup
+	Reply   *tgbotapi.ReplyKeyboardMarkup
+	Deleted bool
+	// Исходный запрос: MessageConfig, DocumentConfig и т.д.
+	Request tgbotapi.Chattable
+}
+
+// Кнопки inline-клавиатуры сообщения построчно
+func (m Message) Buttons() [][]string {
+	if m.Inline == nil {
+		return nil
+	}
+	rows := make([][]string, 0, len(m.Inline.InlineKeyboard))
+	for _, row := range m.Inline.InlineKeyboard {
+		texts := make([]string, 0, len(row))
+		for _, button := range row {
+			texts = append(texts, button.Text)
+		}
+		rows = append(rows, texts)
+	}
+	return rows
+}
+
+// Данные inline-кнопки с текстом text
+func (m Message) Button(text string) (tgbotapi.InlineKeyboardButton, bool) {
+	if m.Inline == nil {
+		return tgbotapi.InlineKeyboardButton{}, false
+	}
+	for _, row := range m.Inline.InlineKeyboard {
+		for _, button := range row {
+			if button.Text == text {
+				return button, true
+			}
+		}
+	}
+	return tgbotapi.InlineKeyboardButton{}, false
+}
+
+// Кнопки обычной клавиатуры построчно
+func (m Message) ReplyButtons() [][]string {
+	if m.Reply == nil {
+		return nil
+	}
+	rows := make([][]string, 0, len(m.Reply.Keyboard))
+	for _, row := range m.Reply.Keyboard {
+		texts := make([]string, 0, len(row))
+		for _, button := range row {
+			texts = append(texts, button.Text)
+		}
+		rows = append(rows, texts)
+	}
+	return rows
+}
+
+// Поддельный клиент Telegram. Записывает все запросы бота и отвечает на них без сети.
+// Участников и администраторов чатов и ошибки отправки задает тест
+type Fake struct {
+	Self tgbotapi.User
+
+	mu        sync.Mutex
+	nextID    int
+	updateID  int
+	messages  []*Message
+	requests  []tgbotapi.Chattable
+	answers   []tgbotapi.CallbackConfig
+	members   map[[2]int64]string
+	admins    map[int64][]tgbotapi.ChatMember
+	sendError map[int64]error
+	nextError map[int64][]error
+}
+
+func New() *Fake {
+	return &Fake{
+		Self:      tgbotapi.User{ID: 100500, IsBot: true, FirstName: "Test", UserName: "test_bot"},
+		members:   make(map[[2]int64]string),
+		admins:    make(map[int64][]tgbotapi.ChatMember),
+		sendError: make(map[int64]error),
+		nextError: make(map[int64][]error),
+	}
+}
+
+// Статус пользователя в чате для getChatMember: member, left, administrator...
+// По умолчанию пользователь состоит в любом чате
+func (f *Fake) SetMember(chatID, userID int64, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.members[[2]int64{chatID, userID}] = status
+}
+
+// Администраторы чата для getChatAdministrators
+func (f *Fake) SetAdmins(chatID int64, userIDs ...int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	admins := make([]tgbotapi.ChatMember, 0, len(userIDs))
+	for _, userID := range userIDs {
+		admins = append(admins, tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: "administrator"})
+	}
+	f.admins[chatID] = admins
+}
+
+// Все отправки в чат завершаются ошибкой err, nil снимает ошибку
+func (f *Fake) FailChat(chatID int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.sendError, chatID)
+		return
+	}
+	f.sendError[chatID] = err
+}
+
+// Следующие отправки в чат по очереди завершаются ошибками errs, затем проходят как обычно
+func (f *Fake) FailNext(chatID int64, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextError[chatID] = append(f.nextError[chatID], errs...)
+}
+
+func (f *Fake) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, c)
+
+	chatID := chatOf(c)
+	if errs := f.nextError[chatID]; len(errs) > 0 {
+		f.nextError[chatID] = errs[1:]
+		return tgbotapi.Message{}, errs[0]
+	}
+	if err := f.sendError[chatID]; err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	switch cfg := c.(type) {
+	case tgbotapi.EditMessageTextConfig:
+		m, err := f.find(cfg.ChatID, cfg.MessageID)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+		m.Text = cfg.Text
+		m.Inline = cfg.ReplyMarkup
+		return f.result(m), nil
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		m, err := f.find(cfg.ChatID, cfg.MessageID)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+		m.Inline = cfg.ReplyMarkup
+		return f.result(m), nil
+	case tgbotapi.DeleteMessageConfig:
+		m, err := f.find(cfg.ChatID, cfg.MessageID)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+		m.Deleted = true
+		return tgbotapi.Message{}, nil
+	case tgbotapi.CallbackConfig:
+		f.answers = append(f.answers, cfg)
+		return tgbotapi.Message{}, nil
+	}
+
+	m := &Message{ChatID: chatID, Request: c}
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		m.Text = cfg.Text
+		m.Inline, m.Reply = markups(cfg.ReplyMarkup)
+	case tgbotapi.DocumentConfig:
+		m.Text = cfg.Caption
+		m.Inline, m.Reply = markups(cfg.ReplyMarkup)
+	case tgbotapi.PhotoConfig:
+		m.Text = cfg.Caption
+		m.Inline, m.Reply = markups(cfg.ReplyMarkup)
+	default:
+		// Остальные запросы только записываются
+		return tgbotapi.Message{}, nil
+	}
+	f.nextID++
+	m.ID = f.nextID
+	f.messages = append(f.messages, m)
+	return f.result(m), nil
+}
+
+func (f *Fake) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if _, err := f.Send(c); err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *Fake) GetChatMember(config tgbotapi.GetChatMemberConfig) (tgbotapi.ChatMember, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.members[[2]int64{config.ChatID, config.UserID}]
+	if !ok {
+		status = "member"
+	}
+	return tgbotapi.ChatMember{User: &tgbotapi.User{ID: config.UserID}, Status: status}, nil
+}
+
+func (f *Fake) GetChatAdministrators(config tgbotapi.ChatAdministratorsConfig) ([]tgbotapi.ChatMember, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]tgbotapi.ChatMember(nil), f.admins[config.ChatID]...), nil
+}
+
+func (f *Fake) GetMe() (tgbotapi.User, error) {
+	return f.Self, nil
+}
+
+// Отправленные в чат сообщения, включая удаленные, в порядке отправки
+func (f *Fake) Messages(chatID int64) []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []Message
+	for _, m := range f.messages {
+		if m.ChatID == chatID {
+			messages = append(messages, *m)
+		}
+	}
+	return messages
+}
+
+// Последнее неудаленное сообщение в чате
+func (f *Fake) LastMessage(chatID int64) (Message, bool) {
+	messages := f.Messages(chatID)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if !messages[i].Deleted {
+			return messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Ответы на нажатия inline-кнопок
+func (f *Fake) Answers() []tgbotapi.CallbackConfig {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]tgbotapi.CallbackConfig(nil), f.answers...)
+}
+
+// Все запросы бота в порядке отправки
+func (f *Fake) Requests() []tgbotapi.Chattable {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]tgbotapi.Chattable(nil), f.requests...)
+}
+
+// Забывает записанные запросы и сообщения, настройки чатов сохраняются
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = nil
+	f.requests = nil
+	f.answers = nil
+}
+
+func (f *Fake) find(chatID int64, messageID int) (*Message, error) {
+	for _, m := range f.messages {
+		if m.ChatID == chatID && m.ID == messageID && !m.Deleted {
+			return m, nil
+		}
+	}
+	return nil, &tgbotapi.Error{Code: 400, Message: "Bad Request: message to edit not found"}
+}
+
+func (f *Fake) result(m *Message) tgbotapi.Message {
+	return tgbotapi.Message{MessageID: m.ID, Chat: &tgbotapi.Chat{ID: m.ChatID}, Text: m.Text}
+}
+
+func (f *Fake) nextUpdateID() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updateID++
+	return f.updateID
+}
+
+// Новое текстовое сообщение пользователя в личном чате. Текст, начинающийся с "/", считается командой
+func (f *Fake) TextUpdate(from tgbotapi.User, text string) tgbotapi.Update {
+	msg := &tgbotapi.Message{
+		MessageID: -f.nextUpdateID(),
+		From:      &from,
+		Chat:      &tgbotapi.Chat{ID: from.ID, Type: "private", UserName: from.UserName},
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+	return tgbotapi.Update{UpdateID: f.nextUpdateID(), Message: msg}
+}
+
+// Нажатие inline-кнопки с данными data под сообщением бота
+func (f *Fake) CallbackUpdate(from tgbotapi.User, message Message, data string) tgbotapi.Update {
+	updateID := f.nextUpdateID()
+	return tgbotapi.Update{
+		UpdateID: updateID,
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   fmt.Sprintf("cb%d", updateID),
+			From: &from,
+			Message: &tgbotapi.Message{
+				MessageID: message.ID,
+				Chat:      &tgbotapi.Chat{ID: message.ChatID, Type: "private"},
+				Text:      message.Text,
+			},
+			Data: data,
+		},
+	}
+}
+
+func chatOf(c tgbotapi.Chattable) int64 {
+	switch cfg := c.(type) {
+	case tgbotapi.MessageConfig:
+		return cfg.ChatID
+	case tgbotapi.DocumentConfig:
+		return cfg.ChatID
+	case tgbotapi.PhotoConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return cfg.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return cfg.ChatID
+	case tgbotapi.DeleteMessageConfig:
+		return cfg.ChatID
+	}
+	return 0
+}
+
+func markups(markup interface{}) (*tgbotapi.InlineKeyboardMarkup, *tgbotapi.ReplyKeyboardMarkup) {
+	switch m := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		return &m, nil
+	case *tgbotapi.InlineKeyboardMarkup:
+		return m, nil
+	case tgbotapi.ReplyKeyboardMarkup:
+		return nil, &m
+	case *tgbotapi.ReplyKeyboardMarkup:
+		return nil, m
+	}
+	return nil, nil
+}
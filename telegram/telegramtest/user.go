@@ -0,0 +1,109 @@
+package telegramtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Cekretik/BoostBot/telegram"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Обработчик обновлений бота, например router.Router.Handle
+type Handler func(bot telegram.Client, update tgbotapi.Update)
+
+// Пользователь, который пишет боту и нажимает кнопки. Обновления обрабатываются синхронно,
+// поэтому после каждого действия ответы бота уже записаны в Fake
+type User struct {
+	t      testing.TB
+	fake   *Fake
+	handle Handler
+	From   tgbotapi.User
+}
+
+func (f *Fake) User(t testing.TB, handle Handler, from tgbotapi.User) *User {
+	return &User{t: t, fake: f, handle: handle, From: from}
+}
+
+func (u *User) ChatID() int64 {
+	return u.From.ID
+}
+
+// Отправляет боту текст или команду. Так же нажимаются кнопки обычной клавиатуры
+func (u *User) Send(text string) {
+	u.t.Helper()
+	u.handle(u.fake, u.fake.TextUpdate(u.From, text))
+}
+
+// Нажимает inline-кнопку text в последнем неудаленном сообщении, где она есть
+func (u *User) Press(text string) {
+	u.t.Helper()
+	messages := u.fake.Messages(u.ChatID())
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Deleted {
+			continue
+		}
+		if button, ok := messages[i].Button(text); ok && button.CallbackData != nil {
+			u.PressData(messages[i], *button.CallbackData)
+			return
+		}
+	}
+	u.t.Fatalf("button %q not found\n%s", text, u.Transcript())
+}
+
+// Нажимает кнопку с произвольными данными, например устаревшую
+func (u *User) PressData(message Message, data string) {
+	u.t.Helper()
+	u.handle(u.fake, u.fake.CallbackUpdate(u.From, message, data))
+}
+
+// Последнее неудаленное сообщение бота пользователю
+func (u *User) Last() Message {
+	u.t.Helper()
+	m, ok := u.fake.LastMessage(u.ChatID())
+	if !ok {
+		u.t.Fatalf("no messages sent to chat %d", u.ChatID())
+	}
+	return m
+}
+
+// Проверяет, что последнее сообщение содержит substr, и возвращает его
+func (u *User) Expect(substr string) Message {
+	u.t.Helper()
+	m := u.Last()
+	if !strings.Contains(m.Text, substr) {
+		u.t.Fatalf("last message does not contain %q\n%s", substr, u.Transcript())
+	}
+	return m
+}
+
+// Проверяет, что одно из сообщений после действия содержит substr
+func (u *User) ExpectAny(substr string) Message {
+	u.t.Helper()
+	for _, m := range u.fake.Messages(u.ChatID()) {
+		if strings.Contains(m.Text, substr) {
+			return m
+		}
+	}
+	u.t.Fatalf("no message contains %q\n%s", substr, u.Transcript())
+	return Message{}
+}
+
+// Переписка с пользователем для сообщений об ошибках
+func (u *User) Transcript() string {
+	var b strings.Builder
+	for _, m := range u.fake.Messages(u.ChatID()) {
+		state := ""
+		if m.Deleted {
+			state = " (deleted)"
+		}
+		fmt.Fprintf(&b, "#%d%s: %q\n", m.ID, state, m.Text)
+		for _, row := range m.Buttons() {
+			fmt.Fprintf(&b, "    [%s]\n", strings.Join(row, "] ["))
+		}
+		for _, row := range m.ReplyButtons() {
+			fmt.Fprintf(&b, "    {%s}\n", strings.Join(row, "} {"))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+
+	"github.com/Cekretik/BoostBot/i18n"
+	"github.com/Cekretik/BoostBot/models"
+	"github.com/Cekretik/BoostBot/sender"
+	"github.com/Cekretik/BoostBot/telegram"
+	"github.com/Cekretik/BoostBot/workerpool"
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Настройки пула обработчиков бота. BOT_WORKERS и BOT_UPDATE_QUEUE задают значения
+// по умолчанию, BOT_UPDATE_OVERFLOW=drop отбрасывает обновления при переполнении очереди
+func updatePoolConfig(botOwner models.BotOwners) workerpool.Config {
+	cfg := workerpool.Config{
+		Workers:    settings.Updates.Workers,
+		QueueLimit: settings.Updates.QueueLimit,
+		Overflow:   workerpool.OverflowPolicy(settings.Updates.Overflow),
+	}
+	if botOwner.Workers > 0 {
+		cfg.Workers = botOwner.Workers
+	}
+	if botOwner.UpdateQueueLimit > 0 {
+		cfg.QueueLimit = botOwner.UpdateQueueLimit
+	}
+	return cfg
+}
+
+// Отправляет обновление в пул. Отброшенный колбэк получает ответ, чтобы у пользователя не висела загрузка
+func submitUpdate(bot telegram.Client, pool *workerpool.Pool, update tgbotapi.Update) {
+	if pool.Submit(update) {
+		return
+	}
+	log.Printf("Очередь обновлений @%s переполнена, обновление %d отброшено", telegram.Username(bot), update.UpdateID)
+	if update.CallbackQuery != nil {
+		tr := i18n.For(i18n.Match(update.CallbackQuery.From.LanguageCode))
+		sender.Request(bot, tgbotapi.NewCallback(update.CallbackQuery.ID, tr.T("bot.overloaded")))
+	}
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+const (
+	PathPrefix   = "/tg/"
+	secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+	bufferSize   = 100
+)
+
+type endpoint struct {
+	botID   int64
+	updates chan tgbotapi.Update
+}
+
+// Распределяет входящие вебхуки Telegram по каналам обновлений ботов
+type Dispatcher struct {
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint
+}
+
+var DefaultDispatcher = NewDispatcher()
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{endpoints: make(map[string]*endpoint)}
+}
+
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (d *Dispatcher) Register(secret string, botID int64) tgbotapi.UpdatesChannel {
+	ch := make(chan tgbotapi.Update, bufferSize)
+	d.mu.Lock()
+	d.endpoints[secret] = &endpoint{botID: botID, updates: ch}
+	d.mu.Unlock()
+	return ch
+}
+
+// Удаляет endpoint, только если он не был заменен более поздней регистрацией
+func (d *Dispatcher) Unregister(secret string, updates tgbotapi.UpdatesChannel) {
+	d.mu.Lock()
+	if ep, ok := d.endpoints[secret]; ok && tgbotapi.UpdatesChannel(ep.updates) == updates {
+		delete(d.endpoints, secret)
+	}
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	secret := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(secret)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	d.mu.RLock()
+	ep, ok := d.endpoints[secret]
+	d.mu.RUnlock()
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case ep.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	default:
+		// Очередь бота заполнена, Telegram повторит доставку позже
+		log.Printf("Webhook queue of bot %d is full, update %d deferred", ep.botID, update.UpdateID)
+		http.Error(w, "Busy", http.StatusServiceUnavailable)
+	}
+}
+
+func SetWebhook(bot *tgbotapi.BotAPI, url, secret string) error {
+	params := make(tgbotapi.Params)
+	params["url"] = url
+	params["secret_token"] = secret
+	_, err := bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+func DeleteWebhook(bot *tgbotapi.BotAPI) error {
+	_, err := bot.Request(tgbotapi.DeleteWebhookConfig{})
+	return err
+}
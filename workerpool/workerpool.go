@@ -0,0 +1,128 @@
+package workerpool
+
+import (
+	"sync"
+
+	tgbotapi "github.com/Cekretik/telegram-bot-api-master"
+)
+
+// Что делать с обновлением, когда очередь бота заполнена
+type OverflowPolicy string
+
+const (
+	// Submit ждет, пока в очереди освободится место, новые обновления не читаются
+	OverflowDefer OverflowPolicy = "defer"
+	// Submit отбрасывает обновление
+	OverflowDrop OverflowPolicy = "drop"
+)
+
+type Config struct {
+	// Сколько обновлений бота обрабатывается одновременно
+	Workers int
+	// Сколько обновлений может ждать обработки, включая обрабатываемые
+	QueueLimit int
+	Overflow   OverflowPolicy
+}
+
+// Обрабатывает обновления бота параллельно. Обновления одного чата обрабатываются
+// строго по очереди, обновления разных чатов - одновременно, не больше Workers за раз
+type Pool struct {
+	cfg    Config
+	handle func(tgbotapi.Update)
+	slots  chan struct{}
+
+	mu     sync.Mutex
+	space  *sync.Cond
+	chats  map[int64][]tgbotapi.Update
+	queued int
+	wg     sync.WaitGroup
+}
+
+func New(cfg Config, handle func(tgbotapi.Update)) *Pool {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueLimit < cfg.Workers {
+		cfg.QueueLimit = cfg.Workers
+	}
+	p := &Pool{
+		cfg:    cfg,
+		handle: handle,
+		slots:  make(chan struct{}, cfg.Workers),
+		chats:  make(map[int64][]tgbotapi.Update),
+	}
+	p.space = sync.NewCond(&p.mu)
+	return p
+}
+
+// Ставит обновление в очередь его чата. Возвращает false, если обновление отброшено
+func (p *Pool) Submit(update tgbotapi.Update) bool {
+	key := chatKey(update)
+
+	p.mu.Lock()
+	for p.queued >= p.cfg.QueueLimit {
+		if p.cfg.Overflow == OverflowDrop {
+			p.mu.Unlock()
+			return false
+		}
+		p.space.Wait()
+	}
+	p.queued++
+	if queue, busy := p.chats[key]; busy {
+		p.chats[key] = append(queue, update)
+		p.mu.Unlock()
+		return true
+	}
+	// Чат становится активным: его очередь разбирает одна горутина
+	p.chats[key] = nil
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	go p.run(key, update)
+	return true
+}
+
+func (p *Pool) run(key int64, update tgbotapi.Update) {
+	defer p.wg.Done()
+	for {
+		// Слот занимается на одно обновление, чтобы длинная очередь одного чата не задерживала остальные
+		p.slots <- struct{}{}
+		p.handle(update)
+		<-p.slots
+
+		p.mu.Lock()
+		p.queued--
+		p.space.Broadcast()
+		queue := p.chats[key]
+		if len(queue) == 0 {
+			delete(p.chats, key)
+			p.mu.Unlock()
+			return
+		}
+		update = queue[0]
+		p.chats[key] = queue[1:]
+		p.mu.Unlock()
+	}
+}
+
+// Сколько обновлений ждет обработки
+func (p *Pool) Queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queued
+}
+
+// Ждет, пока будут обработаны все принятые обновления
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func chatKey(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}